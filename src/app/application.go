@@ -1,19 +1,77 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"tokenalert_user-api/src/config"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/datasources/cache"
 	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/services"
+	"tokenalert_user-api/src/tracing"
 
 	"github.com/gin-gonic/gin"
 )
 
 var (
-	router = gin.Default()
+	// router logs requests through middlewares.AccessLog rather than gin's text
+	// logger.
+	router = gin.New()
 )
 
-func StartApplication() {
+// StartApplication reads the configuration, flags included, from args, and serves
+// until SIGTERM or SIGINT, when it drains the requests in flight and releases what
+// the API holds. A second signal stops the process right away.
+func StartApplication(args []string) {
+	settings, rest, err := config.Load(args)
+	if err != nil {
+		panic(err)
+	}
+	if len(rest) > 0 {
+		panic(fmt.Errorf("unexpected argument %q", rest[0]))
+	}
+	config.Current = settings
+
+	tracing.InitTracing(settings.Tracing)
+	services.ImpersonationService = services.NewImpersonationService(settings.Impersonation)
 	mapUrls()
-	users_db.InitDataBase()
-	router.Run(":8080")
+	users_db.InitDataBase(settings.Database)
+	autoMigrate(settings.Migrations)
+	cacheUsers(settings.Cache)
+	bus.InitPublisher(settings.EventBus)
+	services.OutboxRelay.Start()
+	registerHealthChecks()
+
+	listener, err := net.Listen("tcp", settings.Server.Address)
+	if err != nil {
+		panic(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
+	err = serve(ctx, newServer(settings.Server, router), listener, settings.Server.DrainDelay, settings.Server.ShutdownTimeout)
+	release()
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
 }
 
+// cacheUsers puts the users cache, when one is configured, in front of the users
+// repository.
+func cacheUsers(settings cache.Config) {
+	cache.InitCache(settings)
+	if cache.Client == nil {
+		return
+	}
+	repositories.UsersRepository = repositories.NewCachedUsersRepository(repositories.UsersRepository, cache.Client, cache.Settings.TTL, cache.Settings.NegativeTTL)
+}
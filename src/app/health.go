@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/datasources/mysql/migrations"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/services"
+)
+
+const (
+	databaseCheckTimeout   = 2 * time.Second
+	migrationsCheckTimeout = 3 * time.Second
+	cacheCheckTimeout      = time.Second
+	eventBusCheckTimeout   = 2 * time.Second
+
+	cacheCheckKey = "health:ready"
+)
+
+// registerHealthChecks makes readiness depend on the database, its schema, and the
+// cache and event bus when they are configured.
+func registerHealthChecks() {
+	services.HealthService.Register("database", databaseCheckTimeout, checkDatabase)
+	services.HealthService.Register("migrations", migrationsCheckTimeout, checkMigrations)
+	if cache.Client != nil {
+		services.HealthService.Register("cache", cacheCheckTimeout, checkCache)
+	}
+	if bus.Client != nil {
+		services.HealthService.Register("event_bus", eventBusCheckTimeout, checkEventBus)
+	}
+}
+
+func checkDatabase(ctx context.Context) error {
+	return users_db.Client.PingContext(ctx)
+}
+
+// checkMigrations fails while the schema is behind the migrations the binary ships,
+// which happens when a new version starts before migrate up ran. It only reads
+// schema_migrations, and a database without one is behind.
+func checkMigrations(ctx context.Context) error {
+	all, err := migrations.Load(users_db.Current)
+	if err != nil {
+		return err
+	}
+	statuses, err := migrations.New(users_db.Client, users_db.Current, all).Status(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d of %d migrations pending", pending, len(statuses))
+	}
+	return nil
+}
+
+func checkCache(ctx context.Context) error {
+	_, _, err := cache.Client.Get(ctx, cacheCheckKey)
+	return err
+}
+
+func checkEventBus(ctx context.Context) error {
+	return bus.Client.Ping(ctx)
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"tokenalert_user-api/src/config"
+	"tokenalert_user-api/src/datasources/mysql/migrations"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+)
+
+const (
+	migrateUsage = "usage: migrate [flags] up|down|status"
+)
+
+// RunMigrate implements the migrate subcommand and returns the process exit code. The
+// configuration flags go before the command.
+func RunMigrate(args []string, out io.Writer) int {
+	settings, rest, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	if len(rest) != 1 {
+		fmt.Fprintln(out, migrateUsage)
+		return 2
+	}
+
+	migrator, err := newMigrator(settings.Database)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	if err := migrate(context.Background(), migrator, rest[0], out); err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	return 0
+}
+
+func newMigrator(settings users_db.Config) (*migrations.Migrator, error) {
+	users_db.InitDataBase(settings)
+	all, err := migrations.Load(users_db.Current)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(users_db.Client, users_db.Current, all), nil
+}
+
+func migrate(ctx context.Context, migrator *migrations.Migrator, command string, out io.Writer) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Fprintln(out, "no migration to revert")
+			return nil
+		}
+		fmt.Fprintf(out, "reverted %04d_%s\n", reverted.Version, reverted.Name)
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+}
+
+// autoMigrate applies pending migrations on start when settings.Auto is set.
+func autoMigrate(settings config.MigrationsConfig) {
+	if !settings.Auto {
+		return
+	}
+
+	all, err := migrations.Load(users_db.Current)
+	if err != nil {
+		panic(err)
+	}
+	if err := migrate(context.Background(), migrations.New(users_db.Client, users_db.Current, all), "up", os.Stdout); err != nil {
+		panic(err)
+	}
+}
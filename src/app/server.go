@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+	"tokenalert_user-api/src/config"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/services"
+	"tokenalert_user-api/src/tracing"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+)
+
+func newServer(settings config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         settings.Address,
+		Handler:      handler,
+		ReadTimeout:  settings.ReadTimeout,
+		WriteTimeout: settings.WriteTimeout,
+		IdleTimeout:  settings.IdleTimeout,
+	}
+}
+
+// serve answers requests on listener until ctx ends. It then reports the API as
+// shutting down and keeps serving for drainDelay, for load balancers to see readiness
+// fail and stop routing requests to it, before it stops accepting new ones and waits
+// up to shutdownTimeout for those in flight, closing the connections still open after
+// that. It returns the error that stopped the server early or kept it from draining
+// in time.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, drainDelay time.Duration, shutdownTimeout time.Duration) error {
+	failed := make(chan error, 1)
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	services.HealthService.ShuttingDown()
+	if drainDelay > 0 {
+		log.Printf("shutting down, serving for %s while load balancers drain", drainDelay)
+		select {
+		case err := <-failed:
+			return err
+		case <-time.After(drainDelay):
+		}
+	}
+	log.Println("shutting down, waiting for the requests in flight")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("requests still in flight after %s: %w", shutdownTimeout, err)
+	}
+	return nil
+}
+
+// release stops the background workers and closes every connection the API holds,
+// in the order that lets each one finish with those it depends on: the outbox relay
+// completes its batch while the bus and the database are still there. Spans go
+// last, so that those of the shutdown itself are exported.
+func release() {
+	services.OutboxRelay.Stop()
+	if bus.Client != nil {
+		if err := bus.Client.Close(); err != nil {
+			logger.Error("error when trying to close the event bus publisher", err)
+		}
+	}
+	if cache.Client != nil {
+		if err := cache.Client.Close(); err != nil {
+			logger.Error("error when trying to close the users cache", err)
+		}
+	}
+	repositories.CloseStatements()
+	if err := users_db.Replicas.Close(); err != nil {
+		logger.Error("error when trying to close the database replicas", err)
+	}
+	if users_db.Client != nil {
+		if err := users_db.Client.Close(); err != nil {
+			logger.Error("error when trying to close the database", err)
+		}
+	}
+	if err := tracing.Shutdown(); err != nil {
+		logger.Error("error when trying to export the last spans", err)
+	}
+	log.Println("shutdown complete")
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/config"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/health"
+	"tokenalert_user-api/src/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// healthServiceMock records when the API was reported as shutting down.
+type healthServiceMock struct {
+	mu           sync.Mutex
+	shuttingDown time.Time
+}
+
+func (m *healthServiceMock) Register(string, time.Duration, services.Checker) {}
+
+func (m *healthServiceMock) Ready(context.Context) health.Report {
+	return health.Report{}
+}
+
+func (m *healthServiceMock) ShuttingDown() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shuttingDown = time.Now()
+}
+
+func (m *healthServiceMock) shuttingDownAt() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.shuttingDown
+}
+
+func withHealthMock(t *testing.T) *healthServiceMock {
+	previous := services.HealthService
+	mock := &healthServiceMock{}
+	services.HealthService = mock
+	t.Cleanup(func() {
+		services.HealthService = previous
+	})
+	return mock
+}
+
+// startServer serves handler on a free port until the returned cancel is called; the
+// result of serve is sent to the returned channel.
+func startServer(t *testing.T, handler http.Handler, drainDelay time.Duration, shutdownTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	server := newServer(config.Default().Server, handler)
+	go func() {
+		result <- serve(ctx, server, listener, drainDelay, shutdownTimeout)
+	}()
+	t.Cleanup(cancel)
+	return "http://" + listener.Addr().String(), cancel, result
+}
+
+func TestServeDrainsRequestsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	url, cancel, result := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-finish
+		w.Write([]byte("done"))
+	}), 0, time.Second)
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Error(err)
+		}
+		response <- resp
+	}()
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(finish)
+
+	resp := <-response
+	if assert.NotNil(t, resp) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Nil(t, <-result)
+
+	_, err := http.Get(url)
+	assert.NotNil(t, err)
+}
+
+func TestServeKeepsServingWhileDraining(t *testing.T) {
+	healthMock := withHealthMock(t)
+	url, cancel, result := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}), 200*time.Millisecond, time.Second)
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	assert.False(t, healthMock.shuttingDownAt().IsZero())
+	resp, err := http.Get(url)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Nil(t, <-result)
+	assert.True(t, time.Since(healthMock.shuttingDownAt()) >= 200*time.Millisecond)
+
+	_, err = http.Get(url)
+	assert.NotNil(t, err)
+}
+
+func TestServeGivesUpAfterShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	defer close(finish)
+	url, cancel, result := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-finish
+	}), 0, 50*time.Millisecond)
+
+	go http.Get(url)
+	<-started
+	cancel()
+
+	err := <-result
+	assert.NotNil(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, "requests still in flight after 50ms: context deadline exceeded", err.Error())
+}
+
+func TestServeReturnsListenerFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+
+	err = serve(context.Background(), newServer(config.Default().Server, http.NotFoundHandler()), listener, 0, time.Second)
+
+	assert.NotNil(t, err)
+}
+
+func TestReleaseClosesConnections(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectClose()
+	publisher := bus.NewMemoryPublisher()
+	store := cache.NewMemoryStore(10)
+	users_db.Client, bus.Client, cache.Client = db, publisher, store
+	defer func() {
+		users_db.Client, bus.Client, cache.Client = nil, nil, nil
+	}()
+
+	release()
+
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, bus.ErrClosed, publisher.Publish("user.created", []byte("{}")))
+	_, _, err = store.Get(context.Background(), "users:1")
+	assert.Equal(t, cache.ErrClosed, err)
+}
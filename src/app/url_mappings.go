@@ -1,15 +1,95 @@
 package app
 
 import (
+	"tokenalert_user-api/src/controllers/admin"
+	"tokenalert_user-api/src/controllers/alerts"
+	"tokenalert_user-api/src/controllers/audit"
+	"tokenalert_user-api/src/controllers/debug"
+	"tokenalert_user-api/src/controllers/health"
+	"tokenalert_user-api/src/controllers/notifications"
 	"tokenalert_user-api/src/controllers/ping"
+	"tokenalert_user-api/src/controllers/plans"
 	"tokenalert_user-api/src/controllers/users"
+	"tokenalert_user-api/src/controllers/webhooks"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/metrics"
+	"tokenalert_user-api/src/middlewares"
+
+	"github.com/gin-gonic/gin"
 )
 
 
 func mapUrls() {
+	router.Use(middlewares.Tracing)
+	router.Use(middlewares.RequestId)
+	router.Use(middlewares.Caller)
+	router.Use(middlewares.AccessLog)
+	router.Use(middlewares.Metrics)
+	router.Use(gin.Recovery())
+	router.Use(middlewares.Impersonation)
+	router.Use(middlewares.ReadYourWrites)
+
 	router.GET("/ping", ping.Ping)
+	router.GET("/health/live", health.Live)
+	router.GET("/health/ready", health.Ready)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	debugRoutes := router.Group("/debug", middlewares.ForbidImpersonation, middlewares.RequirePermission(access.PermissionDebugRead))
+	debugRoutes.GET("/vars", debug.Vars)
+	debugRoutes.GET("/config", debug.Config)
 
-	router.GET("/users/:user_id", users.Get)
+	// Routes about a user are open to that user, and to staff with the permission
+	// the /admin routes ask for.
+	ownerReads := middlewares.RequireOwnerOrPermission(access.PermissionUsersRead)
+	ownerEdits := middlewares.RequireOwnerOrPermission(access.PermissionUsersEdit)
+
+	router.GET("/users/:user_id", ownerReads, users.Get)
 	router.POST("/users", users.Create)
-	router.POST("/users/login", users.Login)
+	router.PUT("/users/:user_id", ownerEdits, users.Update)
+	router.PATCH("/users/:user_id", ownerEdits, users.Update)
+	router.DELETE("/users/:user_id", middlewares.ForbidImpersonation, ownerEdits, users.Delete)
+	router.PUT("/users/:user_id/password", middlewares.ForbidImpersonation, ownerEdits, users.ChangePassword)
+	router.POST("/users/login", middlewares.ForbidImpersonation, users.Login)
+
+	router.POST("/users/:user_id/alerts", ownerEdits, alerts.Create)
+	router.GET("/users/:user_id/alerts", ownerReads, alerts.List)
+	router.GET("/users/:user_id/alerts/:alert_id", ownerReads, alerts.Get)
+	router.PUT("/users/:user_id/alerts/:alert_id", ownerEdits, alerts.Update)
+	router.POST("/users/:user_id/alerts/:alert_id/enable", ownerEdits, alerts.Enable)
+	router.POST("/users/:user_id/alerts/:alert_id/disable", ownerEdits, alerts.Disable)
+	router.DELETE("/users/:user_id/alerts/:alert_id", ownerEdits, alerts.Delete)
+
+	router.GET("/users/:user_id/notification-settings", ownerReads, notifications.Get)
+	router.PUT("/users/:user_id/notification-settings", ownerEdits, notifications.Update)
+	router.GET("/users/:user_id/quiet-hours", ownerReads, notifications.GetQuietHours)
+	router.PUT("/users/:user_id/quiet-hours", ownerEdits, notifications.UpdateQuietHours)
+
+	router.GET("/users/:user_id/usage", ownerReads, plans.GetUsage)
+
+	// Every /admin route checks the caller has the permission it needs, and none is
+	// open to impersonated requests.
+	adminRoutes := router.Group("/admin", middlewares.ForbidImpersonation)
+	adminRoutes.GET("/users", middlewares.RequirePermission(access.PermissionUsersRead), admin.ListUsers)
+	adminRoutes.GET("/users/:user_id", middlewares.RequirePermission(access.PermissionUsersRead), users.Get)
+	adminRoutes.PATCH("/users/:user_id", middlewares.RequirePermission(access.PermissionUsersEdit), users.Update)
+	adminRoutes.POST("/users/:user_id/suspend", middlewares.RequirePermission(access.PermissionUsersSuspend), admin.Suspend)
+	adminRoutes.POST("/users/:user_id/activate", middlewares.RequirePermission(access.PermissionUsersSuspend), admin.Activate)
+	adminRoutes.PUT("/users/:user_id/role", middlewares.RequirePermission(access.PermissionRolesChange), admin.ChangeRole)
+	adminRoutes.POST("/impersonations", middlewares.RequirePermission(access.PermissionUsersImpersonate), admin.Impersonate)
+	adminRoutes.GET("/users/:user_id/plans", middlewares.RequirePermission(access.PermissionPlansManage), plans.History)
+	adminRoutes.POST("/users/:user_id/plans", middlewares.RequirePermission(access.PermissionPlansManage), plans.Assign)
+	adminRoutes.GET("/webhooks", middlewares.RequirePermission(access.PermissionWebhooksManage), webhooks.List)
+	adminRoutes.POST("/webhooks", middlewares.RequirePermission(access.PermissionWebhooksManage), webhooks.Create)
+	adminRoutes.DELETE("/webhooks/:webhook_id", middlewares.RequirePermission(access.PermissionWebhooksManage), webhooks.Delete)
+	adminRoutes.GET("/webhooks/:webhook_id/deliveries", middlewares.RequirePermission(access.PermissionWebhooksManage), webhooks.Deliveries)
+	adminRoutes.GET("/audit", middlewares.RequirePermission(access.PermissionAuditRead), audit.List)
+	adminRoutes.GET("/audit/export", middlewares.RequirePermission(access.PermissionAuditRead), audit.Export)
+
+	// The /internal routes serve other services, through accounts with the service
+	// role.
+	internalRoutes := router.Group("/internal", middlewares.ForbidImpersonation, middlewares.RequirePermission(access.PermissionInternalCall))
+	internalRoutes.GET("/users/:user_id", users.GetInternal)
+	internalRoutes.GET("/users/:user_id/can-notify", notifications.CanNotify)
+	internalRoutes.POST("/users/:user_id/notification-settings/:channel/verify", notifications.Verify)
+	internalRoutes.GET("/alerts/active", alerts.StreamActive)
 }
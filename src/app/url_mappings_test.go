@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/middlewares"
+	"tokenalert_user-api/src/services"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var mapUrlsOnce sync.Once
+
+// mappedRouter returns router with the routes of mapUrls, mapped once for all the
+// tests of the package.
+func mappedRouter() http.Handler {
+	mapUrlsOnce.Do(mapUrls)
+	return router
+}
+
+// routePath fills the parameters of a route pattern, such as /admin/users/:user_id,
+// with id.
+func routePath(pattern string, id string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = id
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// gatedRoute tells whether path is one of the routes asking for a permission.
+func gatedRoute(path string) bool {
+	for _, prefix := range []string{"/admin/", "/internal/", "/debug/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGatedRoutesNeedACaller(t *testing.T) {
+	handler := mappedRouter()
+
+	checked := map[string]int{}
+	for _, route := range router.Routes() {
+		if !gatedRoute(route.Path) {
+			continue
+		}
+		checked[strings.SplitN(route.Path, "/", 3)[1]]++
+		response := httptest.NewRecorder()
+		request := httptest.NewRequest(route.Method, routePath(route.Path, "7"), strings.NewReader("{}"))
+		handler.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusUnauthorized, response.Code, route.Method+" "+route.Path)
+	}
+	assert.NotEqual(t, 0, checked["admin"])
+	assert.NotEqual(t, 0, checked["internal"])
+	assert.NotEqual(t, 0, checked["debug"])
+}
+
+type adminServiceMock struct {
+	roles map[int64]string
+}
+
+func (m *adminServiceMock) Authorize(ctx context.Context, callerId int64, permission string) (*users.User, rest_errors.RestErr) {
+	role, ok := m.roles[callerId]
+	if !ok {
+		return nil, rest_errors.NewUnauthorizedError("unknown caller")
+	}
+	if !access.Can(role, permission) {
+		return nil, rest_errors.NewRestError("missing permission "+permission, http.StatusForbidden, "forbidden", nil)
+	}
+	return &users.User{Id: callerId, Role: role, Status: users.StatusActive}, nil
+}
+
+func (m *adminServiceMock) SearchUsers(context.Context, users.Filter) (users.Users, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (m *adminServiceMock) ChangeStatus(context.Context, int64, string) (*users.User, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (m *adminServiceMock) ChangeRole(context.Context, int64, access.RoleRequest) (*users.User, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func TestUserRoutesRefuseOtherUsers(t *testing.T) {
+	handler := mappedRouter()
+	previous := services.AdminService
+	services.AdminService = &adminServiceMock{roles: map[int64]string{7: access.RoleUser, 8: access.RoleUser}}
+	t.Cleanup(func() {
+		services.AdminService = previous
+	})
+
+	checked := 0
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, "/users/:user_id") {
+			continue
+		}
+		checked++
+		for callerId, status := range map[string]int{"": http.StatusUnauthorized, "8": http.StatusForbidden} {
+			response := httptest.NewRecorder()
+			request := httptest.NewRequest(route.Method, routePath(route.Path, "7"), strings.NewReader("{}"))
+			if callerId != "" {
+				request.Header.Set(middlewares.CallerIdHeader, callerId)
+			}
+			handler.ServeHTTP(response, request)
+
+			assert.Equal(t, status, response.Code, "caller "+callerId+" on "+route.Method+" "+route.Path)
+		}
+	}
+	assert.NotEqual(t, 0, checked)
+}
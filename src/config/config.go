@@ -0,0 +1,236 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/impersonation"
+	"tokenalert_user-api/src/tracing"
+)
+
+const (
+	usersApiConfig = "users_api_config"
+	configFlag     = "config"
+
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+var (
+	// Current is the configuration the API was started with, nil before.
+	Current *Config
+
+	lookupEnv = os.LookupEnv
+)
+
+// Config gathers the settings of every part of the API. Each one can come from a
+// YAML or TOML file, the environment or a flag, the latter winning; see settings for
+// their names.
+type Config struct {
+	Server     ServerConfig
+	Migrations MigrationsConfig
+	Database   users_db.Config
+	Cache      cache.Config
+	EventBus   bus.Config
+	Tracing    tracing.Config
+	// Impersonation signs the tokens admins act as users with.
+	Impersonation impersonation.Config
+
+	origins map[string]origin
+}
+
+// ServerConfig sets the HTTP server up. Zero read, write or idle timeouts mean none.
+type ServerConfig struct {
+	Address      string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// DrainDelay is how long the API keeps serving on SIGTERM or SIGINT while
+	// reporting itself not ready, for load balancers to stop routing requests to it.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds the wait for the requests in flight once DrainDelay is
+	// over.
+	ShutdownTimeout time.Duration
+}
+
+type MigrationsConfig struct {
+	// Auto applies the pending migrations on start.
+	Auto bool
+}
+
+// origin records where a setting was read from and, for a secret read from a file,
+// which one.
+type origin struct {
+	source string
+	file   string
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    time.Minute,
+			IdleTimeout:     2 * time.Minute,
+			DrainDelay:      5 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Database:      users_db.DefaultConfig(),
+		Cache:         cache.DefaultConfig(),
+		EventBus:      bus.DefaultConfig(),
+		Tracing:       tracing.DefaultConfig(),
+		Impersonation: impersonation.DefaultConfig(),
+		origins:       map[string]origin{},
+	}
+}
+
+// Load reads the configuration from, in increasing order of precedence, the file
+// given with -config or users_api_config, the environment and the flags in args. It
+// returns the arguments left after the flags, and the first invalid setting found.
+func Load(args []string) (*Config, []string, error) {
+	flagValues, rest, err := parseFlags(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := Default()
+	path, fromFlag := flagValues[configFlag]
+	if !fromFlag {
+		path, _ = lookupEnv(usersApiConfig)
+	}
+	if path != "" {
+		fileValues, err := readFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := config.apply(SourceFile, fileValues); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := config.apply(SourceEnv, environment()); err != nil {
+		return nil, nil, err
+	}
+	if err := config.apply(SourceFlag, flagValues); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return config, rest, nil
+}
+
+func (c *Config) Validate() error {
+	if strings.TrimSpace(c.Server.Address) == "" {
+		return errors.New("server.address can not be empty")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		return errors.New("server.shutdown_timeout must be above zero")
+	}
+	if err := c.Database.Validate(); err != nil {
+		return err
+	}
+	if err := c.Cache.Validate(); err != nil {
+		return err
+	}
+	if err := c.EventBus.Validate(); err != nil {
+		return err
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
+	return c.Impersonation.Validate()
+}
+
+// apply sets the settings found in values, keyed by setting key for files and flags
+// and by variable name for the environment. A secret can be given as the path of a
+// file holding it instead, under the same name ending in _file.
+func (c *Config) apply(source string, values map[string]string) error {
+	for _, setting := range settings {
+		name := setting.key
+		if source == SourceEnv {
+			name = setting.env
+		}
+
+		value, hasValue := values[name]
+		file, hasFile := values[name+secretFileSuffix]
+		if hasValue && hasFile {
+			return fmt.Errorf("set either %s or %s", name, name+secretFileSuffix)
+		}
+		if !hasValue && !hasFile {
+			continue
+		}
+		if hasFile {
+			var err error
+			if value, err = readSecret(file); err != nil {
+				return fmt.Errorf("%s: %w", name+secretFileSuffix, err)
+			}
+		}
+
+		if err := parse(value, setting.field(c)); err != nil {
+			if setting.secret {
+				return fmt.Errorf("invalid %s", name)
+			}
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+		c.origins[setting.key] = origin{source: source, file: file}
+	}
+	return nil
+}
+
+// environment collects the variables of the settings. Empty ones count as unset, so
+// a variable declared without a value keeps the default.
+func environment() map[string]string {
+	values := map[string]string{}
+	for _, setting := range settings {
+		names := []string{setting.env}
+		if setting.secret {
+			names = append(names, setting.env+secretFileSuffix)
+		}
+		for _, name := range names {
+			if value, ok := lookupEnv(name); ok && value != "" {
+				values[name] = value
+			}
+		}
+	}
+	return values
+}
+
+// parseFlags reads -config and one flag per setting, named after its key, such as
+// -database.host. Only the flags given end up in the result.
+func parseFlags(args []string) (map[string]string, []string, error) {
+	flags := flag.NewFlagSet("users-api", flag.ContinueOnError)
+	flags.String(configFlag, "", "YAML or TOML file to read the configuration from (env "+usersApiConfig+")")
+	for _, setting := range settings {
+		flags.String(setting.key, "", setting.usage())
+		if setting.secret {
+			flags.String(setting.key+secretFileSuffix, "", "file holding "+setting.key)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	values := map[string]string{}
+	flags.Visit(func(given *flag.Flag) {
+		values[given.Name] = given.Value.String()
+	})
+	return values, flags.Args(), nil
+}
+
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
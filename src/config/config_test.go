@@ -0,0 +1,242 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withEnv(t *testing.T, env map[string]string) {
+	lookupEnv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	t.Cleanup(func() { lookupEnv = os.LookupEnv })
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	withEnv(t, map[string]string{})
+
+	config, rest, err := Load(nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, ":8080", config.Server.Address)
+	assert.Equal(t, 5*time.Second, config.Server.DrainDelay)
+	assert.Equal(t, 20*time.Second, config.Server.ShutdownTimeout)
+	assert.Equal(t, "mysql", config.Database.Driver)
+	assert.Equal(t, 20, config.Database.Pool.MaxOpenConns)
+	assert.Equal(t, 3*time.Second, config.Database.Timeouts.Default)
+	assert.Equal(t, "tokenalert", config.EventBus.Subjects.Prefix)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "users-api.yaml", `
+server:
+  address: ":9000"
+database:
+  host: file-host:3306
+  schema: users
+  max_open_conns: 50
+  replica_hosts:
+    - replica-1:3306
+    - replica-2:3306
+  operation_timeouts:
+    users.get: 500ms
+event_bus:
+  subjects:
+    user.created: crm.signups
+`)
+	withEnv(t, map[string]string{"users_api_config": path, "mysql_users_host": "env-host:3306", "mysql_users_max_open_conns": "40", "mysql_users_schema": ""})
+
+	config, rest, err := Load([]string{"-database.max_open_conns=30", "up"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"up"}, rest)
+	assert.Equal(t, ":9000", config.Server.Address)
+	assert.Equal(t, "env-host:3306", config.Database.Host)
+	assert.Equal(t, "users", config.Database.Schema)
+	assert.Equal(t, 30, config.Database.Pool.MaxOpenConns)
+	assert.Equal(t, []string{"replica-1:3306", "replica-2:3306"}, config.Database.ReplicaHosts)
+	assert.Equal(t, 500*time.Millisecond, config.Database.Timeouts.For("users.get"))
+	assert.Equal(t, "crm.signups", config.EventBus.Subjects.For("user.created"))
+
+	entries := config.Redacted()
+	assert.Equal(t, Entry{Value: ":9000", Source: SourceFile}, entries["server.address"])
+	assert.Equal(t, Entry{Value: "env-host:3306", Source: SourceEnv}, entries["database.host"])
+	assert.Equal(t, Entry{Value: "30", Source: SourceFlag}, entries["database.max_open_conns"])
+	assert.Equal(t, Entry{Value: "1m0s", Source: SourceDefault}, entries["cache.ttl"])
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "users-api.toml", `
+[database]
+driver = "sqlite"
+dsn = "file:users.db"
+connect_attempts = 3
+
+[cache]
+url = "memory"
+ttl = "30s"
+`)
+	withEnv(t, map[string]string{})
+
+	config, _, err := Load([]string{"-config", path})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "sqlite", config.Database.Driver)
+	assert.Equal(t, "file:users.db", config.Database.DSN)
+	assert.Equal(t, 3, config.Database.Retry.Attempts)
+	assert.Equal(t, "memory", config.Cache.Url)
+	assert.Equal(t, 30*time.Second, config.Cache.TTL)
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	secret := writeFile(t, "mysql_password", "s3cret\n")
+	withEnv(t, map[string]string{"mysql_users_password_file": secret})
+
+	config, _, err := Load(nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cret", config.Database.Password)
+	assert.Equal(t, Entry{Value: "[redacted]", Source: SourceEnv, File: secret}, config.Redacted()["database.password"])
+}
+
+func TestLoadSecretFromFileAndValue(t *testing.T) {
+	withEnv(t, map[string]string{"mysql_users_password": "s3cret", "mysql_users_password_file": "/run/secrets/mysql_password"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "set either mysql_users_password or mysql_users_password_file", err.Error())
+}
+
+func TestLoadInvalidValueNamesItsSource(t *testing.T) {
+	withEnv(t, map[string]string{"mysql_users_max_open_conns": "many"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid mysql_users_max_open_conns "many"`, err.Error())
+
+	withEnv(t, map[string]string{})
+	_, _, err = Load([]string{"-cache.ttl=soon"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid cache.ttl "soon"`, err.Error())
+}
+
+func TestLoadUnknownFileSetting(t *testing.T) {
+	path := writeFile(t, "users-api.yaml", "database:\n  hots: mysql:3306\n")
+	withEnv(t, map[string]string{})
+
+	_, _, err := Load([]string{"-config=" + path})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, path+`: unknown setting "database.hots"`, err.Error())
+}
+
+func TestLoadUnsupportedFile(t *testing.T) {
+	path := writeFile(t, "users-api.json", "{}")
+	withEnv(t, map[string]string{})
+
+	_, _, err := Load([]string{"-config=" + path})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `unsupported config file "`+path+`", use .yaml, .yml or .toml`, err.Error())
+}
+
+func TestLoadValidates(t *testing.T) {
+	withEnv(t, map[string]string{"users_db_driver": "postgres"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "database.dsn is required for postgres", err.Error())
+}
+
+func TestLoadRejectsZeroShutdownTimeout(t *testing.T) {
+	withEnv(t, map[string]string{"users_api_shutdown_timeout": "0s"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "server.shutdown_timeout must be above zero", err.Error())
+}
+
+func TestLoadRejectsNegativeDrainDelay(t *testing.T) {
+	withEnv(t, map[string]string{"users_api_drain_delay": "-1s"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid users_api_drain_delay "-1s"`, err.Error())
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	withEnv(t, map[string]string{"users_cache_url": "redis://:s3cret@cache:6379", "mysql_users_username": "users"})
+
+	config, _, err := Load(nil)
+
+	assert.Nil(t, err)
+	entries := config.Redacted()
+	assert.Equal(t, "[redacted]", entries["cache.url"].Value)
+	assert.Equal(t, "users", entries["database.username"].Value)
+	assert.Equal(t, "", entries["database.password"].Value)
+	assert.Equal(t, len(settings), len(entries))
+}
+
+func TestLoadTracing(t *testing.T) {
+	withEnv(t, map[string]string{"tracing_exporter": "otlp", "tracing_endpoint": "https://collector:4318/v1/traces",
+		"tracing_headers": "authorization=Bearer abc", "tracing_sample_ratio": "0.25"})
+
+	config, _, err := Load(nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "otlp", config.Tracing.Exporter)
+	assert.Equal(t, map[string]string{"authorization": "Bearer abc"}, config.Tracing.Headers)
+	assert.Equal(t, 0.25, config.Tracing.SampleRatio)
+	assert.Equal(t, "users-api", config.Tracing.ServiceName)
+	assert.Equal(t, "[redacted]", config.Redacted()["tracing.headers"].Value)
+}
+
+func TestLoadRejectsSampleRatioAboveOne(t *testing.T) {
+	withEnv(t, map[string]string{"tracing_sample_ratio": "1.5"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "tracing.sample_ratio must be between 0 and 1", err.Error())
+}
+
+func TestLoadImpersonation(t *testing.T) {
+	withEnv(t, map[string]string{"impersonation_secret": strings.Repeat("s", 32), "impersonation_ttl": "5m"})
+
+	config, _, err := Load(nil)
+
+	assert.Nil(t, err)
+	assert.True(t, config.Impersonation.Enabled())
+	assert.Equal(t, 5*time.Minute, config.Impersonation.TTL)
+	assert.Equal(t, "[redacted]", config.Redacted()["impersonation.secret"].Value)
+}
+
+func TestLoadRejectsShortImpersonationSecret(t *testing.T) {
+	withEnv(t, map[string]string{"impersonation_secret": "short"})
+
+	_, _, err := Load(nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "impersonation.secret must be at least 32 characters long", err.Error())
+}
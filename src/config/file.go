@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads a YAML or TOML file, picked by extension, whose sections follow the
+// setting keys:
+//
+//	database:
+//	  host: mysql:3306
+//	  password_file: /run/secrets/mysql_password
+//	  operation_timeouts:
+//	    users.get: 500ms
+//
+// Settings holding lists or maps can be written as such. Unknown keys are refused so
+// that a typo does not go unnoticed.
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &tree)
+	case ".toml":
+		err = toml.Unmarshal(content, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file %q, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flatten("", tree, values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten walks tree down to the settings, storing their text form under their key.
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) error {
+	for name, node := range tree {
+		key := prefix + name
+		if _, ok := lookupSetting(key); ok {
+			text, err := text(node)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			values[key] = text
+			continue
+		}
+
+		section, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+		if err := flatten(key+".", section, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func text(node interface{}) (string, error) {
+	switch value := node.(type) {
+	case nil:
+		return "", nil
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(value))
+		for name, item := range value {
+			itemText, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, name+"="+itemText)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			itemText, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, itemText)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return scalar(node)
+}
+
+func scalar(node interface{}) (string, error) {
+	switch node.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("unexpected %T", node)
+	}
+	return fmt.Sprint(node), nil
+}
@@ -0,0 +1,31 @@
+package config
+
+const (
+	redacted = "[redacted]"
+)
+
+// Entry is a setting as shown to operators.
+type Entry struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// File is the file a secret was read from.
+	File string `json:"file,omitempty"`
+}
+
+// Redacted returns every setting by key with its value and where it came from. The
+// values of secrets are replaced, so the result can be shown to operators.
+func (c *Config) Redacted() map[string]Entry {
+	entries := make(map[string]Entry, len(settings))
+	for _, setting := range settings {
+		entry := Entry{Value: format(setting.field(c)), Source: SourceDefault}
+		if origin, ok := c.origins[setting.key]; ok {
+			entry.Source = origin.source
+			entry.File = origin.file
+		}
+		if setting.secret && entry.Value != "" {
+			entry.Value = redacted
+		}
+		entries[setting.key] = entry
+	}
+	return entries
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	secretFileSuffix = "_file"
+)
+
+// setting ties a key, used in files and as flag name, and an environment variable to
+// the field of Config they set. Secrets are redacted when the configuration is shown
+// and may be read from a file.
+type setting struct {
+	key    string
+	env    string
+	secret bool
+	field  func(*Config) interface{}
+}
+
+func (s setting) usage() string {
+	return "env " + s.env
+}
+
+var settings = []setting{
+	{key: "server.address", env: "users_api_address", field: func(c *Config) interface{} { return &c.Server.Address }},
+	{key: "server.read_timeout", env: "users_api_read_timeout", field: func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{key: "server.write_timeout", env: "users_api_write_timeout", field: func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{key: "server.idle_timeout", env: "users_api_idle_timeout", field: func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+	{key: "server.drain_delay", env: "users_api_drain_delay", field: func(c *Config) interface{} { return &c.Server.DrainDelay }},
+	{key: "server.shutdown_timeout", env: "users_api_shutdown_timeout", field: func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+	{key: "migrations.auto", env: "mysql_users_auto_migrate", field: func(c *Config) interface{} { return &c.Migrations.Auto }},
+
+	{key: "database.driver", env: "users_db_driver", field: func(c *Config) interface{} { return &c.Database.Driver }},
+	{key: "database.dsn", env: "users_db_dsn", secret: true, field: func(c *Config) interface{} { return &c.Database.DSN }},
+	{key: "database.username", env: "mysql_users_username", field: func(c *Config) interface{} { return &c.Database.Username }},
+	{key: "database.password", env: "mysql_users_password", secret: true, field: func(c *Config) interface{} { return &c.Database.Password }},
+	{key: "database.host", env: "mysql_users_host", field: func(c *Config) interface{} { return &c.Database.Host }},
+	{key: "database.schema", env: "mysql_users_schema", field: func(c *Config) interface{} { return &c.Database.Schema }},
+	{key: "database.tls", env: "mysql_users_tls", field: func(c *Config) interface{} { return &c.Database.TLS.Mode }},
+	{key: "database.tls_ca", env: "mysql_users_tls_ca", field: func(c *Config) interface{} { return &c.Database.TLS.CA }},
+	{key: "database.tls_cert", env: "mysql_users_tls_cert", field: func(c *Config) interface{} { return &c.Database.TLS.Cert }},
+	{key: "database.tls_key", env: "mysql_users_tls_key", field: func(c *Config) interface{} { return &c.Database.TLS.Key }},
+	{key: "database.tls_server_name", env: "mysql_users_tls_server_name", field: func(c *Config) interface{} { return &c.Database.TLS.ServerName }},
+	{key: "database.max_open_conns", env: "mysql_users_max_open_conns", field: func(c *Config) interface{} { return &c.Database.Pool.MaxOpenConns }},
+	{key: "database.max_idle_conns", env: "mysql_users_max_idle_conns", field: func(c *Config) interface{} { return &c.Database.Pool.MaxIdleConns }},
+	{key: "database.conn_max_lifetime", env: "mysql_users_conn_max_lifetime", field: func(c *Config) interface{} { return &c.Database.Pool.ConnMaxLifetime }},
+	{key: "database.conn_max_idle_time", env: "mysql_users_conn_max_idle_time", field: func(c *Config) interface{} { return &c.Database.Pool.ConnMaxIdleTime }},
+	{key: "database.connect_attempts", env: "mysql_users_connect_attempts", field: func(c *Config) interface{} { return &c.Database.Retry.Attempts }},
+	{key: "database.connect_backoff", env: "mysql_users_connect_backoff", field: func(c *Config) interface{} { return &c.Database.Retry.Backoff }},
+	{key: "database.connect_max_backoff", env: "mysql_users_connect_max_backoff", field: func(c *Config) interface{} { return &c.Database.Retry.MaxBackoff }},
+	{key: "database.timeout", env: "mysql_users_timeout", field: func(c *Config) interface{} { return &c.Database.Timeouts.Default }},
+	{key: "database.operation_timeouts", env: "mysql_users_operation_timeouts", field: func(c *Config) interface{} { return &c.Database.Timeouts.Operations }},
+	{key: "database.replica_hosts", env: "mysql_users_replica_hosts", field: func(c *Config) interface{} { return &c.Database.ReplicaHosts }},
+	{key: "database.replica_check_interval", env: "mysql_users_replica_check_interval", field: func(c *Config) interface{} { return &c.Database.ReplicaCheckInterval }},
+
+	{key: "cache.url", env: "users_cache_url", secret: true, field: func(c *Config) interface{} { return &c.Cache.Url }},
+	{key: "cache.size", env: "users_cache_size", field: func(c *Config) interface{} { return &c.Cache.Size }},
+	{key: "cache.ttl", env: "users_cache_ttl", field: func(c *Config) interface{} { return &c.Cache.TTL }},
+	{key: "cache.negative_ttl", env: "users_cache_negative_ttl", field: func(c *Config) interface{} { return &c.Cache.NegativeTTL }},
+
+	{key: "event_bus.url", env: "event_bus_url", secret: true, field: func(c *Config) interface{} { return &c.EventBus.Url }},
+	{key: "event_bus.subject_prefix", env: "event_bus_subject_prefix", field: func(c *Config) interface{} { return &c.EventBus.Subjects.Prefix }},
+	{key: "event_bus.subjects", env: "event_bus_subjects", field: func(c *Config) interface{} { return &c.EventBus.Subjects.Overrides }},
+
+	{key: "tracing.exporter", env: "tracing_exporter", field: func(c *Config) interface{} { return &c.Tracing.Exporter }},
+	{key: "tracing.endpoint", env: "tracing_endpoint", field: func(c *Config) interface{} { return &c.Tracing.Endpoint }},
+	{key: "tracing.headers", env: "tracing_headers", secret: true, field: func(c *Config) interface{} { return &c.Tracing.Headers }},
+	{key: "tracing.service_name", env: "tracing_service_name", field: func(c *Config) interface{} { return &c.Tracing.ServiceName }},
+	{key: "tracing.sample_ratio", env: "tracing_sample_ratio", field: func(c *Config) interface{} { return &c.Tracing.SampleRatio }},
+
+	{key: "impersonation.secret", env: "impersonation_secret", secret: true, field: func(c *Config) interface{} { return &c.Impersonation.Secret }},
+	{key: "impersonation.ttl", env: "impersonation_ttl", field: func(c *Config) interface{} { return &c.Impersonation.TTL }},
+}
+
+// lookupSetting returns the setting with the given key. Keys of secrets ending in
+// _file are found as well.
+func lookupSetting(key string) (setting, bool) {
+	for _, candidate := range settings {
+		if candidate.key == key || (candidate.secret && candidate.key+secretFileSuffix == key) {
+			return candidate, true
+		}
+	}
+	return setting{}, false
+}
+
+// parse sets the field target points to from its text form. Lists are written
+// "a,b" and maps "key=value,key=value". Numbers and durations can not be negative.
+func parse(value string, target interface{}) error {
+	value = strings.TrimSpace(value)
+	switch field := target.(type) {
+	case *string:
+		*field = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return errors.New("invalid number")
+		}
+		*field = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			return errors.New("invalid number")
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *[]string:
+		*field = splitList(value)
+	case *map[string]string:
+		pairs, err := splitPairs(value)
+		if err != nil {
+			return err
+		}
+		*field = pairs
+	case *map[string]time.Duration:
+		pairs, err := splitPairs(value)
+		if err != nil {
+			return err
+		}
+		durations := make(map[string]time.Duration, len(pairs))
+		for name, text := range pairs {
+			if durations[name], err = parseDuration(text); err != nil {
+				return err
+			}
+		}
+		*field = durations
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
+
+// format returns the text form of the field target points to, as parse reads it.
+func format(target interface{}) string {
+	switch field := target.(type) {
+	case *string:
+		return *field
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *int:
+		return strconv.Itoa(*field)
+	case *float64:
+		return strconv.FormatFloat(*field, 'g', -1, 64)
+	case *time.Duration:
+		return field.String()
+	case *[]string:
+		return strings.Join(*field, ",")
+	case *map[string]string:
+		return joinPairs(*field, func(value string) string { return value })
+	case *map[string]time.Duration:
+		return joinPairs(*field, time.Duration.String)
+	}
+	return fmt.Sprint(target)
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, errors.New("invalid duration")
+	}
+	return duration, nil
+}
+
+func splitList(value string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func splitPairs(value string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, pair := range splitList(value) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid pair %q", pair)
+		}
+		result[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return result, nil
+}
+
+func joinPairs[V any](pairs map[string]V, format func(V) string) string {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = key + "=" + format(pairs[key])
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/impersonation"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+func getUserId(userIdParam string) (int64, rest_errors.RestErr) {
+	userId, userErr := strconv.ParseInt(userIdParam, 10, 64)
+	if userErr != nil {
+		return 0, rest_errors.NewBadRequestError("user id should be a number")
+	}
+	return userId, nil
+}
+
+func getFilter(c *gin.Context) (users.Filter, rest_errors.RestErr) {
+	filter := users.Filter{
+		Status: c.Query("status"),
+		Role:   c.Query("role"),
+		Email:  c.Query("email"),
+	}
+	if text := c.Query("before_id"); text != "" {
+		beforeId, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return filter, rest_errors.NewBadRequestError("before_id should be a number")
+		}
+		filter.BeforeId = beforeId
+	}
+	if text := c.Query("limit"); text != "" {
+		limit, err := strconv.Atoi(text)
+		if err != nil {
+			return filter, rest_errors.NewBadRequestError("limit should be a number")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// ListUsers returns a page of users, newest first, with their status and role.
+func ListUsers(c *gin.Context) {
+	filter, filterErr := getFilter(c)
+	if filterErr != nil {
+		c.JSON(filterErr.Status(), filterErr)
+		return
+	}
+
+	result, searchErr := services.AdminService.SearchUsers(c.Request.Context(), filter)
+	if searchErr != nil {
+		c.JSON(searchErr.Status(), searchErr)
+		return
+	}
+	c.JSON(http.StatusOK, result.Marshall(false))
+}
+
+func Suspend(c *gin.Context) {
+	changeStatus(c, users.StatusSuspended)
+}
+
+func Activate(c *gin.Context) {
+	changeStatus(c, users.StatusActive)
+}
+
+func changeStatus(c *gin.Context, status string) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	user, changeErr := services.AdminService.ChangeStatus(c.Request.Context(), userId, status)
+	if changeErr != nil {
+		c.JSON(changeErr.Status(), changeErr)
+		return
+	}
+	c.JSON(http.StatusOK, user.Marshall(false))
+}
+
+func ChangeRole(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var request access.RoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+
+	user, changeErr := services.AdminService.ChangeRole(c.Request.Context(), userId, request)
+	if changeErr != nil {
+		c.JSON(changeErr.Status(), changeErr)
+		return
+	}
+	c.JSON(http.StatusOK, user.Marshall(false))
+}
+
+// Impersonate issues a token for the caller to act as a user; see
+// middlewares.Impersonation.
+func Impersonate(c *gin.Context) {
+	var request impersonation.Request
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+
+	grant, startErr := services.ImpersonationService.Start(c.Request.Context(), request)
+	if startErr != nil {
+		c.JSON(startErr.Status(), startErr)
+		return
+	}
+	c.JSON(http.StatusCreated, grant)
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/impersonation"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	searchUsersFunc  func(users.Filter) (users.Users, rest_errors.RestErr)
+	changeStatusFunc func(int64, string) (*users.User, rest_errors.RestErr)
+	changeRoleFunc   func(int64, access.RoleRequest) (*users.User, rest_errors.RestErr)
+	startFunc        func(impersonation.Request) (*impersonation.Grant, rest_errors.RestErr)
+)
+
+type adminServiceMock struct{}
+
+func (*adminServiceMock) Authorize(ctx context.Context, callerId int64, permission string) (*users.User, rest_errors.RestErr) {
+	return &users.User{Id: callerId}, nil
+}
+
+func (*adminServiceMock) SearchUsers(ctx context.Context, filter users.Filter) (users.Users, rest_errors.RestErr) {
+	return searchUsersFunc(filter)
+}
+
+func (*adminServiceMock) ChangeStatus(ctx context.Context, userId int64, status string) (*users.User, rest_errors.RestErr) {
+	return changeStatusFunc(userId, status)
+}
+
+func (*adminServiceMock) ChangeRole(ctx context.Context, userId int64, request access.RoleRequest) (*users.User, rest_errors.RestErr) {
+	return changeRoleFunc(userId, request)
+}
+
+type impersonationServiceMock struct{}
+
+func (*impersonationServiceMock) Start(ctx context.Context, request impersonation.Request) (*impersonation.Grant, rest_errors.RestErr) {
+	return startFunc(request)
+}
+
+func (*impersonationServiceMock) Verify(ctx context.Context, token string, callerId int64) (*impersonation.Claims, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (*impersonationServiceMock) Restrict(ctx context.Context, operation string) rest_errors.RestErr {
+	return nil
+}
+
+func TestListUsersPassesFilter(t *testing.T) {
+	var received users.Filter
+	searchUsersFunc = func(filter users.Filter) (users.Users, rest_errors.RestErr) {
+		received = filter
+		return users.Users{{Id: 7, Email: "john@mail.com", Status: users.StatusSuspended, Role: access.RoleUser, Password: "hash"}}, nil
+	}
+	services.AdminService = &adminServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/users?status=suspended&role=user&before_id=10&limit=5", nil)
+
+	ListUsers(c)
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Equal(t, users.Filter{Status: users.StatusSuspended, Role: access.RoleUser, BeforeId: 10, Limit: 5}, received)
+	assert.NotContains(t, response.Body.String(), "hash")
+	var result []users.PublicUser
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, access.RoleUser, result[0].Role)
+}
+
+func TestListUsersInvalidLimitReturnBadRequest(t *testing.T) {
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/users?limit=all", nil)
+
+	ListUsers(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestSuspendAndActivate(t *testing.T) {
+	var statuses []string
+	changeStatusFunc = func(userId int64, status string) (*users.User, rest_errors.RestErr) {
+		statuses = append(statuses, status)
+		return &users.User{Id: userId, Status: status}, nil
+	}
+	services.AdminService = &adminServiceMock{}
+
+	for _, handler := range []gin.HandlerFunc{Suspend, Activate} {
+		response := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(response)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/admin/users/7/suspend", nil)
+		c.Params = gin.Params{{Key: "user_id", Value: "7"}}
+
+		handler(c)
+
+		assert.EqualValues(t, http.StatusOK, response.Code)
+	}
+	assert.Equal(t, []string{users.StatusSuspended, users.StatusActive}, statuses)
+}
+
+func TestChangeRole(t *testing.T) {
+	changeRoleFunc = func(userId int64, request access.RoleRequest) (*users.User, rest_errors.RestErr) {
+		if request.Role == "root" {
+			return nil, rest_errors.NewBadRequestError("invalid role root")
+		}
+		return &users.User{Id: userId, Role: request.Role}, nil
+	}
+	services.AdminService = &adminServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/admin/users/7/role", bytes.NewBufferString(`{"role":"support"}`))
+	c.Params = gin.Params{{Key: "user_id", Value: "7"}}
+
+	ChangeRole(c)
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"role":"support"`)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/admin/users/7/role", bytes.NewBufferString(`{"role":"root"}`))
+	c.Params = gin.Params{{Key: "user_id", Value: "7"}}
+
+	ChangeRole(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestImpersonate(t *testing.T) {
+	var received impersonation.Request
+	startFunc = func(request impersonation.Request) (*impersonation.Grant, rest_errors.RestErr) {
+		received = request
+		return &impersonation.Grant{Token: "token", UserId: request.UserId, ImpersonatorId: 1, ExpiresAt: "2024-03-01T10:15:00Z"}, nil
+	}
+	services.ImpersonationService = &impersonationServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/impersonations", bytes.NewBufferString(`{"user_id":7,"reason":"ticket 42"}`))
+
+	Impersonate(c)
+
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.Equal(t, impersonation.Request{UserId: 7, Reason: "ticket 42"}, received)
+	var grant impersonation.Grant
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &grant))
+	assert.Equal(t, "token", grant.Token)
+	assert.Equal(t, int64(7), grant.UserId)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/impersonations", bytes.NewBufferString(`{"user_id":"7"}`))
+
+	Impersonate(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
@@ -0,0 +1,174 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+func getId(idParam string, name string) (int64, rest_errors.RestErr) {
+	id, idErr := strconv.ParseInt(idParam, 10, 64)
+	if idErr != nil {
+		return 0, rest_errors.NewBadRequestError(name + " id should be a number")
+	}
+	return id, nil
+}
+
+func getUserAndAlertIds(c *gin.Context) (int64, int64, rest_errors.RestErr) {
+	userId, idErr := getId(c.Param("user_id"), "user")
+	if idErr != nil {
+		return 0, 0, idErr
+	}
+	alertId, idErr := getId(c.Param("alert_id"), "alert")
+	if idErr != nil {
+		return 0, 0, idErr
+	}
+	return userId, alertId, nil
+}
+
+func Create(c *gin.Context) {
+	userId, idErr := getId(c.Param("user_id"), "user")
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var rule alerts.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	rule.UserId = userId
+
+	result, saveErr := services.AlertRulesService.CreateAlertRule(c.Request.Context(), rule)
+	if saveErr != nil {
+		c.JSON(saveErr.Status(), saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func Get(c *gin.Context) {
+	userId, alertId, idErr := getUserAndAlertIds(c)
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	rule, getErr := services.AlertRulesService.GetAlertRule(c.Request.Context(), userId, alertId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func List(c *gin.Context) {
+	userId, idErr := getId(c.Param("user_id"), "user")
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	rules, getErr := services.AlertRulesService.GetUserAlertRules(c.Request.Context(), userId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func Update(c *gin.Context) {
+	userId, alertId, idErr := getUserAndAlertIds(c)
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var rule alerts.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	rule.Id = alertId
+	rule.UserId = userId
+
+	result, updateErr := services.AlertRulesService.UpdateAlertRule(c.Request.Context(), rule)
+	if updateErr != nil {
+		c.JSON(updateErr.Status(), updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func setEnabled(c *gin.Context, enabled bool) {
+	userId, alertId, idErr := getUserAndAlertIds(c)
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	result, updateErr := services.AlertRulesService.SetAlertRuleEnabled(c.Request.Context(), userId, alertId, enabled)
+	if updateErr != nil {
+		c.JSON(updateErr.Status(), updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func Enable(c *gin.Context) {
+	setEnabled(c, true)
+}
+
+func Disable(c *gin.Context) {
+	setEnabled(c, false)
+}
+
+func Delete(c *gin.Context) {
+	userId, alertId, idErr := getUserAndAlertIds(c)
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	if deleteErr := services.AlertRulesService.DeleteAlertRule(c.Request.Context(), userId, alertId); deleteErr != nil {
+		c.JSON(deleteErr.Status(), deleteErr)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// StreamActive writes every active rule as newline delimited JSON, flushing after each
+// one so the evaluator can start working before the whole table has been read.
+func StreamActive(c *gin.Context) {
+	encoder := json.NewEncoder(c.Writer)
+	streamErr := services.AlertRulesService.StreamActiveAlertRules(c.Request.Context(), func(rule alerts.AlertRule) error {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+		}
+		if err := encoder.Encode(rule); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if streamErr != nil {
+		if !c.Writer.Written() {
+			c.JSON(streamErr.Status(), streamErr)
+		}
+		return
+	}
+	if !c.Writer.Written() {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+	}
+}
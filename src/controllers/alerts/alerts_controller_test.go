@@ -0,0 +1,238 @@
+package alerts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	createAlertRuleFunc        func(alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr)
+	getAlertRuleFunc           func(int64, int64) (*alerts.AlertRule, rest_errors.RestErr)
+	getUserAlertRulesFunc      func(int64) (alerts.AlertRules, rest_errors.RestErr)
+	updateAlertRuleFunc        func(alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr)
+	setAlertRuleEnabledFunc    func(int64, int64, bool) (*alerts.AlertRule, rest_errors.RestErr)
+	deleteAlertRuleFunc        func(int64, int64) rest_errors.RestErr
+	streamActiveAlertRulesFunc func(func(alerts.AlertRule) error) rest_errors.RestErr
+)
+
+type alertRulesServiceMock struct{}
+
+func (*alertRulesServiceMock) CreateAlertRule(ctx context.Context, rule alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr) {
+	return createAlertRuleFunc(rule)
+}
+
+func (*alertRulesServiceMock) GetAlertRule(ctx context.Context, userId int64, ruleId int64) (*alerts.AlertRule, rest_errors.RestErr) {
+	return getAlertRuleFunc(userId, ruleId)
+}
+
+func (*alertRulesServiceMock) GetUserAlertRules(ctx context.Context, userId int64) (alerts.AlertRules, rest_errors.RestErr) {
+	return getUserAlertRulesFunc(userId)
+}
+
+func (*alertRulesServiceMock) UpdateAlertRule(ctx context.Context, rule alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr) {
+	return updateAlertRuleFunc(rule)
+}
+
+func (*alertRulesServiceMock) SetAlertRuleEnabled(ctx context.Context, userId int64, ruleId int64, enabled bool) (*alerts.AlertRule, rest_errors.RestErr) {
+	return setAlertRuleEnabledFunc(userId, ruleId, enabled)
+}
+
+func (*alertRulesServiceMock) DeleteAlertRule(ctx context.Context, userId int64, ruleId int64) rest_errors.RestErr {
+	return deleteAlertRuleFunc(userId, ruleId)
+}
+
+func (*alertRulesServiceMock) StreamActiveAlertRules(ctx context.Context, callback func(alerts.AlertRule) error) rest_errors.RestErr {
+	return streamActiveAlertRulesFunc(callback)
+}
+
+func TestAlertCreateOK(t *testing.T) {
+
+	createAlertRuleFunc = func(rule alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr) {
+		rule.Id = 12
+		return &rule, nil
+	}
+
+	services.AlertRulesService = &alertRulesServiceMock{}
+
+	body, _ := json.Marshal(alerts.AlertRule{Token: "btc", Type: alerts.TypePriceAbove, Threshold: 30000})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/users/1/alerts", bytes.NewBuffer(body))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "1"},
+	}
+
+	Create(c)
+
+	var ruleResponse alerts.AlertRule
+	error := json.Unmarshal(response.Body.Bytes(), &ruleResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, 12, ruleResponse.Id)
+	assert.EqualValues(t, 1, ruleResponse.UserId)
+}
+
+func TestAlertCreateBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/users/1/alerts", bytes.NewBufferString("{"))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "1"},
+	}
+
+	Create(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAlertGetBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/1/alerts/ABC", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "1"},
+		{Key: "alert_id", Value: "ABC"},
+	}
+
+	Get(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAlertListOK(t *testing.T) {
+
+	getUserAlertRulesFunc = func(userId int64) (alerts.AlertRules, rest_errors.RestErr) {
+		return alerts.AlertRules{{Id: 12, UserId: userId}, {Id: 13, UserId: userId}}, nil
+	}
+
+	services.AlertRulesService = &alertRulesServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/1/alerts", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "1"},
+	}
+
+	List(c)
+
+	var rulesResponse alerts.AlertRules
+	error := json.Unmarshal(response.Body.Bytes(), &rulesResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 2, len(rulesResponse))
+}
+
+func TestAlertDisableOK(t *testing.T) {
+
+	setAlertRuleEnabledFunc = func(userId int64, ruleId int64, enabled bool) (*alerts.AlertRule, rest_errors.RestErr) {
+		return &alerts.AlertRule{Id: ruleId, UserId: userId, Enabled: &enabled}, nil
+	}
+
+	services.AlertRulesService = &alertRulesServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/users/1/alerts/12/disable", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "1"},
+		{Key: "alert_id", Value: "12"},
+	}
+
+	Disable(c)
+
+	var ruleResponse alerts.AlertRule
+	error := json.Unmarshal(response.Body.Bytes(), &ruleResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.False(t, *ruleResponse.Enabled)
+}
+
+func TestAlertDeleteNotFoundError(t *testing.T) {
+
+	deleteAlertRuleFunc = func(userId int64, ruleId int64) rest_errors.RestErr {
+		return rest_errors.NewNotFoundError("alert rule not found")
+	}
+
+	services.AlertRulesService = &alertRulesServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/users/1/alerts/12", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "1"},
+		{Key: "alert_id", Value: "12"},
+	}
+
+	Delete(c)
+
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
+
+func TestAlertStreamActiveOK(t *testing.T) {
+
+	streamActiveAlertRulesFunc = func(callback func(alerts.AlertRule) error) rest_errors.RestErr {
+		enabled := true
+		for _, id := range []int64{12, 13, 14} {
+			if err := callback(alerts.AlertRule{Id: id, Enabled: &enabled}); err != nil {
+				return rest_errors.NewInternalServerError("error streaming alert rules", err)
+			}
+		}
+		return nil
+	}
+
+	services.AlertRulesService = &alertRulesServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/internal/alerts/active", nil)
+
+	StreamActive(c)
+
+	var ids []int64
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var rule alerts.AlertRule
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &rule))
+		ids = append(ids, rule.Id)
+	}
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "application/x-ndjson", response.Header().Get("Content-Type"))
+	assert.EqualValues(t, []int64{12, 13, 14}, ids)
+}
+
+func TestAlertStreamActiveInternalServerError(t *testing.T) {
+
+	streamActiveAlertRulesFunc = func(callback func(alerts.AlertRule) error) rest_errors.RestErr {
+		return rest_errors.NewInternalServerError("error fetching alert rules", errors.New("database error"))
+	}
+
+	services.AlertRulesService = &alertRulesServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/internal/alerts/active", nil)
+
+	StreamActive(c)
+
+	assert.EqualValues(t, http.StatusInternalServerError, response.Code)
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+// getFilter reads the filter of the query string. Dates use the database layout,
+// such as 2022-09-06 10:00:00.
+func getFilter(c *gin.Context) (audit.Filter, rest_errors.RestErr) {
+	filter := audit.Filter{
+		Action:  c.Query("action"),
+		Outcome: c.Query("outcome"),
+		From:    c.Query("from"),
+		Until:   c.Query("until"),
+	}
+	numbers := []struct {
+		param string
+		value *int64
+	}{
+		{"actor_id", &filter.ActorId},
+		{"user_id", &filter.TargetUserId},
+		{"impersonator_id", &filter.ImpersonatorId},
+		{"before_id", &filter.BeforeId},
+	}
+	for _, number := range numbers {
+		if text := c.Query(number.param); text != "" {
+			value, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return filter, rest_errors.NewBadRequestError(number.param + " should be a number")
+			}
+			*number.value = value
+		}
+	}
+	if text := c.Query("limit"); text != "" {
+		limit, err := strconv.Atoi(text)
+		if err != nil {
+			return filter, rest_errors.NewBadRequestError("limit should be a number")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// List returns a page of entries, newest first.
+func List(c *gin.Context) {
+	filter, filterErr := getFilter(c)
+	if filterErr != nil {
+		c.JSON(filterErr.Status(), filterErr)
+		return
+	}
+
+	entries, findErr := services.AuditService.Find(c.Request.Context(), filter)
+	if findErr != nil {
+		c.JSON(findErr.Status(), findErr)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// Export writes every entry matching the filter, oldest first, as newline delimited
+// JSON, flushing after each one. The limit is ignored.
+func Export(c *gin.Context) {
+	filter, filterErr := getFilter(c)
+	if filterErr != nil {
+		c.JSON(filterErr.Status(), filterErr)
+		return
+	}
+
+	encoder := json.NewEncoder(c.Writer)
+	startStream := func() {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="audit.jsonl"`)
+		c.Status(http.StatusOK)
+	}
+	exportErr := services.AuditService.Export(c.Request.Context(), filter, func(entry audit.Entry) error {
+		if !c.Writer.Written() {
+			startStream()
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if exportErr != nil {
+		if !c.Writer.Written() {
+			c.JSON(exportErr.Status(), exportErr)
+		}
+		return
+	}
+	if !c.Writer.Written() {
+		startStream()
+		c.Writer.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	findAuditFunc   func(audit.Filter) (audit.Entries, rest_errors.RestErr)
+	exportAuditFunc func(audit.Filter, func(audit.Entry) error) rest_errors.RestErr
+)
+
+type auditServiceMock struct{}
+
+func (*auditServiceMock) Record(context.Context, audit.Entry) {}
+
+func (*auditServiceMock) Find(ctx context.Context, filter audit.Filter) (audit.Entries, rest_errors.RestErr) {
+	return findAuditFunc(filter)
+}
+
+func (*auditServiceMock) Export(ctx context.Context, filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+	return exportAuditFunc(filter, callback)
+}
+
+func TestAuditListPassesFilters(t *testing.T) {
+	var received audit.Filter
+	findAuditFunc = func(filter audit.Filter) (audit.Entries, rest_errors.RestErr) {
+		received = filter
+		return audit.Entries{{Id: 9, Action: audit.ActionLoginFailed, Outcome: audit.OutcomeFailure}}, nil
+	}
+	services.AuditService = &auditServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/audit?action=login.failed&user_id=666&actor_id=1&impersonator_id=3&from=2022-09-06+10:00:00&before_id=10&limit=20", nil)
+
+	List(c)
+
+	var entries audit.Entries
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &entries))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Equal(t, int64(9), entries[0].Id)
+	assert.Equal(t, audit.Filter{Action: audit.ActionLoginFailed, TargetUserId: 666, ActorId: 1, ImpersonatorId: 3, From: "2022-09-06 10:00:00", BeforeId: 10, Limit: 20}, received)
+}
+
+func TestAuditListInvalidNumberReturnBadRequest(t *testing.T) {
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/audit?user_id=john", nil)
+
+	List(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "user_id should be a number")
+}
+
+func TestAuditExportWritesJSONLines(t *testing.T) {
+	exportAuditFunc = func(filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+		for _, id := range []int64{1, 2, 3} {
+			if err := callback(audit.Entry{Id: id, Action: audit.ActionUserDeleted}); err != nil {
+				return rest_errors.NewInternalServerError("error streaming audit entries", err)
+			}
+		}
+		return nil
+	}
+	services.AuditService = &auditServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/audit/export?action=user.deleted", nil)
+
+	Export(c)
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/x-ndjson", response.Header().Get("Content-Type"))
+	assert.Contains(t, response.Header().Get("Content-Disposition"), "audit.jsonl")
+	ids := make([]int64, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(response.Body.Bytes()))
+	for scanner.Scan() {
+		var entry audit.Entry
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &entry))
+		ids = append(ids, entry.Id)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+}
+
+func TestAuditExportEmptyAndFailures(t *testing.T) {
+	exportAuditFunc = func(filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+		return nil
+	}
+	services.AuditService = &auditServiceMock{}
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/audit/export", nil)
+
+	Export(c)
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Body.String())
+
+	exportAuditFunc = func(filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+		return rest_errors.NewBadRequestError("invalid action user.exploded")
+	}
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/audit/export?action=user.exploded", nil)
+
+	Export(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
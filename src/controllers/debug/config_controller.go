@@ -0,0 +1,20 @@
+package debug
+
+import (
+	"net/http"
+	"tokenalert_user-api/src/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+// Config shows the configuration the API runs with, secrets redacted, and where each
+// setting came from.
+func Config(c *gin.Context) {
+	if config.Current == nil {
+		restErr := rest_errors.NewNotFoundError("configuration not loaded")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	c.JSON(http.StatusOK, config.Current.Redacted())
+}
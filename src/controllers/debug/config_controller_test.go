@@ -0,0 +1,41 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigRedactsSecrets(t *testing.T) {
+	current := config.Default()
+	current.Database.Password = "s3cret"
+	config.Current = current
+	defer func() { config.Current = nil }()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/debug/config", nil)
+
+	Config(c)
+
+	var entries map[string]config.Entry
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &entries))
+	assert.Equal(t, config.Entry{Value: "[redacted]", Source: config.SourceDefault}, entries["database.password"])
+	assert.Equal(t, config.Entry{Value: ":8080", Source: config.SourceDefault}, entries["server.address"])
+	assert.NotContains(t, response.Body.String(), "s3cret")
+}
+
+func TestConfigNotLoaded(t *testing.T) {
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/debug/config", nil)
+
+	Config(c)
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
@@ -0,0 +1,14 @@
+package debug
+
+import (
+	"net/http"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Vars shows the users_db statistics published among the expvar metrics, and only
+// them: the rest, such as cmdline, could reveal flags with secrets.
+func Vars(c *gin.Context) {
+	c.JSON(http.StatusOK, map[string]interface{}{"users_db": users_db.Stats()})
+}
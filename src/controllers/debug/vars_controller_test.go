@@ -0,0 +1,26 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVarsOnlyShowsUsersDb(t *testing.T) {
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/debug/vars", nil)
+
+	Vars(c)
+
+	var vars map[string]json.RawMessage
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &vars))
+	assert.Equal(t, 1, len(vars))
+	assert.Contains(t, vars, "users_db")
+	assert.NotContains(t, response.Body.String(), "cmdline")
+}
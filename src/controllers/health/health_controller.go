@@ -0,0 +1,26 @@
+package health
+
+import (
+	"net/http"
+	"tokenalert_user-api/src/domain/health"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Live answers as long as the process can serve requests at all. It checks no
+// dependency, so an unreachable database does not get the API restarted.
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusUp, Checks: []health.Check{}})
+}
+
+// Ready runs the registered checks and answers 503 when any of them is down or the
+// API is shutting down.
+func Ready(c *gin.Context) {
+	report := services.HealthService.Ready(c.Request.Context())
+	if !report.Up() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/domain/health"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	readyFunc func(context.Context) health.Report
+)
+
+type healthServiceMock struct{}
+
+func (*healthServiceMock) Register(string, time.Duration, services.Checker) {}
+
+func (*healthServiceMock) Ready(ctx context.Context) health.Report {
+	return readyFunc(ctx)
+}
+
+func (*healthServiceMock) ShuttingDown() {}
+
+func TestLive(t *testing.T) {
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/health/live", nil)
+
+	Live(c)
+
+	var report health.Report
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &report))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, health.StatusUp, report.Status)
+}
+
+func TestReadyUp(t *testing.T) {
+	readyFunc = func(context.Context) health.Report {
+		return health.Report{Status: health.StatusUp, Checks: []health.Check{{Name: "database", Status: health.StatusUp, Duration: 2}}}
+	}
+	services.HealthService = &healthServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/health/ready", nil)
+
+	Ready(c)
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"status":"up","checks":[{"name":"database","status":"up","duration_ms":2}]}`, response.Body.String())
+}
+
+func TestReadyDown(t *testing.T) {
+	readyFunc = func(context.Context) health.Report {
+		return health.Report{Status: health.StatusDown, Checks: []health.Check{
+			{Name: "database", Status: health.StatusUp},
+			{Name: "event_bus", Status: health.StatusDown, Error: "no answer within 2s"},
+		}}
+	}
+	services.HealthService = &healthServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/health/ready", nil)
+
+	Ready(c)
+
+	var report health.Report
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &report))
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, "no answer within 2s", report.Checks[1].Error)
+}
@@ -0,0 +1,132 @@
+package notifications
+
+import (
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+func getUserId(userIdParam string) (int64, rest_errors.RestErr) {
+	userId, userErr := strconv.ParseInt(userIdParam, 10, 64)
+	if userErr != nil {
+		return 0, rest_errors.NewBadRequestError("user id should be a number")
+	}
+	return userId, nil
+}
+
+func Get(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	settings, getErr := services.NotificationSettingsService.GetSettings(c.Request.Context(), userId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func Update(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var settings notifications.Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	settings.UserId = userId
+
+	result, updateErr := services.NotificationSettingsService.UpdateSettings(c.Request.Context(), settings)
+	if updateErr != nil {
+		c.JSON(updateErr.Status(), updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func Verify(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	result, verifyErr := services.NotificationSettingsService.VerifyChannel(c.Request.Context(), userId, c.Param("channel"))
+	if verifyErr != nil {
+		c.JSON(verifyErr.Status(), verifyErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func GetQuietHours(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	quietHours, getErr := services.QuietHoursService.GetQuietHours(c.Request.Context(), userId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, quietHours)
+}
+
+func UpdateQuietHours(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var quietHours notifications.QuietHours
+	if err := c.ShouldBindJSON(&quietHours); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	quietHours.UserId = userId
+
+	result, updateErr := services.QuietHoursService.UpdateQuietHours(c.Request.Context(), quietHours)
+	if updateErr != nil {
+		c.JSON(updateErr.Status(), updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func CanNotify(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	urgent, parseErr := strconv.ParseBool(c.DefaultQuery("urgent", "false"))
+	if parseErr != nil {
+		restErr := rest_errors.NewBadRequestError("urgent should be a boolean")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+
+	availability, checkErr := services.QuietHoursService.CanNotify(c.Request.Context(), userId, urgent)
+	if checkErr != nil {
+		c.JSON(checkErr.Status(), checkErr)
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
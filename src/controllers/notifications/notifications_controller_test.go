@@ -0,0 +1,258 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	getSettingsFunc    func(int64) (*notifications.Settings, rest_errors.RestErr)
+	updateSettingsFunc func(notifications.Settings) (*notifications.Settings, rest_errors.RestErr)
+	verifyChannelFunc  func(int64, string) (*notifications.Settings, rest_errors.RestErr)
+)
+
+type notificationSettingsServiceMock struct{}
+
+func (*notificationSettingsServiceMock) GetSettings(ctx context.Context, userId int64) (*notifications.Settings, rest_errors.RestErr) {
+	return getSettingsFunc(userId)
+}
+
+func (*notificationSettingsServiceMock) UpdateSettings(ctx context.Context, settings notifications.Settings) (*notifications.Settings, rest_errors.RestErr) {
+	return updateSettingsFunc(settings)
+}
+
+func (*notificationSettingsServiceMock) VerifyChannel(ctx context.Context, userId int64, channelType string) (*notifications.Settings, rest_errors.RestErr) {
+	return verifyChannelFunc(userId, channelType)
+}
+
+func TestNotificationSettingsGetOK(t *testing.T) {
+
+	getSettingsFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, PrimaryChannel: "telegram", Channels: notifications.Channels{
+			{Type: "telegram", Target: "@serge", Enabled: true},
+		}}, nil
+	}
+
+	services.NotificationSettingsService = &notificationSettingsServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/123/notification-settings", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Get(c)
+
+	var settingsResponse notifications.Settings
+	error := json.Unmarshal(response.Body.Bytes(), &settingsResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 123, settingsResponse.UserId)
+	assert.EqualValues(t, "telegram", settingsResponse.PrimaryChannel)
+}
+
+func TestNotificationSettingsGetBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/ABC/notification-settings", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "ABC"},
+	}
+
+	Get(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestNotificationSettingsUpdateOK(t *testing.T) {
+
+	updateSettingsFunc = func(settings notifications.Settings) (*notifications.Settings, rest_errors.RestErr) {
+		return &settings, nil
+	}
+
+	services.NotificationSettingsService = &notificationSettingsServiceMock{}
+
+	body, _ := json.Marshal(notifications.Settings{UserId: 999, PrimaryChannel: "email", Channels: notifications.Channels{
+		{Type: "email", Target: "serge@gmail.com", Enabled: true},
+	}})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/users/123/notification-settings", bytes.NewBuffer(body))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Update(c)
+
+	var settingsResponse notifications.Settings
+	error := json.Unmarshal(response.Body.Bytes(), &settingsResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 123, settingsResponse.UserId)
+}
+
+func TestNotificationSettingsUpdateValidationError(t *testing.T) {
+
+	updateSettingsFunc = func(settings notifications.Settings) (*notifications.Settings, rest_errors.RestErr) {
+		return nil, rest_errors.NewBadRequestError("primary channel is not configured")
+	}
+
+	services.NotificationSettingsService = &notificationSettingsServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/users/123/notification-settings", bytes.NewBufferString(`{"primary_channel":"email"}`))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Update(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestNotificationChannelVerifyNotFoundError(t *testing.T) {
+
+	verifyChannelFunc = func(userId int64, channelType string) (*notifications.Settings, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("notification channel not found")
+	}
+
+	services.NotificationSettingsService = &notificationSettingsServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/internal/users/123/notification-settings/email/verify", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+		{Key: "channel", Value: "email"},
+	}
+
+	Verify(c)
+
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
+
+var (
+	getQuietHoursFunc    func(int64) (*notifications.QuietHours, rest_errors.RestErr)
+	updateQuietHoursFunc func(notifications.QuietHours) (*notifications.QuietHours, rest_errors.RestErr)
+	canNotifyFunc        func(int64, bool) (*notifications.Availability, rest_errors.RestErr)
+)
+
+type quietHoursServiceMock struct{}
+
+func (*quietHoursServiceMock) GetQuietHours(ctx context.Context, userId int64) (*notifications.QuietHours, rest_errors.RestErr) {
+	return getQuietHoursFunc(userId)
+}
+
+func (*quietHoursServiceMock) UpdateQuietHours(ctx context.Context, quietHours notifications.QuietHours) (*notifications.QuietHours, rest_errors.RestErr) {
+	return updateQuietHoursFunc(quietHours)
+}
+
+func (*quietHoursServiceMock) CanNotify(ctx context.Context, userId int64, urgent bool) (*notifications.Availability, rest_errors.RestErr) {
+	return canNotifyFunc(userId, urgent)
+}
+
+func TestQuietHoursUpdateOK(t *testing.T) {
+
+	updateQuietHoursFunc = func(quietHours notifications.QuietHours) (*notifications.QuietHours, rest_errors.RestErr) {
+		return &quietHours, nil
+	}
+
+	services.QuietHoursService = &quietHoursServiceMock{}
+
+	body := `{"enabled":true,"urgent_bypass":true,"windows":[{"weekday":"monday","start":"22:00","end":"07:00"}]}`
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/users/123/quiet-hours", bytes.NewBufferString(body))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	UpdateQuietHours(c)
+
+	var quietHoursResponse notifications.QuietHours
+	error := json.Unmarshal(response.Body.Bytes(), &quietHoursResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 123, quietHoursResponse.UserId)
+	assert.EqualValues(t, 1, len(quietHoursResponse.Windows))
+}
+
+func TestQuietHoursGetInternalServerError(t *testing.T) {
+
+	getQuietHoursFunc = func(userId int64) (*notifications.QuietHours, rest_errors.RestErr) {
+		return nil, rest_errors.NewInternalServerError("error fetching quiet hours", nil)
+	}
+
+	services.QuietHoursService = &quietHoursServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/123/quiet-hours", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	GetQuietHours(c)
+
+	assert.EqualValues(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestCanNotifyUrgentOK(t *testing.T) {
+
+	var urgentParam bool
+	canNotifyFunc = func(userId int64, urgent bool) (*notifications.Availability, rest_errors.RestErr) {
+		urgentParam = urgent
+		return &notifications.Availability{UserId: userId, CanNotify: true, Reason: notifications.ReasonUrgent}, nil
+	}
+
+	services.QuietHoursService = &quietHoursServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/internal/users/123/can-notify?urgent=true", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	CanNotify(c)
+
+	var availabilityResponse notifications.Availability
+	error := json.Unmarshal(response.Body.Bytes(), &availabilityResponse)
+
+	assert.Nil(t, error)
+	assert.True(t, urgentParam)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.True(t, availabilityResponse.CanNotify)
+}
+
+func TestCanNotifyBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/internal/users/123/can-notify?urgent=maybe", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	CanNotify(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
@@ -0,0 +1,72 @@
+package plans
+
+import (
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+func getUserId(userIdParam string) (int64, rest_errors.RestErr) {
+	userId, userErr := strconv.ParseInt(userIdParam, 10, 64)
+	if userErr != nil {
+		return 0, rest_errors.NewBadRequestError("user id should be a number")
+	}
+	return userId, nil
+}
+
+func GetUsage(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	usage, getErr := services.QuotaService.GetUsage(c.Request.Context(), userId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+func Assign(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var assignment plans.Assignment
+	if err := c.ShouldBindJSON(&assignment); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	assignment.UserId = userId
+
+	result, saveErr := services.PlansService.AssignPlan(c.Request.Context(), assignment)
+	if saveErr != nil {
+		c.JSON(saveErr.Status(), saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func History(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	assignments, getErr := services.PlansService.GetPlanHistory(c.Request.Context(), userId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
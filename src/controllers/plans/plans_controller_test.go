@@ -0,0 +1,155 @@
+package plans
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	getUsageFunc       func(int64) (*plans.Usage, rest_errors.RestErr)
+	assignPlanFunc     func(plans.Assignment) (*plans.Assignment, rest_errors.RestErr)
+	getPlanHistoryFunc func(int64) (plans.Assignments, rest_errors.RestErr)
+)
+
+type quotaServiceMock struct{}
+
+func (*quotaServiceMock) GetUsage(ctx context.Context, userId int64) (*plans.Usage, rest_errors.RestErr) {
+	return getUsageFunc(userId)
+}
+
+func (*quotaServiceMock) CheckAlertRuleQuota(context.Context, repositories.Repositories, alerts.AlertRule) rest_errors.RestErr {
+	return nil
+}
+
+func (*quotaServiceMock) CheckNotificationChannelsQuota(context.Context, int64, int) rest_errors.RestErr {
+	return nil
+}
+
+type plansServiceMock struct{}
+
+func (*plansServiceMock) GetUserPlan(context.Context, int64) (*plans.Plan, rest_errors.RestErr) {
+	return plans.Default(), nil
+}
+
+func (*plansServiceMock) AssignPlan(ctx context.Context, assignment plans.Assignment) (*plans.Assignment, rest_errors.RestErr) {
+	return assignPlanFunc(assignment)
+}
+
+func (*plansServiceMock) GetPlanHistory(ctx context.Context, userId int64) (plans.Assignments, rest_errors.RestErr) {
+	return getPlanHistoryFunc(userId)
+}
+
+func TestGetUsageOK(t *testing.T) {
+
+	getUsageFunc = func(userId int64) (*plans.Usage, rest_errors.RestErr) {
+		return &plans.Usage{UserId: userId, Plan: plans.Default(), AlertRules: plans.Quota{Used: 3, Limit: 10}}, nil
+	}
+
+	services.QuotaService = &quotaServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/123/usage", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	GetUsage(c)
+
+	var usageResponse plans.Usage
+	error := json.Unmarshal(response.Body.Bytes(), &usageResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "free", usageResponse.Plan.Code)
+	assert.EqualValues(t, 3, usageResponse.AlertRules.Used)
+}
+
+func TestGetUsageBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/users/ABC/usage", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "ABC"},
+	}
+
+	GetUsage(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAssignOK(t *testing.T) {
+
+	assignPlanFunc = func(assignment plans.Assignment) (*plans.Assignment, rest_errors.RestErr) {
+		assignment.Id = 7
+		return &assignment, nil
+	}
+
+	services.PlansService = &plansServiceMock{}
+
+	body, _ := json.Marshal(plans.Assignment{Plan: "pro", EffectiveFrom: "2022-09-01 00:00:00"})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/users/123/plans", bytes.NewBuffer(body))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Assign(c)
+
+	var assignmentResponse plans.Assignment
+	error := json.Unmarshal(response.Body.Bytes(), &assignmentResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, 7, assignmentResponse.Id)
+	assert.EqualValues(t, 123, assignmentResponse.UserId)
+}
+
+func TestAssignBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/users/123/plans", bytes.NewBufferString("{"))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Assign(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestHistoryInternalServerError(t *testing.T) {
+
+	getPlanHistoryFunc = func(userId int64) (plans.Assignments, rest_errors.RestErr) {
+		return nil, rest_errors.NewInternalServerError("error fetching plan assignments", nil)
+	}
+
+	services.PlansService = &plansServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/users/123/plans", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	History(c)
+
+	assert.EqualValues(t, http.StatusInternalServerError, response.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/middlewares"
 	"tokenalert_user-api/src/services"
 
 	"github.com/gin-gonic/gin"
@@ -26,7 +27,7 @@ func Get(c *gin.Context) {
 		return
 	}
 
-	user, getErr := services.UsersService.GetUser(userId)
+	user, getErr := services.UsersService.GetUser(c.Request.Context(), userId)
 	if getErr != nil {
 		c.JSON(getErr.Status(), getErr)
 		return
@@ -35,6 +36,29 @@ func Get(c *gin.Context) {
 	c.JSON(http.StatusOK, user.Marshall(/*oauth.IsPublic(c.Request))*/ false))
 }
 
+func GetInternal(c *gin.Context) {
+
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	user, getErr := services.UsersService.GetUser(c.Request.Context(), userId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+
+	settings, settingsErr := services.NotificationSettingsService.GetSettings(c.Request.Context(), userId)
+	if settingsErr != nil {
+		c.JSON(settingsErr.Status(), settingsErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, user.MarshallInternal(settings))
+}
+
 func Create(c *gin.Context) {
 	var user users.User
 	if err := c.ShouldBindJSON(&user); err != nil {
@@ -43,15 +67,76 @@ func Create(c *gin.Context) {
 		return
 	}
 
-	result, saveErr := services.UsersService.CreateUser(user)
+	result, saveErr := services.UsersService.CreateUser(c.Request.Context(), user)
 	if saveErr != nil {
 		c.JSON(saveErr.Status(), saveErr)
 		return
 	}
 
+	c.Set(middlewares.UserIdKey, result.Id)
 	c.JSON(http.StatusCreated, result.Marshall(c.GetHeader("X-Public") == "true"))
 }
 
+func Update(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var user users.User
+	if err := c.ShouldBindJSON(&user); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	user.Id = userId
+
+	isPartial := c.Request.Method == http.MethodPatch
+
+	result, updateErr := services.UsersService.UpdateUser(c.Request.Context(), isPartial, user)
+	if updateErr != nil {
+		c.JSON(updateErr.Status(), updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, result.Marshall(c.GetHeader("X-Public") == "true"))
+}
+
+func Delete(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	if err := services.UsersService.DeleteUser(c.Request.Context(), userId); err != nil {
+		c.JSON(err.Status(), err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func ChangePassword(c *gin.Context) {
+	userId, idErr := getUserId(c.Param("user_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	var request users.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+
+	if err := services.UsersService.ChangePassword(c.Request.Context(), userId, request); err != nil {
+		c.JSON(err.Status(), err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "password changed"})
+}
+
 func Login(c *gin.Context) {
 	var request users.LoginRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -59,10 +144,11 @@ func Login(c *gin.Context) {
 		c.JSON(restErr.Status(), restErr)
 		return
 	}
-	user, err := services.UsersService.LoginUser(request)
+	user, err := services.UsersService.LoginUser(c.Request.Context(), request)
 	if err != nil {
 		c.JSON(err.Status(), err)
 		return
 	}
+	c.Set(middlewares.UserIdKey, user.Id)
 	c.JSON(http.StatusOK, user.Marshall(c.GetHeader("X-Public") == "true"))
 }
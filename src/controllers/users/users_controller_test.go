@@ -2,11 +2,14 @@ package users
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"tokenalert_user-api/src/domain/notifications"
 	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/middlewares"
 	"tokenalert_user-api/src/services"
 
 	"github.com/gin-gonic/gin"
@@ -17,20 +20,35 @@ import (
 var (
 	createUserFunc func(user users.User) (*users.User, rest_errors.RestErr)
 	getUserFunc func(id int64) (*users.User, rest_errors.RestErr)
+	updateUserFunc func(isPartial bool, user users.User) (*users.User, rest_errors.RestErr)
+	deleteUserFunc func(id int64) rest_errors.RestErr
+	changePasswordFunc func(id int64, request users.ChangePasswordRequest) rest_errors.RestErr
 	loginUserFunc  func(request users.LoginRequest) (*users.User, rest_errors.RestErr)
 )
 
 type usersServiceMock struct{}
 
-func (*usersServiceMock) CreateUser(user users.User) (*users.User, rest_errors.RestErr) {
+func (*usersServiceMock) CreateUser(ctx context.Context, user users.User) (*users.User, rest_errors.RestErr) {
 	return createUserFunc(user)
 }
 
-func (*usersServiceMock) GetUser(id int64) (*users.User, rest_errors.RestErr) {
+func (*usersServiceMock) GetUser(ctx context.Context, id int64) (*users.User, rest_errors.RestErr) {
 	return getUserFunc(id)
 }
 
-func (*usersServiceMock) LoginUser(loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
+func (*usersServiceMock) UpdateUser(ctx context.Context, isPartial bool, user users.User) (*users.User, rest_errors.RestErr) {
+	return updateUserFunc(isPartial, user)
+}
+
+func (*usersServiceMock) DeleteUser(ctx context.Context, id int64) rest_errors.RestErr {
+	return deleteUserFunc(id)
+}
+
+func (*usersServiceMock) ChangePassword(ctx context.Context, id int64, request users.ChangePasswordRequest) rest_errors.RestErr {
+	return changePasswordFunc(id, request)
+}
+
+func (*usersServiceMock) LoginUser(ctx context.Context, loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
 	return loginUserFunc(loginRequest)
 }
 
@@ -63,6 +81,8 @@ func TestUserCreateOK(t *testing.T) {
 	assert.Nil(t, error)
 	assert.EqualValues(t, http.StatusCreated, response.Code)
 	assert.EqualValues(t, 123, userResponse.Id)	
+	userId, _ := c.Get(middlewares.UserIdKey)
+	assert.EqualValues(t, int64(123), userId)
 }
 
 func TestUserCreateBadRequestError(t *testing.T) {
@@ -265,4 +285,170 @@ func TestUserLoginInternalError(t *testing.T) {
 
 	assert.NotNil(t, error)
 	assert.EqualValues(t, http.StatusInternalServerError, response.Code)
-}
\ No newline at end of file
+}
+
+type notificationSettingsServiceMock struct{}
+
+var getNotificationSettingsFunc func(int64) (*notifications.Settings, rest_errors.RestErr)
+
+func (*notificationSettingsServiceMock) GetSettings(ctx context.Context, userId int64) (*notifications.Settings, rest_errors.RestErr) {
+	return getNotificationSettingsFunc(userId)
+}
+
+func (*notificationSettingsServiceMock) UpdateSettings(ctx context.Context, settings notifications.Settings) (*notifications.Settings, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (*notificationSettingsServiceMock) VerifyChannel(ctx context.Context, userId int64, channelType string) (*notifications.Settings, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func TestUserGetInternalOK(t *testing.T) {
+
+	getUserFunc = func(int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: 123, Name: "Serge", Email: "serge@gmail.com", TelegramUser: "@serge", Password: "secret"}, nil
+	}
+	getNotificationSettingsFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, PrimaryChannel: "telegram", Channels: notifications.Channels{
+			{Type: "telegram", Target: "@serge", Enabled: true},
+		}}, nil
+	}
+
+	services.UsersService = &usersServiceMock{}
+	services.NotificationSettingsService = &notificationSettingsServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/internal/users/123", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	GetInternal(c)
+
+	var userResponse map[string]interface{}
+	error := json.Unmarshal(response.Body.Bytes(), &userResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 123, userResponse["id"])
+	assert.Nil(t, userResponse["password"])
+	assert.EqualValues(t, "telegram", userResponse["notification_settings"].(map[string]interface{})["primary_channel"])
+}
+
+func TestUserGetInternalNotFoundError(t *testing.T) {
+
+	getUserFunc = func(int64) (*users.User, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("user not found")
+	}
+
+	services.UsersService = &usersServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/internal/users/123", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	GetInternal(c)
+
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
+
+func TestUserUpdatePartialOK(t *testing.T) {
+
+	var partial bool
+	updateUserFunc = func(isPartial bool, user users.User) (*users.User, rest_errors.RestErr) {
+		partial = isPartial
+		return &users.User{Id: user.Id, Name: "Serge", Email: "serge@gmail.com", TimeZone: user.TimeZone}, nil
+	}
+
+	services.UsersService = &usersServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/users/123", bytes.NewBufferString(`{"time_zone":"Europe/Madrid"}`))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Update(c)
+
+	var userResponse users.User
+	error := json.Unmarshal(response.Body.Bytes(), &userResponse)
+
+	assert.Nil(t, error)
+	assert.True(t, partial)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 123, userResponse.Id)
+	assert.EqualValues(t, "Europe/Madrid", userResponse.TimeZone)
+}
+
+func TestUserUpdateBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/users/ABC", bytes.NewBufferString(`{}`))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "ABC"},
+	}
+
+	Update(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestUserDeleteOK(t *testing.T) {
+
+	deleteUserFunc = func(id int64) rest_errors.RestErr {
+		return nil
+	}
+
+	services.UsersService = &usersServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/users/123", nil)
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	Delete(c)
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+}
+
+func TestUserChangePasswordUnauthorizedError(t *testing.T) {
+
+	changePasswordFunc = func(id int64, request users.ChangePasswordRequest) rest_errors.RestErr {
+		return rest_errors.NewUnauthorizedError("invalid current password")
+	}
+
+	services.UsersService = &usersServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/users/123/password", bytes.NewBufferString(`{"current_password":"wrong","new_password":"s3cret"}`))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	ChangePassword(c)
+
+	assert.EqualValues(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestUserChangePasswordBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "/users/123/password", bytes.NewBufferString(`{"new_password":"s3cret"}`))
+	c.Params = gin.Params{
+		{Key: "user_id", Value: "123"},
+	}
+
+	ChangePassword(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/webhooks"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+func getWebhookId(webhookIdParam string) (int64, rest_errors.RestErr) {
+	webhookId, webhookErr := strconv.ParseInt(webhookIdParam, 10, 64)
+	if webhookErr != nil {
+		return 0, rest_errors.NewBadRequestError("webhook id should be a number")
+	}
+	return webhookId, nil
+}
+
+func Create(c *gin.Context) {
+	var webhook webhooks.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+
+	result, saveErr := services.WebhooksService.CreateWebhook(c.Request.Context(), webhook)
+	if saveErr != nil {
+		c.JSON(saveErr.Status(), saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, result.Marshall(true))
+}
+
+func List(c *gin.Context) {
+	result, getErr := services.WebhooksService.GetWebhooks(c.Request.Context())
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, result.Marshall(false))
+}
+
+func Delete(c *gin.Context) {
+	webhookId, idErr := getWebhookId(c.Param("webhook_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	if deleteErr := services.WebhooksService.DeleteWebhook(c.Request.Context(), webhookId); deleteErr != nil {
+		c.JSON(deleteErr.Status(), deleteErr)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func Deliveries(c *gin.Context) {
+	webhookId, idErr := getWebhookId(c.Param("webhook_id"))
+	if idErr != nil {
+		c.JSON(idErr.Status(), idErr)
+		return
+	}
+
+	result, getErr := services.WebhooksService.GetDeliveries(c.Request.Context(), webhookId)
+	if getErr != nil {
+		c.JSON(getErr.Status(), getErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/webhooks"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	createWebhookFunc func(webhooks.Webhook) (*webhooks.Webhook, rest_errors.RestErr)
+	getWebhooksFunc   func() (webhooks.Webhooks, rest_errors.RestErr)
+	deleteWebhookFunc func(int64) rest_errors.RestErr
+	getDeliveriesFunc func(int64) (webhooks.Deliveries, rest_errors.RestErr)
+)
+
+type webhooksServiceMock struct{}
+
+func (*webhooksServiceMock) CreateWebhook(ctx context.Context, webhook webhooks.Webhook) (*webhooks.Webhook, rest_errors.RestErr) {
+	return createWebhookFunc(webhook)
+}
+
+func (*webhooksServiceMock) GetWebhooks(context.Context) (webhooks.Webhooks, rest_errors.RestErr) {
+	return getWebhooksFunc()
+}
+
+func (*webhooksServiceMock) DeleteWebhook(ctx context.Context, webhookId int64) rest_errors.RestErr {
+	return deleteWebhookFunc(webhookId)
+}
+
+func (*webhooksServiceMock) GetDeliveries(ctx context.Context, webhookId int64) (webhooks.Deliveries, rest_errors.RestErr) {
+	return getDeliveriesFunc(webhookId)
+}
+
+func (*webhooksServiceMock) Dispatch(context.Context, events.Event) rest_errors.RestErr {
+	return nil
+}
+
+func (*webhooksServiceMock) DeliverPending(context.Context, int) (int, rest_errors.RestErr) {
+	return 0, nil
+}
+
+func TestWebhookCreateShowsSecret(t *testing.T) {
+
+	createWebhookFunc = func(webhook webhooks.Webhook) (*webhooks.Webhook, rest_errors.RestErr) {
+		webhook.Id = 3
+		webhook.Secret = "secret"
+		return &webhook, nil
+	}
+
+	services.WebhooksService = &webhooksServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewBufferString(`{"url":"https://example.com/hook"}`))
+
+	Create(c)
+
+	var webhookResponse webhooks.Webhook
+	error := json.Unmarshal(response.Body.Bytes(), &webhookResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, 3, webhookResponse.Id)
+	assert.EqualValues(t, "secret", webhookResponse.Secret)
+}
+
+func TestWebhookListHidesSecret(t *testing.T) {
+
+	getWebhooksFunc = func() (webhooks.Webhooks, rest_errors.RestErr) {
+		return webhooks.Webhooks{{Id: 3, Url: "https://example.com/hook", Secret: "secret"}}, nil
+	}
+
+	services.WebhooksService = &webhooksServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+
+	List(c)
+
+	var webhooksResponse webhooks.Webhooks
+	error := json.Unmarshal(response.Body.Bytes(), &webhooksResponse)
+
+	assert.Nil(t, error)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "", webhooksResponse[0].Secret)
+}
+
+func TestWebhookDeleteBadRequestError(t *testing.T) {
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/admin/webhooks/ABC", nil)
+	c.Params = gin.Params{
+		{Key: "webhook_id", Value: "ABC"},
+	}
+
+	Delete(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestWebhookDeliveriesNotFoundError(t *testing.T) {
+
+	getDeliveriesFunc = func(webhookId int64) (webhooks.Deliveries, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("webhook not found")
+	}
+
+	services.WebhooksService = &webhooksServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/webhooks/3/deliveries", nil)
+	c.Params = gin.Params{
+		{Key: "webhook_id", Value: "3"},
+	}
+
+	Deliveries(c)
+
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
@@ -0,0 +1,63 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrClosed = errors.New("publisher is closed")
+
+type Message struct {
+	Subject string
+	Data    []byte
+}
+
+// MemoryPublisher keeps published messages in memory. It is meant for tests and
+// local runs without a broker.
+type MemoryPublisher struct {
+	mutex    sync.Mutex
+	messages []Message
+	closed   bool
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{messages: make([]Message, 0)}
+}
+
+func (p *MemoryPublisher) Publish(subject string, data []byte) error {
+	if !validSubject(subject) {
+		return errors.New("invalid subject")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	p.messages = append(p.messages, Message{Subject: subject, Data: append([]byte(nil), data...)})
+	return nil
+}
+
+func (p *MemoryPublisher) Ping(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	return ctx.Err()
+}
+
+func (p *MemoryPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	return nil
+}
+
+// Messages returns a copy of everything published so far, in order.
+func (p *MemoryPublisher) Messages() []Message {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]Message(nil), p.messages...)
+}
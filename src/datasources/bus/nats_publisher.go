@@ -0,0 +1,311 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsClientName    = "tokenalert_user-api"
+	natsDefaultPort   = "4222"
+	natsLineSeparator = "\r\n"
+)
+
+// natsPublisher speaks the NATS client protocol. Every publish is followed by a PING
+// and only returns once the matching PONG arrives, which the server sends after
+// processing everything before it. A broken connection is dialed again on the next
+// publish.
+type natsPublisher struct {
+	address string
+	user    *url.Userinfo
+	timeout time.Duration
+
+	mutex  sync.Mutex
+	conn   *natsConn
+	closed bool
+}
+
+type natsServerInfo struct {
+	MaxPayload int64 `json:"max_payload"`
+}
+
+type natsConnectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"pass,omitempty"`
+}
+
+// DialNats connects to a NATS server given as nats://[user:password@]host[:port].
+func DialNats(address string, timeout time.Duration) (Publisher, error) {
+	if !strings.Contains(address, "://") {
+		address = "nats://" + address
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Scheme != "nats" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid nats url %q", address)
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+
+	publisher := &natsPublisher{
+		address: net.JoinHostPort(parsed.Hostname(), port),
+		user:    parsed.User,
+		timeout: timeout,
+	}
+	if publisher.conn, err = publisher.dial(); err != nil {
+		return nil, err
+	}
+	return publisher, nil
+}
+
+func (p *natsPublisher) Publish(subject string, data []byte) error {
+	if !validSubject(subject) {
+		return errors.New("invalid subject")
+	}
+
+	return p.exchange(func(conn *natsConn) error {
+		return conn.publish(subject, data, p.timeout)
+	})
+}
+
+// Ping sends a PING on the connection publishes use, dialing it again if broken, and
+// waits for the PONG.
+func (p *natsPublisher) Ping(ctx context.Context) error {
+	return p.exchange(func(conn *natsConn) error {
+		if err := conn.write("PING" + natsLineSeparator); err != nil {
+			return err
+		}
+		return conn.waitPong(ctx, p.timeout)
+	})
+}
+
+// exchange runs fn on the connection, dialed if needed. A failed exchange closes it,
+// so a late PONG can not be taken as the answer to the next one.
+func (p *natsPublisher) exchange(fn func(*natsConn) error) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if p.conn == nil || p.conn.failed() != nil {
+		conn, err := p.dial()
+		if err != nil {
+			return err
+		}
+		p.conn = conn
+	}
+
+	if err := fn(p.conn); err != nil {
+		p.conn.close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.close()
+	p.conn = nil
+	return err
+}
+
+func (p *natsPublisher) dial() (*natsConn, error) {
+	netConn, err := net.DialTimeout("tcp", p.address, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &natsConn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+		pongs:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := conn.handshake(p.connectOptions(), p.timeout); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	go conn.readLoop()
+	return conn, nil
+}
+
+func (p *natsPublisher) connectOptions() natsConnectOptions {
+	options := natsConnectOptions{Name: natsClientName, Lang: "go", Version: "1.0.0"}
+	if p.user != nil {
+		options.User = p.user.Username()
+		options.Password, _ = p.user.Password()
+	}
+	return options
+}
+
+type natsConn struct {
+	netConn    net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+	writer     *bufio.Writer
+	maxPayload int64
+
+	pongs chan struct{}
+	done  chan struct{}
+
+	errMutex sync.Mutex
+	err      error
+}
+
+// handshake reads the server INFO, sends CONNECT and waits for the PONG of a first
+// PING, which is where the server reports authorization errors.
+func (c *natsConn) handshake(options natsConnectOptions, timeout time.Duration) error {
+	c.netConn.SetDeadline(time.Now().Add(timeout))
+	defer c.netConn.SetDeadline(time.Time{})
+
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected nats greeting %q", line)
+	}
+	var info natsServerInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return err
+	}
+	c.maxPayload = info.MaxPayload
+
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	if err := c.write("CONNECT " + string(connect) + natsLineSeparator + "PING" + natsLineSeparator); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return natsError(line)
+		}
+	}
+}
+
+func (c *natsConn) publish(subject string, data []byte, timeout time.Duration) error {
+	if c.maxPayload > 0 && int64(len(data)) > c.maxPayload {
+		return fmt.Errorf("message of %d bytes exceeds the server maximum of %d", len(data), c.maxPayload)
+	}
+
+	command := fmt.Sprintf("PUB %s %d%s%s%sPING%s", subject, len(data), natsLineSeparator, data, natsLineSeparator, natsLineSeparator)
+	if err := c.write(command); err != nil {
+		return err
+	}
+	return c.waitPong(context.Background(), timeout)
+}
+
+func (c *natsConn) waitPong(ctx context.Context, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.pongs:
+		return nil
+	case <-c.done:
+		return c.failed()
+	case <-timer.C:
+		return errors.New("timeout waiting for nats server")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readLoop answers server PINGs and hands PONGs to the publish waiting for them. Any
+// protocol error ends the connection.
+func (c *natsConn) readLoop() {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		switch {
+		case line == "PING":
+			if err := c.write("PONG" + natsLineSeparator); err != nil {
+				c.fail(err)
+				return
+			}
+		case line == "PONG":
+			select {
+			case c.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			c.fail(natsError(line))
+			c.netConn.Close()
+			return
+		}
+	}
+}
+
+func (c *natsConn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, natsLineSeparator), nil
+}
+
+func (c *natsConn) write(command string) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if _, err := c.writer.WriteString(command); err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+func (c *natsConn) fail(err error) {
+	c.errMutex.Lock()
+	defer c.errMutex.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+func (c *natsConn) failed() error {
+	c.errMutex.Lock()
+	defer c.errMutex.Unlock()
+	return c.err
+}
+
+func (c *natsConn) close() error {
+	c.fail(ErrClosed)
+	return c.netConn.Close()
+}
+
+func natsError(line string) error {
+	message := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+	return errors.New("nats: " + message)
+}
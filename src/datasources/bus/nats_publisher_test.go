@@ -0,0 +1,241 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// natsTestServer is an embedded server implementing the part of the NATS protocol a
+// publisher uses.
+type natsTestServer struct {
+	listener   net.Listener
+	maxPayload int
+	rejectWith string
+
+	mutex    sync.Mutex
+	connects []natsConnectOptions
+	messages []Message
+	conns    []net.Conn
+}
+
+func startNatsTestServer(t *testing.T, configure ...func(*natsTestServer)) *natsTestServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &natsTestServer{listener: listener, maxPayload: 1024}
+	for _, apply := range configure {
+		apply(server)
+	}
+	go server.serve()
+	t.Cleanup(server.stop)
+	return server
+}
+
+func (s *natsTestServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *natsTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *natsTestServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"max_payload\":%d}\r\n", s.maxPayload)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			var options natsConnectOptions
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &options)
+			s.mutex.Lock()
+			s.connects = append(s.connects, options)
+			s.mutex.Unlock()
+			if s.rejectWith != "" {
+				fmt.Fprintf(conn, "-ERR '%s'\r\n", s.rejectWith)
+				return
+			}
+		case line == "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case strings.HasPrefix(line, "PUB "):
+			parts := strings.Fields(line)
+			size, _ := strconv.Atoi(parts[len(parts)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.messages = append(s.messages, Message{Subject: parts[1], Data: payload[:size]})
+			s.mutex.Unlock()
+		}
+	}
+}
+
+func (s *natsTestServer) connections() []natsConnectOptions {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]natsConnectOptions(nil), s.connects...)
+}
+
+func (s *natsTestServer) received() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// dropConnections closes every client connection, as a restarting server would.
+func (s *natsTestServer) dropConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *natsTestServer) stop() {
+	s.listener.Close()
+	s.dropConnections()
+}
+
+func TestNatsPublishOK(t *testing.T) {
+	server := startNatsTestServer(t)
+
+	publisher, err := DialNats(server.url(), time.Second)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	assert.Nil(t, publisher.Publish("tokenalert.user.created", []byte(`{"id":"1"}`)))
+	assert.Nil(t, publisher.Publish("tokenalert.user.deleted", []byte(`{"id":"2"}`)))
+
+	messages := server.received()
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "tokenalert.user.created", messages[0].Subject)
+	assert.Equal(t, `{"id":"1"}`, string(messages[0].Data))
+	assert.Equal(t, "tokenalert.user.deleted", messages[1].Subject)
+}
+
+func TestNatsDialSendsCredentials(t *testing.T) {
+	server := startNatsTestServer(t)
+
+	publisher, err := DialNats(strings.Replace(server.url(), "nats://", "nats://alerts:s3cret@", 1), time.Second)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	connects := server.connections()
+	assert.Equal(t, 1, len(connects))
+	assert.Equal(t, "alerts", connects[0].User)
+	assert.Equal(t, "s3cret", connects[0].Password)
+	assert.Equal(t, natsClientName, connects[0].Name)
+}
+
+func TestNatsDialRejected(t *testing.T) {
+	server := startNatsTestServer(t, func(server *natsTestServer) {
+		server.rejectWith = "Authorization Violation"
+	})
+
+	publisher, err := DialNats(server.url(), time.Second)
+
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+	assert.Equal(t, "nats: Authorization Violation", err.Error())
+}
+
+func TestNatsDialInvalidUrl(t *testing.T) {
+	_, err := DialNats("http://localhost:4222", time.Second)
+
+	assert.NotNil(t, err)
+}
+
+func TestNatsPublishRejectsOversizedPayload(t *testing.T) {
+	server := startNatsTestServer(t, func(server *natsTestServer) {
+		server.maxPayload = 8
+	})
+
+	publisher, err := DialNats(server.url(), time.Second)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	err = publisher.Publish("tokenalert.user.created", []byte(`{"id":"too long"}`))
+
+	assert.NotNil(t, err)
+	assert.Empty(t, server.received())
+}
+
+func TestNatsPublishReconnects(t *testing.T) {
+	server := startNatsTestServer(t)
+
+	publisher, err := DialNats(server.url(), time.Second)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	server.dropConnections()
+	// The first publish may or may not notice the dropped connection before writing.
+	var publishErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if publishErr = publisher.Publish("tokenalert.user.created", []byte(`{}`)); publishErr == nil {
+			break
+		}
+	}
+
+	assert.Nil(t, publishErr)
+	assert.Equal(t, 1, len(server.received()))
+}
+
+func TestNatsPublishAfterClose(t *testing.T) {
+	server := startNatsTestServer(t)
+
+	publisher, err := DialNats(server.url(), time.Second)
+	assert.Nil(t, err)
+	publisher.Close()
+
+	assert.Equal(t, ErrClosed, publisher.Publish("tokenalert.user.created", []byte(`{}`)))
+}
+
+func TestNatsPing(t *testing.T) {
+	server := startNatsTestServer(t)
+
+	publisher, err := DialNats(server.url(), time.Second)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	assert.Nil(t, publisher.Ping(context.Background()))
+	assert.Empty(t, server.received())
+
+	server.stop()
+	// The first ping may or may not notice the dropped connection before writing.
+	for attempt := 0; attempt < 3 && err == nil; attempt++ {
+		err = publisher.Ping(context.Background())
+	}
+	assert.NotNil(t, err)
+
+	publisher.Close()
+	assert.Equal(t, ErrClosed, publisher.Ping(context.Background()))
+}
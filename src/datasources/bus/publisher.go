@@ -0,0 +1,94 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSubjectPrefix = "tokenalert"
+	publishTimeout       = 5 * time.Second
+)
+
+var (
+	// Client stays nil, and nothing is published, unless InitPublisher finds a bus
+	// configured.
+	Client Publisher
+
+	// Routes maps event types to the subject, or topic, they are published on.
+	Routes = Subjects{Prefix: defaultSubjectPrefix}
+)
+
+// Config names the bus, as nats://[user:password@]host[:port], and the subjects
+// events go to. Publishing is disabled without Url.
+type Config struct {
+	Url      string
+	Subjects Subjects
+}
+
+// DefaultConfig returns the settings used for anything left unset.
+func DefaultConfig() Config {
+	return Config{Subjects: Subjects{Prefix: defaultSubjectPrefix, Overrides: map[string]string{}}}
+}
+
+func (c Config) Validate() error {
+	for eventType, subject := range c.Subjects.Overrides {
+		if strings.TrimSpace(eventType) == "" || !validSubject(subject) {
+			return fmt.Errorf("invalid subject mapping %q", eventType+"="+subject)
+		}
+	}
+	return nil
+}
+
+// Publisher delivers an already encoded message to a subject. Implementations return
+// only once the broker has accepted the message, so callers can retry on error.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+	// Ping checks the broker answers, without publishing anything.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Subjects routes an event type to Prefix.<event type> unless Overrides names a
+// subject for it.
+type Subjects struct {
+	Prefix    string
+	Overrides map[string]string
+}
+
+func (s Subjects) For(eventType string) string {
+	if subject, ok := s.Overrides[eventType]; ok {
+		return subject
+	}
+	if s.Prefix == "" {
+		return eventType
+	}
+	return s.Prefix + "." + eventType
+}
+
+func validSubject(subject string) bool {
+	return subject != "" && !strings.ContainsAny(subject, " \t\r\n")
+}
+
+// InitPublisher connects to the bus named by config.Url. Publishing stays disabled
+// when it is not set.
+func InitPublisher(config Config) {
+	if config.Url == "" {
+		log.Println("event bus not configured, events are only sent to webhooks")
+		return
+	}
+	if err := config.Validate(); err != nil {
+		panic(err)
+	}
+
+	publisher, err := DialNats(config.Url, publishTimeout)
+	if err != nil {
+		panic(err)
+	}
+	Routes = config.Subjects
+	Client = publisher
+	log.Println("event bus successfully configured")
+}
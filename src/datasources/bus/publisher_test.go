@@ -0,0 +1,42 @@
+package bus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubjectsForUsesPrefixAndOverrides(t *testing.T) {
+	subjects := Subjects{Prefix: "tokenalert", Overrides: map[string]string{"user.created": "crm.signups", "user.deleted": "crm.churn"}}
+
+	assert.Equal(t, "crm.signups", subjects.For("user.created"))
+	assert.Equal(t, "crm.churn", subjects.For("user.deleted"))
+	assert.Equal(t, "tokenalert.user.updated", subjects.For("user.updated"))
+}
+
+func TestConfigValidateInvalidMapping(t *testing.T) {
+	config := DefaultConfig()
+	config.Subjects.Overrides["user.created"] = "crm signups"
+
+	err := config.Validate()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid subject mapping "user.created=crm signups"`, err.Error())
+}
+
+func TestMemoryPublisherKeepsMessagesInOrder(t *testing.T) {
+	publisher := NewMemoryPublisher()
+
+	assert.Nil(t, publisher.Publish("tokenalert.user.created", []byte("1")))
+	assert.Nil(t, publisher.Publish("tokenalert.user.deleted", []byte("2")))
+	assert.NotNil(t, publisher.Publish("", []byte("3")))
+	publisher.Close()
+
+	assert.Equal(t, ErrClosed, publisher.Publish("tokenalert.user.updated", []byte("4")))
+	assert.Equal(t, ErrClosed, publisher.Ping(context.Background()))
+	assert.Equal(t, []Message{
+		{Subject: "tokenalert.user.created", Data: []byte("1")},
+		{Subject: "tokenalert.user.deleted", Data: []byte("2")},
+	}, publisher.Messages())
+}
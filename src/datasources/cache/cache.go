@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// MemoryUrl keeps the cache in the process instead of a Redis server.
+	MemoryUrl = "memory"
+
+	storeTimeout = time.Second
+)
+
+var (
+	ErrClosed = errors.New("cache is closed")
+
+	// Client stays nil, and lookups go to the database, unless InitCache finds a cache
+	// configured.
+	Client Store
+
+	// Settings holds the configuration Client was opened with.
+	Settings = DefaultConfig()
+)
+
+// Store keeps values for a while. Implementations may drop a value before it expires,
+// so callers must be able to rebuild anything they store.
+type Store interface {
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+// Config describes the cache: Url is either MemoryUrl or redis://[:password@]host[:port][/db],
+// Size bounds the entries of the memory store, TTL is how long found values are kept
+// and NegativeTTL how long a miss is remembered.
+type Config struct {
+	Url         string
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// DefaultConfig returns the settings used for anything left unset, which leave the
+// cache off.
+func DefaultConfig() Config {
+	return Config{Size: 10000, TTL: time.Minute, NegativeTTL: 10 * time.Second}
+}
+
+func (c Config) Validate() error {
+	if c.Url != "" && c.Url != MemoryUrl && !strings.HasPrefix(c.Url, "redis://") {
+		return fmt.Errorf("invalid cache.url %q", c.Url)
+	}
+	if c.Size < 1 {
+		return errors.New("cache.size must be at least 1")
+	}
+	if c.TTL <= 0 {
+		return errors.New("cache.ttl must be above zero")
+	}
+	return nil
+}
+
+// Open returns the store config.Url names.
+func Open(config Config) (Store, error) {
+	if config.Url == MemoryUrl {
+		return NewMemoryStore(config.Size), nil
+	}
+	return DialRedis(config.Url, storeTimeout)
+}
+
+// InitCache opens the store named by config.Url. Caching stays disabled when it is
+// not set.
+func InitCache(config Config) {
+	if config.Url == "" {
+		log.Println("users cache not configured, every lookup goes to the database")
+		return
+	}
+	if err := config.Validate(); err != nil {
+		panic(err)
+	}
+
+	store, err := Open(config)
+	if err != nil {
+		panic(err)
+	}
+	Client = store
+	Settings = config
+	log.Println("users cache successfully configured")
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	now = time.Now
+)
+
+// MemoryStore keeps up to size values in the process, evicting the least recently
+// used one to make room. Expired values are dropped when they are read or evicted.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	recency *list.List
+	closed  bool
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{size: size, entries: map[string]*list.Element{}, recency: list.New()}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return nil, false, ErrClosed
+	}
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !now().Before(entry.expires) {
+		s.remove(element)
+		return nil, false, nil
+	}
+	s.recency.MoveToFront(element)
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	entry := &memoryEntry{key: key, value: append([]byte(nil), value...), expires: now().Add(ttl)}
+	if element, ok := s.entries[key]; ok {
+		element.Value = entry
+		s.recency.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.recency.PushFront(entry)
+	for s.recency.Len() > s.size {
+		s.remove(s.recency.Back())
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	for _, key := range keys {
+		if element, ok := s.entries[key]; ok {
+			s.remove(element)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	s.entries = map[string]*list.Element{}
+	s.recency.Init()
+	return nil
+}
+
+// Len returns the number of values held, expired ones included.
+func (s *MemoryStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.recency.Len()
+}
+
+func (s *MemoryStore) remove(element *list.Element) {
+	s.recency.Remove(element)
+	delete(s.entries, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withClock(t *testing.T) *time.Time {
+	current := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return &current
+}
+
+func TestMemoryStoreGetSet(t *testing.T) {
+	store := NewMemoryStore(10)
+	ctx := context.Background()
+
+	_, found, err := store.Get(ctx, "users:1")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	assert.Nil(t, store.Set(ctx, "users:1", []byte("john"), time.Minute))
+	value, found, err := store.Get(ctx, "users:1")
+
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "john", string(value))
+}
+
+func TestMemoryStoreExpires(t *testing.T) {
+	clock := withClock(t)
+	store := NewMemoryStore(10)
+	ctx := context.Background()
+	assert.Nil(t, store.Set(ctx, "users:1", []byte("john"), time.Minute))
+
+	*clock = clock.Add(59 * time.Second)
+	_, found, _ := store.Get(ctx, "users:1")
+	assert.True(t, found)
+
+	*clock = clock.Add(time.Second)
+	_, found, _ = store.Get(ctx, "users:1")
+	assert.False(t, found)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryStore(2)
+	ctx := context.Background()
+	store.Set(ctx, "users:1", []byte("john"), time.Minute)
+	store.Set(ctx, "users:2", []byte("jane"), time.Minute)
+	store.Get(ctx, "users:1")
+
+	store.Set(ctx, "users:3", []byte("jim"), time.Minute)
+
+	_, found, _ := store.Get(ctx, "users:2")
+	assert.False(t, found)
+	_, found, _ = store.Get(ctx, "users:1")
+	assert.True(t, found)
+	_, found, _ = store.Get(ctx, "users:3")
+	assert.True(t, found)
+	assert.Equal(t, 2, store.Len())
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	store := NewMemoryStore(10)
+	ctx := context.Background()
+	store.Set(ctx, "users:1", []byte("john"), time.Minute)
+	store.Set(ctx, "users:2", []byte("jane"), time.Minute)
+
+	assert.Nil(t, store.Delete(ctx, "users:1", "users:2", "users:3"))
+
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestMemoryStoreClosed(t *testing.T) {
+	store := NewMemoryStore(10)
+	store.Close()
+
+	_, _, err := store.Get(context.Background(), "users:1")
+
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig()
+	config.Url = "memcached://cache:11211"
+
+	err := config.Validate()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid cache.url "memcached://cache:11211"`, err.Error())
+
+	config = DefaultConfig()
+	config.TTL = 0
+
+	err = config.Validate()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "cache.ttl must be above zero", err.Error())
+}
@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisDefaultPort   = "6379"
+	redisLineSeparator = "\r\n"
+)
+
+// redisStore speaks the Redis protocol (RESP2) over a single connection, which is
+// enough for the short commands of a cache and works with any server speaking it. A
+// command failing on a connection that was already open is sent again on a new one,
+// so an invalidation is not lost because the server restarted in between.
+type redisStore struct {
+	address  string
+	user     string
+	password string
+	database int
+	timeout  time.Duration
+
+	mutex  sync.Mutex
+	conn   *redisConn
+	closed bool
+}
+
+// redisError is an error reply of the server. It leaves the connection usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// DialRedis connects to a server given as redis://[[user]:password@]host[:port][/db].
+func DialRedis(address string, timeout time.Duration) (Store, error) {
+	if !strings.Contains(address, "://") {
+		address = "redis://" + address
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Scheme != "redis" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid redis url %q", address)
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = redisDefaultPort
+	}
+
+	store := &redisStore{address: net.JoinHostPort(parsed.Hostname(), port), timeout: timeout}
+	if parsed.User != nil {
+		store.user = parsed.User.Username()
+		store.password, _ = parsed.User.Password()
+	}
+	if database := strings.Trim(parsed.Path, "/"); database != "" {
+		if store.database, err = strconv.Atoi(database); err != nil || store.database < 0 {
+			return nil, fmt.Errorf("invalid redis database %q", database)
+		}
+	}
+
+	if store.conn, err = store.dial(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	return reply.value, !reply.null, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	milliseconds := ttl.Milliseconds()
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+	_, err := s.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(milliseconds, 10))
+	return err
+}
+
+func (s *redisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := s.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+func (s *redisStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.close()
+	s.conn = nil
+	return err
+}
+
+func (s *redisStore) do(ctx context.Context, args ...string) (redisReply, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return redisReply{}, ErrClosed
+	}
+
+	for attempt := 0; ; attempt++ {
+		reused := s.conn != nil
+		if !reused {
+			conn, err := s.dial()
+			if err != nil {
+				return redisReply{}, err
+			}
+			s.conn = conn
+		}
+
+		reply, err := s.conn.do(ctx, s.timeout, args)
+		var replyErr redisError
+		if err == nil || errors.As(err, &replyErr) {
+			return reply, err
+		}
+		s.conn.close()
+		s.conn = nil
+		if !reused || attempt > 0 || ctx.Err() != nil {
+			return redisReply{}, err
+		}
+	}
+}
+
+// dial opens a connection, authenticating and selecting the database when the url
+// asks for it.
+func (s *redisStore) dial() (*redisConn, error) {
+	netConn, err := net.DialTimeout("tcp", s.address, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{netConn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}
+
+	commands := make([][]string, 0, 2)
+	switch {
+	case s.user != "" && s.password != "":
+		commands = append(commands, []string{"AUTH", s.user, s.password})
+	case s.password != "":
+		commands = append(commands, []string{"AUTH", s.password})
+	}
+	if s.database != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(s.database)})
+	}
+	for _, command := range commands {
+		if _, err := conn.do(context.Background(), s.timeout, command); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+type redisConn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+type redisReply struct {
+	value []byte
+	null  bool
+}
+
+// do sends a command and reads its reply, giving up at the deadline of ctx or after
+// timeout, whichever comes first.
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args []string) (redisReply, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.netConn.SetDeadline(deadline)
+	defer c.netConn.SetDeadline(time.Time{})
+
+	c.writer.WriteString("*" + strconv.Itoa(len(args)) + redisLineSeparator)
+	for _, arg := range args {
+		c.writer.WriteString("$" + strconv.Itoa(len(arg)) + redisLineSeparator + arg + redisLineSeparator)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return redisReply{}, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (redisReply, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return redisReply{}, err
+	}
+	line = strings.TrimRight(line, redisLineSeparator)
+	if line == "" {
+		return redisReply{}, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return redisReply{value: []byte(line[1:])}, nil
+	case '-':
+		return redisReply{}, redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return redisReply{}, fmt.Errorf("invalid redis reply %q", line)
+		}
+		if size < 0 {
+			return redisReply{null: true}, nil
+		}
+		payload := make([]byte, size+len(redisLineSeparator))
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return redisReply{}, err
+		}
+		return redisReply{value: payload[:size]}, nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return redisReply{}, fmt.Errorf("invalid redis reply %q", line)
+		}
+		for i := 0; i < count; i++ {
+			if _, err := c.readReply(); err != nil {
+				return redisReply{}, err
+			}
+		}
+		return redisReply{null: count < 0}, nil
+	}
+	return redisReply{}, fmt.Errorf("invalid redis reply %q", line)
+}
+
+func (c *redisConn) close() error {
+	return c.netConn.Close()
+}
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// redisTestServer is an embedded stand-in implementing the commands a redisStore
+// sends, expiring values with the clock of the test.
+type redisTestServer struct {
+	listener net.Listener
+	password string
+
+	mutex    sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	commands [][]string
+	conns    []net.Conn
+}
+
+func startRedisTestServer(t *testing.T, configure ...func(*redisTestServer)) *redisTestServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &redisTestServer{listener: listener, values: map[string]string{}, expires: map[string]time.Time{}}
+	for _, apply := range configure {
+		apply(server)
+	}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *redisTestServer) url() string {
+	return "redis://" + s.listener.Addr().String()
+}
+
+func (s *redisTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *redisTestServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.commands = append(s.commands, args)
+		s.mutex.Unlock()
+
+		if !authenticated && args[0] != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		io.WriteString(conn, s.reply(args, &authenticated))
+	}
+}
+
+func (s *redisTestServer) reply(args []string, authenticated *bool) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch args[0] {
+	case "AUTH":
+		if args[len(args)-1] != s.password {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+		*authenticated = true
+		return "+OK\r\n"
+	case "SELECT", "PING":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.values[args[1]]
+		if !ok || !now().Before(s.expires[args[1]]) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		milliseconds, _ := strconv.Atoi(args[4])
+		s.values[args[1]] = args[2]
+		s.expires[args[1]] = now().Add(time.Duration(milliseconds) * time.Millisecond)
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.values[key]; ok {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(strings.TrimRight(line[1:], "\r\n"))
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimRight(line[1:], "\r\n"))
+		payload := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, err
+		}
+		args[i] = string(payload[:size])
+	}
+	return args, nil
+}
+
+func (s *redisTestServer) received() [][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+// dropConnections closes every client connection, as a restarting server would.
+func (s *redisTestServer) dropConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func TestRedisStoreGetSetDelete(t *testing.T) {
+	server := startRedisTestServer(t)
+	store, err := DialRedis(server.url(), time.Second)
+	assert.Nil(t, err)
+	defer store.Close()
+	ctx := context.Background()
+
+	_, found, err := store.Get(ctx, "users:1")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	assert.Nil(t, store.Set(ctx, "users:1", []byte("john\r\n"), time.Minute))
+	value, found, err := store.Get(ctx, "users:1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "john\r\n", string(value))
+
+	assert.Nil(t, store.Delete(ctx, "users:1", "users:2"))
+	_, found, _ = store.Get(ctx, "users:1")
+	assert.False(t, found)
+
+	assert.Contains(t, server.received(), []string{"SET", "users:1", "john\r\n", "PX", "60000"})
+}
+
+func TestRedisStoreKeepsEmptyValues(t *testing.T) {
+	server := startRedisTestServer(t)
+	store, _ := DialRedis(server.url(), time.Second)
+	defer store.Close()
+	ctx := context.Background()
+
+	assert.Nil(t, store.Set(ctx, "users:1", []byte{}, time.Minute))
+	value, found, err := store.Get(ctx, "users:1")
+
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Empty(t, value)
+}
+
+func TestRedisStoreExpires(t *testing.T) {
+	clock := withClock(t)
+	server := startRedisTestServer(t)
+	store, _ := DialRedis(server.url(), time.Second)
+	defer store.Close()
+	ctx := context.Background()
+	store.Set(ctx, "users:1", []byte("john"), 10*time.Second)
+
+	*clock = clock.Add(10 * time.Second)
+	_, found, err := store.Get(ctx, "users:1")
+
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestRedisStoreAuthenticatesAndSelectsDatabase(t *testing.T) {
+	server := startRedisTestServer(t, func(s *redisTestServer) { s.password = "secret" })
+
+	store, err := DialRedis("redis://:secret@"+server.listener.Addr().String()+"/2", time.Second)
+
+	assert.Nil(t, err)
+	defer store.Close()
+	assert.Nil(t, store.Set(context.Background(), "users:1", []byte("john"), time.Minute))
+	assert.Equal(t, []string{"AUTH", "secret"}, server.received()[0])
+	assert.Equal(t, []string{"SELECT", "2"}, server.received()[1])
+}
+
+func TestRedisStoreWrongPassword(t *testing.T) {
+	server := startRedisTestServer(t, func(s *redisTestServer) { s.password = "secret" })
+
+	_, err := DialRedis("redis://:wrong@"+server.listener.Addr().String(), time.Second)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "redis: WRONGPASS invalid username-password pair", err.Error())
+}
+
+func TestRedisStoreInvalidUrl(t *testing.T) {
+	_, err := DialRedis("http://cache:6379", time.Second)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid redis url "http://cache:6379"`, err.Error())
+}
+
+func TestRedisStoreRedialsAfterServerRestart(t *testing.T) {
+	server := startRedisTestServer(t)
+	store, _ := DialRedis(server.url(), time.Second)
+	defer store.Close()
+	ctx := context.Background()
+	store.Set(ctx, "users:1", []byte("john"), time.Minute)
+
+	server.dropConnections()
+	err := store.Delete(ctx, "users:1")
+
+	assert.Nil(t, err)
+	_, found, _ := store.Get(ctx, "users:1")
+	assert.False(t, found)
+}
+
+func TestRedisStoreClosed(t *testing.T) {
+	server := startRedisTestServer(t)
+	store, _ := DialRedis(server.url(), time.Second)
+	store.Close()
+
+	err := store.Set(context.Background(), "users:1", []byte("john"), time.Minute)
+
+	assert.Equal(t, ErrClosed, err)
+}
@@ -0,0 +1,338 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+)
+
+const (
+	queryCreateSchemaMigrations = "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL, PRIMARY KEY (version)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;"
+	queryFindAppliedMigrations  = "SELECT version, applied_at FROM schema_migrations ORDER BY version;"
+	queryInsertSchemaMigration  = "INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, ?);"
+	queryDeleteSchemaMigration  = "DELETE FROM schema_migrations WHERE version=?;"
+	queryGetLock                = "SELECT GET_LOCK(?, ?);"
+	queryReleaseLock            = "SELECT RELEASE_LOCK(?);"
+
+	queryCountSchemaMigrationsTables = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema=DATABASE() AND table_name='schema_migrations';"
+
+	queryCreateSchemaMigrationsPortable      = "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL);"
+	queryCountSchemaMigrationsTablesPortable = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema=current_schema() AND table_name='schema_migrations';"
+	queryCountSchemaMigrationsTablesSQLite   = "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations';"
+	queryGetAdvisoryLock                     = "SELECT 1 FROM pg_advisory_lock(hashtext(?));"
+	queryReleaseAdvisoryLock                 = "SELECT pg_advisory_unlock(hashtext(?));"
+
+	lockName         = "tokenalert_user-api.schema_migrations"
+	lockTimeoutSecs  = 60
+	dbDateTimeLayout = "2006-01-02 15:04:05"
+)
+
+//go:embed sql
+var files embed.FS
+
+// schema holds the statements the migrator runs itself, which differ per dialect.
+// SQLite needs no lock, a single writer holds the database file at a time anyway.
+type schema struct {
+	createTable string
+	countTables string
+	lock        string
+	lockArgs    []interface{}
+	unlock      string
+}
+
+var schemas = map[string]schema{
+	users_db.MySQL.Name:      {createTable: queryCreateSchemaMigrations, countTables: queryCountSchemaMigrationsTables, lock: queryGetLock, lockArgs: []interface{}{lockName, lockTimeoutSecs}, unlock: queryReleaseLock},
+	users_db.PostgreSQL.Name: {createTable: queryCreateSchemaMigrationsPortable, countTables: queryCountSchemaMigrationsTablesPortable, lock: queryGetAdvisoryLock, lockArgs: []interface{}{lockName}, unlock: queryReleaseAdvisoryLock},
+	users_db.SQLite.Name:     {createTable: queryCreateSchemaMigrationsPortable, countTables: queryCountSchemaMigrationsTablesSQLite},
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down|check)\.sql$`)
+
+// Migration is one versioned schema change. Versions only ever grow; a shipped
+// migration is never edited, a new one is added instead.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Check is an optional query run before Up, returning a line for each row the
+	// migration can not handle. Any line stops the migration, for the data to be
+	// fixed on purpose rather than rewritten by a schema change.
+	Check string
+}
+
+type Status struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// Load reads the migrations embedded in the binary for dialect, ordered by version.
+// Every dialect gets the tables of every repository, though each numbers its
+// migrations on its own.
+func Load(dialect users_db.Dialect) ([]Migration, error) {
+	return load(files, path.Join("sql", dialect.Name))
+}
+
+func load(source fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(parts[1], 10, 64)
+		content, err := fs.ReadFile(source, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		switch parts[3] {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		case "check":
+			migration.Check = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", migration.Version)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// statements splits a migration file on the semicolons ending its lines, leaving
+// out comment lines, since the driver runs a single statement per call.
+func statements(script string) []string {
+	result := make([]string, 0)
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    users_db.Dialect
+	schema     schema
+	migrations []Migration
+}
+
+func New(db *sql.DB, dialect users_db.Dialect, migrations []Migration) *Migrator {
+	return &Migrator{db: db, dialect: dialect, schema: schemas[dialect.Name], migrations: migrations}
+}
+
+// Up applies every pending migration in order and returns the ones applied. It
+// holds a named lock meanwhile, so instances starting together with auto-migrate on
+// do not race each other.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := check(ctx, conn, migration.Check); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if err := run(ctx, conn, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, m.dialect.Rebind(queryInsertSchemaMigration), migration.Version, migration.Name,
+				time.Now().UTC().Format(dbDateTimeLayout)); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest applied migration. It returns nil when nothing is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for index := len(m.migrations) - 1; index >= 0; index-- {
+			migration := m.migrations[index]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := run(ctx, conn, migration.Down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, m.dialect.Rebind(queryDeleteSchemaMigration), migration.Version); err != nil {
+				return err
+			}
+			reverted = &migration
+			return nil
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists the migrations and whether each is applied. It only reads, so it can
+// back readiness probes and run with read-only users: every migration is pending
+// while schema_migrations does not exist.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var tables int
+	if err := m.db.QueryRowContext(ctx, m.schema.countTables).Scan(&tables); err != nil {
+		return nil, err
+	}
+	done := map[int64]string{}
+	if tables > 0 {
+		var err error
+		if done, err = findApplied(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := done[migration.Version]
+		result = append(result, Status{Version: migration.Version, Name: migration.Name, Applied: ok, AppliedAt: appliedAt})
+	}
+	return result, nil
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.schema.lock != "" {
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, m.dialect.Rebind(m.schema.lock), m.schema.lockArgs...).Scan(&acquired); err != nil {
+			return err
+		}
+		if acquired.Int64 != 1 {
+			return fmt.Errorf("timeout waiting for lock %s", lockName)
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.Rebind(m.schema.unlock), lockName)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions creates schema_migrations on first use, and returns when each
+// applied version was applied.
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]string, error) {
+	if _, err := conn.ExecContext(ctx, m.schema.createTable); err != nil {
+		return nil, err
+	}
+	return findApplied(ctx, conn)
+}
+
+func findApplied(ctx context.Context, db interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}) (map[int64]string, error) {
+	rows, err := db.QueryContext(ctx, queryFindAppliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[int64]string{}
+	for rows.Next() {
+		var version int64
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt
+	}
+	return result, rows.Err()
+}
+
+// check runs the check query of a migration, if it has one, and fails with the lines
+// it returns.
+func check(ctx context.Context, conn *sql.Conn, query string) error {
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	conflicts := make([]string, 0)
+	for rows.Next() {
+		var conflict string
+		if err := rows.Scan(&conflict); err != nil {
+			return err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("resolve before migrating: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// run executes the statements of a script one by one. MySQL commits DDL implicitly,
+// so a failing migration is left half applied and has to be fixed by hand.
+func run(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, statement := range statements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
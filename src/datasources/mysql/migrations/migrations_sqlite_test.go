@@ -0,0 +1,51 @@
+//go:build cgo
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrationsLoadForEveryDialect(t *testing.T) {
+	for _, dialect := range []users_db.Dialect{users_db.PostgreSQL, users_db.SQLite} {
+		all, err := Load(dialect)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"create_users", "create_outbox"}, []string{all[0].Name, all[1].Name})
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	db, err := sql.Open(users_db.SQLite.Driver, "file:"+filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	all, err := Load(users_db.SQLite)
+	assert.Nil(t, err)
+	migrator := New(db, users_db.SQLite, all)
+
+	statuses, err := migrator.Status(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, statuses[0].Applied)
+
+	applied, err := migrator.Up(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(all), len(applied))
+
+	reverted, err := migrator.Down(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "create_webhook_queue", reverted.Name)
+
+	statuses, err = migrator.Status(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.NotEmpty(t, statuses[0].AppliedAt)
+	assert.False(t, statuses[len(statuses)-1].Applied)
+}
@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id BIGINT);", Down: "DROP TABLE users;"},
+	{Version: 2, Name: "create_webhooks", Up: "CREATE TABLE webhooks (id BIGINT);\nCREATE TABLE webhook_deliveries (id BIGINT);", Down: "DROP TABLE webhook_deliveries;\nDROP TABLE webhooks;"},
+}
+
+func newMock(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db, users_db.MySQL, testMigrations), mock
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(queryGetLock).WithArgs(lockName, lockTimeoutSecs).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int64) {
+	mock.ExpectExec(queryCreateSchemaMigrations).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, "2022-09-20 10:00:00")
+	}
+	mock.ExpectQuery(queryFindAppliedMigrations).WillReturnRows(rows)
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	all, err := Load(users_db.MySQL)
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, all)
+	for index, migration := range all {
+		assert.Equal(t, int64(index+1), migration.Version)
+		assert.NotEmpty(t, statements(migration.Up))
+		assert.NotEmpty(t, statements(migration.Down))
+	}
+	assert.Equal(t, "create_users", all[0].Name)
+	assert.Equal(t, "add_users_email_unique", all[11].Name)
+	assert.NotEmpty(t, all[11].Check)
+}
+
+func TestLoadRejectsMissingDownFile(t *testing.T) {
+	source := fstest.MapFS{
+		"sql/0001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id BIGINT);")},
+	}
+
+	_, err := load(source, "sql")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "migration 1 needs both an up and a down file", err.Error())
+}
+
+func TestLoadRejectsInvalidFileName(t *testing.T) {
+	source := fstest.MapFS{
+		"sql/create_users.sql": {Data: []byte("CREATE TABLE users (id BIGINT);")},
+	}
+
+	_, err := load(source, "sql")
+
+	assert.NotNil(t, err)
+}
+
+func TestStatementsSplitsOnLineEndingSemicolons(t *testing.T) {
+	script := "-- users\nCREATE TABLE users (\n  id BIGINT,\n  name VARCHAR(255) DEFAULT ';'\n);\n\nDROP TABLE old;\n"
+
+	assert.Equal(t, []string{
+		"CREATE TABLE users (\n  id BIGINT,\n  name VARCHAR(255) DEFAULT ';'\n);",
+		"DROP TABLE old;",
+	}, statements(script))
+}
+
+func TestUpAppliesPendingMigrations(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	expectLock(mock)
+	expectApplied(mock, 1)
+	mock.ExpectExec("CREATE TABLE webhooks (id BIGINT);").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE webhook_deliveries (id BIGINT);").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(queryInsertSchemaMigration).WithArgs(2, "create_webhooks", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(queryReleaseLock).WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(applied))
+	assert.Equal(t, int64(2), applied[0].Version)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpStopsAtFailingMigration(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	expectLock(mock)
+	expectApplied(mock)
+	mock.ExpectExec("CREATE TABLE users (id BIGINT);").WillReturnError(errors.New("table exists"))
+	mock.ExpectExec(queryReleaseLock).WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "migration 1_create_users: table exists", err.Error())
+	assert.Empty(t, applied)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpStopsWhenCheckFindsConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checked := []Migration{{Version: 1, Name: "add_users_email_unique", Check: "SELECT conflict FROM users;", Up: "ALTER TABLE users ADD UNIQUE KEY (email);", Down: "DO 0;"}}
+	migrator := New(db, users_db.MySQL, checked)
+
+	expectLock(mock)
+	expectApplied(mock)
+	mock.ExpectQuery("SELECT conflict FROM users;").WillReturnRows(sqlmock.NewRows([]string{"conflict"}).
+		AddRow("email a@mail.com is shared by users 1, 4").
+		AddRow("email b@mail.com is shared by users 2, 3"))
+	mock.ExpectExec(queryReleaseLock).WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "migration 1_add_users_email_unique: resolve before migrating: email a@mail.com is shared by users 1, 4; email b@mail.com is shared by users 2, 3", err.Error())
+	assert.Empty(t, applied)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpFailsWhenLockIsTaken(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	mock.ExpectQuery(queryGetLock).WithArgs(lockName, lockTimeoutSecs).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+
+	_, err := migrator.Up(context.Background())
+
+	assert.NotNil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDownRevertsLatestMigration(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	expectLock(mock)
+	expectApplied(mock, 1, 2)
+	mock.ExpectExec("DROP TABLE webhook_deliveries;").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DROP TABLE webhooks;").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(queryDeleteSchemaMigration).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(queryReleaseLock).WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := migrator.Down(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), reverted.Version)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDownWithNothingApplied(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	expectLock(mock)
+	expectApplied(mock)
+	mock.ExpectExec(queryReleaseLock).WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := migrator.Down(context.Background())
+
+	assert.Nil(t, err)
+	assert.Nil(t, reverted)
+}
+
+func TestStatusListsAppliedAndPending(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	mock.ExpectQuery(queryCountSchemaMigrationsTables).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(queryFindAppliedMigrations).WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, "2022-09-20 10:00:00"))
+
+	statuses, err := migrator.Status(context.Background())
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []Status{
+		{Version: 1, Name: "create_users", Applied: true, AppliedAt: "2022-09-20 10:00:00"},
+		{Version: 2, Name: "create_webhooks", Applied: false},
+	}, statuses)
+}
+
+func TestStatusWithoutSchemaMigrationsTable(t *testing.T) {
+	migrator, mock := newMock(t)
+
+	mock.ExpectQuery(queryCountSchemaMigrationsTables).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	statuses, err := migrator.Status(context.Background())
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 2, len(statuses))
+	assert.False(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+}
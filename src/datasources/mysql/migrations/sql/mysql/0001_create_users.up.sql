@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS users (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  telegram_user VARCHAR(255) NOT NULL DEFAULT '',
+  status VARCHAR(45) NOT NULL,
+  password VARCHAR(255) NOT NULL,
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY users_email_unique (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
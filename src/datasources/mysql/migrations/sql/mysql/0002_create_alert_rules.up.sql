@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  user_id BIGINT NOT NULL,
+  token VARCHAR(64) NOT NULL,
+  type VARCHAR(45) NOT NULL,
+  threshold DOUBLE NOT NULL,
+  window_minutes BIGINT NOT NULL DEFAULT 0,
+  cooldown_minutes BIGINT NOT NULL DEFAULT 0,
+  enabled TINYINT(1) NOT NULL DEFAULT 1,
+  expires_at DATETIME NULL,
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY alert_rules_user_id (user_id),
+  KEY alert_rules_active (enabled, expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
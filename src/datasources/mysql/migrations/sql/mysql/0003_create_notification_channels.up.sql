@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS notification_channels (
+  user_id BIGINT NOT NULL,
+  type VARCHAR(45) NOT NULL,
+  target VARCHAR(512) NOT NULL,
+  enabled TINYINT(1) NOT NULL DEFAULT 1,
+  verified TINYINT(1) NOT NULL DEFAULT 0,
+  is_primary TINYINT(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
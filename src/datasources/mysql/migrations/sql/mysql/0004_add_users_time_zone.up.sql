@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC' AFTER telegram_user;
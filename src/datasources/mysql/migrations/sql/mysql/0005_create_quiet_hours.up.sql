@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS quiet_hours (
+  user_id BIGINT NOT NULL,
+  enabled TINYINT(1) NOT NULL DEFAULT 0,
+  urgent_bypass TINYINT(1) NOT NULL DEFAULT 0,
+  windows JSON NOT NULL,
+  PRIMARY KEY (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_plans (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  user_id BIGINT NOT NULL,
+  plan VARCHAR(45) NOT NULL,
+  effective_from DATETIME NOT NULL,
+  effective_until DATETIME NULL,
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY user_plans_user_id (user_id, effective_from)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
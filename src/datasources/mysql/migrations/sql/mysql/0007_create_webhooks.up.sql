@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  url VARCHAR(2048) NOT NULL,
+  secret VARCHAR(255) NOT NULL,
+  event_types VARCHAR(1024) NOT NULL DEFAULT '',
+  enabled TINYINT(1) NOT NULL DEFAULT 1,
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  webhook_id BIGINT NOT NULL,
+  event_id VARCHAR(64) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  attempt INT NOT NULL,
+  status_code INT NOT NULL DEFAULT 0,
+  success TINYINT(1) NOT NULL DEFAULT 0,
+  error VARCHAR(1024) NOT NULL DEFAULT '',
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY webhook_deliveries_webhook_id (webhook_id, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
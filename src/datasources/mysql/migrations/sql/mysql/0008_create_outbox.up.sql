@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  event_id VARCHAR(64) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  user_id BIGINT NOT NULL,
+  payload JSON NOT NULL,
+  date_created DATETIME NOT NULL,
+  dispatched_at DATETIME NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY outbox_event_id (event_id),
+  KEY outbox_pending (dispatched_at, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  action VARCHAR(64) NOT NULL,
+  outcome VARCHAR(16) NOT NULL,
+  actor_id BIGINT NOT NULL,
+  target_user_id BIGINT NOT NULL,
+  request_id VARCHAR(128) NOT NULL,
+  changes JSON NULL,
+  details JSON NULL,
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY audit_log_action (action, id),
+  KEY audit_log_actor (actor_id, id),
+  KEY audit_log_target_user (target_user_id, id),
+  KEY audit_log_date_created (date_created)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+-- Entries are append-only, even for whoever holds write access to the table.
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
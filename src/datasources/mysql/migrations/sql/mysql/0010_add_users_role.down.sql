@@ -0,0 +1,2 @@
+DROP INDEX users_role ON users;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(45) NOT NULL DEFAULT 'user' AFTER status;
+CREATE INDEX users_role ON users (role);
@@ -0,0 +1,7 @@
+-- The unique key can not be added while users share an email. Which account keeps
+-- it is for support to decide, not for the migration.
+SELECT CONCAT('email ', email, ' is shared by users ', GROUP_CONCAT(id ORDER BY id SEPARATOR ', '))
+  FROM users
+  GROUP BY email
+  HAVING COUNT(*) > 1
+  ORDER BY email;
@@ -0,0 +1,2 @@
+-- The key stays: databases created by 0001 had it from the start.
+DO 0;
@@ -0,0 +1,7 @@
+-- 0001 creates users with IF NOT EXISTS, so databases that had the table before it
+-- never got the unique key. The check refuses to add it while users share an email.
+SET @users_email_unique = (SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'users_email_unique');
+SET @add_users_email_unique = IF(@users_email_unique = 0, 'ALTER TABLE users ADD UNIQUE KEY users_email_unique (email)', 'DO 0');
+PREPARE add_users_email_unique FROM @add_users_email_unique;
+EXECUTE add_users_email_unique;
+DEALLOCATE PREPARE add_users_email_unique;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS webhook_queue (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  webhook_id BIGINT NOT NULL,
+  event_id VARCHAR(64) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  payload JSON NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at DATETIME NOT NULL,
+  date_created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY webhook_queue_due (next_attempt_at, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS users (
+  id BIGSERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  telegram_user VARCHAR(255) NOT NULL DEFAULT '',
+  time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  status VARCHAR(45) NOT NULL,
+  password VARCHAR(255) NOT NULL,
+  date_created TIMESTAMP(0) NOT NULL,
+  CONSTRAINT users_email_unique UNIQUE (email)
+);
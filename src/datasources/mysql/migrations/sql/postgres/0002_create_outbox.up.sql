@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS outbox (
+  id BIGSERIAL PRIMARY KEY,
+  event_id VARCHAR(64) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  user_id BIGINT NOT NULL,
+  payload JSONB NOT NULL,
+  date_created TIMESTAMP(0) NOT NULL,
+  dispatched_at TIMESTAMP(0) NULL,
+  CONSTRAINT outbox_event_id UNIQUE (event_id)
+);
+CREATE INDEX IF NOT EXISTS outbox_pending ON outbox (dispatched_at, id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  action VARCHAR(64) NOT NULL,
+  outcome VARCHAR(16) NOT NULL,
+  actor_id BIGINT NOT NULL,
+  target_user_id BIGINT NOT NULL,
+  request_id VARCHAR(128) NOT NULL,
+  changes JSONB NULL,
+  details JSONB NULL,
+  date_created TIMESTAMP(0) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_action ON audit_log (action, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor ON audit_log (actor_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_target_user ON audit_log (target_user_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_date_created ON audit_log (date_created);
+-- Entries are append-only, even for whoever holds write access to the table.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$ BEGIN RAISE EXCEPTION 'audit_log is append-only'; END; $$ LANGUAGE plpgsql;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(45) NOT NULL DEFAULT 'user';
+CREATE INDEX users_role ON users (role);
@@ -0,0 +1,2 @@
+ALTER TABLE audit_log ADD COLUMN impersonator_id BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX audit_log_impersonator ON audit_log (impersonator_id, id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  token VARCHAR(64) NOT NULL,
+  type VARCHAR(45) NOT NULL,
+  threshold DOUBLE PRECISION NOT NULL,
+  window_minutes BIGINT NOT NULL DEFAULT 0,
+  cooldown_minutes BIGINT NOT NULL DEFAULT 0,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  expires_at TIMESTAMP(0) NULL,
+  date_created TIMESTAMP(0) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS alert_rules_user_id ON alert_rules (user_id);
+CREATE INDEX IF NOT EXISTS alert_rules_active ON alert_rules (enabled, expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS notification_channels (
+  user_id BIGINT NOT NULL,
+  type VARCHAR(45) NOT NULL,
+  target VARCHAR(512) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  verified BOOLEAN NOT NULL DEFAULT FALSE,
+  is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+  PRIMARY KEY (user_id, type)
+);
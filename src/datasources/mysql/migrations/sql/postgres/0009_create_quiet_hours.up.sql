@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS quiet_hours (
+  user_id BIGINT NOT NULL PRIMARY KEY,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  urgent_bypass BOOLEAN NOT NULL DEFAULT FALSE,
+  windows JSONB NOT NULL
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_plans (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  plan VARCHAR(45) NOT NULL,
+  effective_from TIMESTAMP(0) NOT NULL,
+  effective_until TIMESTAMP(0) NULL,
+  date_created TIMESTAMP(0) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS user_plans_user_id ON user_plans (user_id, effective_from);
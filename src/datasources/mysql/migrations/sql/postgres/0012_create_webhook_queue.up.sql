@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS webhook_queue (
+  id BIGSERIAL PRIMARY KEY,
+  webhook_id BIGINT NOT NULL,
+  event_id VARCHAR(64) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  payload JSONB NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP(0) NOT NULL,
+  date_created TIMESTAMP(0) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_queue_due ON webhook_queue (next_attempt_at, id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  email TEXT NOT NULL,
+  telegram_user TEXT NOT NULL DEFAULT '',
+  time_zone TEXT NOT NULL DEFAULT 'UTC',
+  status TEXT NOT NULL,
+  password TEXT NOT NULL,
+  date_created TEXT NOT NULL,
+  CONSTRAINT users_email_unique UNIQUE (email)
+);
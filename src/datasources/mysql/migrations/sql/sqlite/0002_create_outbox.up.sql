@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS outbox (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_id TEXT NOT NULL,
+  event_type TEXT NOT NULL,
+  user_id INTEGER NOT NULL,
+  payload TEXT NOT NULL,
+  date_created TEXT NOT NULL,
+  dispatched_at TEXT NULL,
+  CONSTRAINT outbox_event_id UNIQUE (event_id)
+);
+CREATE INDEX IF NOT EXISTS outbox_pending ON outbox (dispatched_at, id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  action TEXT NOT NULL,
+  outcome TEXT NOT NULL,
+  actor_id INTEGER NOT NULL,
+  target_user_id INTEGER NOT NULL,
+  request_id TEXT NOT NULL,
+  changes TEXT NULL,
+  details TEXT NULL,
+  date_created TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_action ON audit_log (action, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor ON audit_log (actor_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_target_user ON audit_log (target_user_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_date_created ON audit_log (date_created);
+-- Entries are append-only, even for whoever holds write access to the table.
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END;
@@ -0,0 +1,2 @@
+DROP INDEX audit_log_impersonator;
+ALTER TABLE audit_log DROP COLUMN impersonator_id;
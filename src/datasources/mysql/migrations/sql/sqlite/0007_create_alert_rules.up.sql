@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  token TEXT NOT NULL,
+  type TEXT NOT NULL,
+  threshold REAL NOT NULL,
+  window_minutes INTEGER NOT NULL DEFAULT 0,
+  cooldown_minutes INTEGER NOT NULL DEFAULT 0,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  expires_at TEXT NULL,
+  date_created TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS alert_rules_user_id ON alert_rules (user_id);
+CREATE INDEX IF NOT EXISTS alert_rules_active ON alert_rules (enabled, expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS notification_channels (
+  user_id INTEGER NOT NULL,
+  type TEXT NOT NULL,
+  target TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  verified INTEGER NOT NULL DEFAULT 0,
+  is_primary INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, type)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS quiet_hours (
+  user_id INTEGER NOT NULL PRIMARY KEY,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  urgent_bypass INTEGER NOT NULL DEFAULT 0,
+  windows TEXT NOT NULL
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_plans (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  plan TEXT NOT NULL,
+  effective_from TEXT NOT NULL,
+  effective_until TEXT NULL,
+  date_created TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS user_plans_user_id ON user_plans (user_id, effective_from);
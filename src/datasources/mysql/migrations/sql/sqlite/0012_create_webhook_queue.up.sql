@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS webhook_queue (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  webhook_id INTEGER NOT NULL,
+  event_id TEXT NOT NULL,
+  event_type TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TEXT NOT NULL,
+  date_created TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_queue_due ON webhook_queue (next_attempt_at, id);
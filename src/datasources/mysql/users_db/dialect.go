@@ -0,0 +1,90 @@
+package users_db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes what the repositories have to adapt to the database behind
+// Client. Queries are written for MySQL and rebound for the others.
+type Dialect struct {
+	Name string
+	// Driver is the database/sql driver the dialect is opened with.
+	Driver string
+	// NumberedPlaceholders is set for databases using $1, $2... instead of ?.
+	NumberedPlaceholders bool
+	// Returning is set for databases whose driver has no LastInsertId, where the id of
+	// an inserted row is read with RETURNING instead.
+	Returning bool
+	// RowLocks is set for databases supporting SELECT ... FOR UPDATE. SQLite has no
+	// row locks, a transaction takes the whole database once it writes.
+	RowLocks bool
+}
+
+var (
+	MySQL      = Dialect{Name: "mysql", Driver: "mysql", RowLocks: true}
+	PostgreSQL = Dialect{Name: "postgres", Driver: "postgres", NumberedPlaceholders: true, Returning: true, RowLocks: true}
+	SQLite     = Dialect{Name: "sqlite", Driver: "sqlite3"}
+
+	// Current is the dialect of Client, picked by Config.Driver.
+	Current = MySQL
+)
+
+// DialectNamed returns the dialect called name, MySQL for an empty one.
+func DialectNamed(name string) (Dialect, error) {
+	switch name {
+	case "", MySQL.Name:
+		return MySQL, nil
+	case PostgreSQL.Name:
+		return PostgreSQL, nil
+	case SQLite.Name:
+		return SQLite, nil
+	}
+	return Dialect{}, fmt.Errorf("invalid database.driver %q", name)
+}
+
+// Rebind rewrites the ? placeholders of query for the dialect. Question marks inside
+// quoted literals are left alone.
+func (d Dialect) Rebind(query string) string {
+	if !d.NumberedPlaceholders {
+		return query
+	}
+
+	var result strings.Builder
+	result.Grow(len(query) + 8)
+	position := 0
+	var quote rune
+	for _, char := range query {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '?':
+			position++
+			result.WriteString("$" + strconv.Itoa(position))
+			continue
+		}
+		result.WriteRune(char)
+	}
+	return result.String()
+}
+
+// ReturningId adds RETURNING id to an INSERT for the dialects that need it.
+func (d Dialect) ReturningId(query string) string {
+	if !d.Returning {
+		return query
+	}
+	return strings.TrimSuffix(strings.TrimSpace(query), ";") + " RETURNING id;"
+}
+
+// ForUpdate adds FOR UPDATE to a SELECT for the dialects with row locks.
+func (d Dialect) ForUpdate(query string) string {
+	if !d.RowLocks {
+		return query
+	}
+	return strings.TrimSuffix(strings.TrimSpace(query), ";") + " FOR UPDATE;"
+}
@@ -0,0 +1,44 @@
+package users_db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectNamed(t *testing.T) {
+	dialect, err := DialectNamed("")
+	assert.Nil(t, err)
+	assert.Equal(t, MySQL, dialect)
+
+	dialect, err = DialectNamed("postgres")
+	assert.Nil(t, err)
+	assert.Equal(t, PostgreSQL, dialect)
+
+	_, err = DialectNamed("oracle")
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid database.driver "oracle"`, err.Error())
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM users WHERE email=? AND name<>'who?' AND status=?;"
+
+	assert.Equal(t, query, MySQL.Rebind(query))
+	assert.Equal(t, query, SQLite.Rebind(query))
+	assert.Equal(t, "SELECT id FROM users WHERE email=$1 AND name<>'who?' AND status=$2;", PostgreSQL.Rebind(query))
+}
+
+func TestReturningId(t *testing.T) {
+	query := "INSERT INTO users(name) VALUES(?);"
+
+	assert.Equal(t, query, MySQL.ReturningId(query))
+	assert.Equal(t, "INSERT INTO users(name) VALUES(?) RETURNING id;", PostgreSQL.ReturningId(query))
+}
+
+func TestForUpdate(t *testing.T) {
+	query := "SELECT id FROM users WHERE id=?;"
+
+	assert.Equal(t, "SELECT id FROM users WHERE id=? FOR UPDATE;", MySQL.ForUpdate(query))
+	assert.Equal(t, "SELECT id FROM users WHERE id=? FOR UPDATE;", PostgreSQL.ForUpdate(query))
+	assert.Equal(t, query, SQLite.ForUpdate(query))
+}
@@ -0,0 +1,93 @@
+package users_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+)
+
+var (
+	// DefaultPool keeps the pool below the connection limit of a small MySQL instance
+	// when a few replicas of the API run, and recycles connections before the server
+	// or a proxy in between drops them.
+	DefaultPool = PoolConfig{MaxOpenConns: 20, MaxIdleConns: 10, ConnMaxLifetime: 5 * time.Minute, ConnMaxIdleTime: time.Minute}
+
+	// DefaultRetry waits about two minutes in total for MySQL to come up.
+	DefaultRetry = RetryConfig{Attempts: 10, Backoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second}
+
+	sleep = time.Sleep
+)
+
+// PoolConfig holds the connection pool settings. Zero means unlimited, as in
+// database/sql.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (p PoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// RetryConfig controls how long the API waits for MySQL on start. The wait after
+// each failed attempt doubles, starting at Backoff, up to MaxBackoff.
+type RetryConfig struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (r RetryConfig) delay(attempt int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempt && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return delay
+}
+
+// connect pings the database until it answers or the attempts run out, returning the
+// last error in that case.
+func connect(ctx context.Context, ping func(context.Context) error, retry RetryConfig) error {
+	var err error
+	for attempt := 1; attempt <= retry.Attempts; attempt++ {
+		if err = ping(ctx); err == nil {
+			return nil
+		}
+		if attempt == retry.Attempts {
+			break
+		}
+		delay := retry.delay(attempt)
+		logger.Error(fmt.Sprintf("database not ready, attempt %d of %d, retrying in %s", attempt, retry.Attempts, delay), err)
+		sleep(delay)
+	}
+	return fmt.Errorf("database not ready after %d attempts: %w", retry.Attempts, err)
+}
+
+func (p PoolConfig) validate() error {
+	if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		return errors.New("database.max_idle_conns can not be above database.max_open_conns")
+	}
+	return nil
+}
+
+func (r RetryConfig) validate() error {
+	if r.Attempts < 1 {
+		return errors.New("database.connect_attempts must be at least 1")
+	}
+	if r.MaxBackoff < r.Backoff {
+		return errors.New("database.connect_max_backoff can not be below database.connect_backoff")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package users_db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidateDefaults(t *testing.T) {
+	assert.Nil(t, DefaultConfig().Validate())
+}
+
+func TestConfigValidateInvalid(t *testing.T) {
+	for expected, change := range map[string]func(*Config){
+		`invalid database.driver "oracle"`:                                       func(c *Config) { c.Driver = "oracle" },
+		"database.dsn is required for postgres":                                  func(c *Config) { c.Driver = "postgres" },
+		"database.max_idle_conns can not be above database.max_open_conns":       func(c *Config) { c.Pool.MaxOpenConns = 5 },
+		"database.connect_attempts must be at least 1":                           func(c *Config) { c.Retry.Attempts = 0 },
+		"database.connect_max_backoff can not be below database.connect_backoff": func(c *Config) { c.Retry.Backoff = 10 * time.Minute },
+		"database.timeout must be above zero":                                    func(c *Config) { c.Timeouts.Default = 0 },
+		"timeout of users.get must be above zero":                                func(c *Config) { c.Timeouts.Operations["users.get"] = 0 },
+		"database.replica_check_interval must be above zero":                     func(c *Config) { c.ReplicaHosts, c.ReplicaCheckInterval = []string{"replica:3306"}, 0 },
+		"database.tls must be true when certificates or a server name are given": func(c *Config) { c.TLS = TLSConfig{Mode: "preferred", ServerName: "mysql"} },
+	} {
+		config := DefaultConfig()
+		change(&config)
+
+		err := config.Validate()
+
+		assert.NotNil(t, err)
+		assert.Equal(t, expected, err.Error())
+	}
+}
+
+func TestRetryDelayDoublesUpToMax(t *testing.T) {
+	retry := RetryConfig{Attempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, retry.delay(1))
+	assert.Equal(t, 2*time.Second, retry.delay(2))
+	assert.Equal(t, 4*time.Second, retry.delay(3))
+	assert.Equal(t, 5*time.Second, retry.delay(4))
+	assert.Equal(t, 5*time.Second, retry.delay(9))
+}
+
+func withSleepRecorder(t *testing.T) *[]time.Duration {
+	slept := make([]time.Duration, 0)
+	sleep = func(delay time.Duration) { slept = append(slept, delay) }
+	t.Cleanup(func() { sleep = time.Sleep })
+	return &slept
+}
+
+func TestConnectRetriesUntilDatabaseAnswers(t *testing.T) {
+	slept := withSleepRecorder(t)
+	calls := 0
+	ping := func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	err := connect(context.Background(), ping, RetryConfig{Attempts: 5, Backoff: time.Second, MaxBackoff: time.Minute})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *slept)
+}
+
+func TestConnectGivesUp(t *testing.T) {
+	slept := withSleepRecorder(t)
+	ping := func(context.Context) error { return errors.New("connection refused") }
+
+	err := connect(context.Background(), ping, RetryConfig{Attempts: 3, Backoff: time.Second, MaxBackoff: time.Minute})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "database not ready after 3 attempts: connection refused", err.Error())
+	assert.Equal(t, 2, len(*slept))
+}
+
+func TestStatsArePublished(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Client = db
+	defer func() {
+		Client.Close()
+		Client = nil
+	}()
+	PoolConfig{MaxOpenConns: 7}.apply(db)
+
+	var stats map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(expvar.Get("users_db").String()), &stats))
+	assert.EqualValues(t, 7, stats["MaxOpenConnections"])
+}
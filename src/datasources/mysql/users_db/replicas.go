@@ -0,0 +1,212 @@
+package users_db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+)
+
+const (
+	defaultReplicaCheckInterval = 5 * time.Second
+)
+
+var (
+	// Replicas holds the read replicas of the primary in Client. It is empty unless
+	// Config.ReplicaHosts lists some.
+	Replicas = &ReplicaSet{}
+)
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so the reads made with it go to the primary, for a request
+// that has to see its own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsFromPrimary reports whether ctx was marked by WithPrimary.
+func ReadsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// ReadClient returns the pool a read-only query made with ctx should run on: one of
+// the healthy replicas in turn, or the primary when ctx asks for it or none is
+// healthy.
+func ReadClient(ctx context.Context) *sql.DB {
+	if ReadsFromPrimary(ctx) {
+		return Client
+	}
+	if replica := Replicas.next(); replica != nil {
+		return replica
+	}
+	return Client
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// ReplicaSet balances reads over replicas, leaving out the ones failing their health
+// checks until they answer again.
+type ReplicaSet struct {
+	replicas []*replica
+	counter  atomic.Uint64
+
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func NewReplicaSet() *ReplicaSet {
+	return &ReplicaSet{}
+}
+
+// Add registers db under name, a host usually, healthy until a check says otherwise.
+func (s *ReplicaSet) Add(name string, db *sql.DB) {
+	added := &replica{name: name, db: db}
+	added.healthy.Store(true)
+	s.replicas = append(s.replicas, added)
+}
+
+func (s *ReplicaSet) next() *sql.DB {
+	count := uint64(len(s.replicas))
+	if count == 0 {
+		return nil
+	}
+	start := s.counter.Add(1)
+	for i := uint64(0); i < count; i++ {
+		candidate := s.replicas[(start+i)%count]
+		if candidate.healthy.Load() {
+			return candidate.db
+		}
+	}
+	return nil
+}
+
+// Failed takes db out of rotation when err shows its replica can not be reached, and
+// reports whether it did, in which case the caller should retry on the primary.
+func (s *ReplicaSet) Failed(db *sql.DB, err error) bool {
+	if !connectionError(err) {
+		return false
+	}
+	for _, candidate := range s.replicas {
+		if candidate.db == db {
+			if candidate.healthy.CompareAndSwap(true, false) {
+				logger.Error("replica "+candidate.name+" taken out of rotation", err)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Check pings every replica, taking the failing ones out of rotation and bringing
+// back those that recovered.
+func (s *ReplicaSet) Check(ctx context.Context) {
+	for _, candidate := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, Timeouts.Default)
+		err := candidate.db.PingContext(pingCtx)
+		cancel()
+
+		if err != nil {
+			if candidate.healthy.CompareAndSwap(true, false) {
+				logger.Error("replica "+candidate.name+" failed its health check", err)
+			}
+			continue
+		}
+		if candidate.healthy.CompareAndSwap(false, true) {
+			logger.Info("replica " + candidate.name + " back in rotation")
+		}
+	}
+}
+
+// Start checks the replicas every interval until Stop is called.
+func (s *ReplicaSet) Start(interval time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stop != nil || len(s.replicas) == 0 {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.Check(context.Background())
+			}
+		}
+	}(s.stop, s.done)
+}
+
+func (s *ReplicaSet) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+	s.done = nil
+}
+
+// Close stops the health checks and closes every replica pool.
+func (s *ReplicaSet) Close() error {
+	s.Stop()
+	var result error
+	for _, candidate := range s.replicas {
+		if err := candidate.db.Close(); err != nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// connectionError tells a replica that can not be reached from a failing query. The
+// context errors implement net.Error as well but say nothing about the replica.
+func connectionError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.As(err, &netErr)
+}
+
+// openReplicas opens a pool per host in hosts with the settings of the primary. A
+// replica that does not answer yet does not hold the start back; it joins the
+// rotation once a health check passes.
+func openReplicas(config *mysql.Config, hosts []string, checkInterval time.Duration, pool PoolConfig) {
+	replicas := NewReplicaSet()
+	for _, host := range hosts {
+		replicaConfig := config.Clone()
+		replicaConfig.Addr = host
+		db, err := sql.Open("mysql", replicaConfig.FormatDSN())
+		if err != nil {
+			panic(err)
+		}
+		pool.apply(db)
+		replicas.Add(host, db)
+	}
+	replicas.Check(context.Background())
+
+	Replicas = replicas
+	Replicas.Start(checkInterval)
+}
@@ -0,0 +1,110 @@
+package users_db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPingMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func withReplicas(t *testing.T, replicas *ReplicaSet) {
+	primary, _ := newPingMock(t)
+	previousClient, previousReplicas := Client, Replicas
+	Client, Replicas = primary, replicas
+	t.Cleanup(func() {
+		Client, Replicas = previousClient, previousReplicas
+	})
+}
+
+func TestReadClientBalancesHealthyReplicas(t *testing.T) {
+	first, _ := newPingMock(t)
+	second, _ := newPingMock(t)
+	replicas := NewReplicaSet()
+	replicas.Add("first", first)
+	replicas.Add("second", second)
+	withReplicas(t, replicas)
+
+	picked := map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		picked[ReadClient(context.Background())]++
+	}
+
+	assert.Equal(t, map[*sql.DB]int{first: 2, second: 2}, picked)
+}
+
+func TestReadClientWithPrimary(t *testing.T) {
+	replica, _ := newPingMock(t)
+	replicas := NewReplicaSet()
+	replicas.Add("replica", replica)
+	withReplicas(t, replicas)
+
+	ctx := WithPrimary(context.Background())
+
+	assert.True(t, ReadsFromPrimary(ctx))
+	assert.Same(t, Client, ReadClient(ctx))
+}
+
+func TestReadClientWithoutReplicas(t *testing.T) {
+	withReplicas(t, NewReplicaSet())
+
+	assert.Same(t, Client, ReadClient(context.Background()))
+}
+
+func TestCheckTakesFailingReplicaOutAndBack(t *testing.T) {
+	first, firstMock := newPingMock(t)
+	second, secondMock := newPingMock(t)
+	replicas := NewReplicaSet()
+	replicas.Add("first", first)
+	replicas.Add("second", second)
+	withReplicas(t, replicas)
+
+	firstMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	secondMock.ExpectPing()
+	replicas.Check(context.Background())
+
+	for i := 0; i < 3; i++ {
+		assert.Same(t, second, ReadClient(context.Background()))
+	}
+
+	firstMock.ExpectPing()
+	secondMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	replicas.Check(context.Background())
+
+	assert.Same(t, first, ReadClient(context.Background()))
+
+	firstMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	secondMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	replicas.Check(context.Background())
+
+	assert.Same(t, Client, ReadClient(context.Background()))
+	assert.Nil(t, firstMock.ExpectationsWereMet())
+	assert.Nil(t, secondMock.ExpectationsWereMet())
+}
+
+func TestFailedOnlyTakesUnreachableReplicasOut(t *testing.T) {
+	replica, _ := newPingMock(t)
+	replicas := NewReplicaSet()
+	replicas.Add("replica", replica)
+	withReplicas(t, replicas)
+
+	assert.False(t, replicas.Failed(replica, sql.ErrNoRows))
+	assert.False(t, replicas.Failed(replica, context.DeadlineExceeded))
+	assert.False(t, replicas.Failed(Client, mysql.ErrInvalidConn))
+	assert.Same(t, replica, ReadClient(context.Background()))
+
+	assert.True(t, replicas.Failed(replica, mysql.ErrInvalidConn))
+	assert.Same(t, Client, ReadClient(context.Background()))
+}
@@ -0,0 +1,82 @@
+package users_db
+
+import (
+	"database/sql"
+	"expvar"
+	"tokenalert_user-api/src/metrics"
+)
+
+const (
+	primaryPool = "primary"
+)
+
+// poolMetrics exposes every field of sql.DBStats, labelled with the pool it comes
+// from: primary or the name of a replica.
+var poolMetrics = []struct {
+	name    string
+	help    string
+	counter bool
+	value   func(sql.DBStats) float64
+}{
+	{"users_db_max_open_connections", "Maximum number of open connections to the database.", false,
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"users_db_open_connections", "Established connections, in use and idle.", false,
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"users_db_in_use_connections", "Connections currently in use.", false,
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"users_db_idle_connections", "Idle connections.", false,
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"users_db_wait_count_total", "Connections waited for.", true,
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"users_db_wait_duration_seconds_total", "Time blocked waiting for a new connection.", true,
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"users_db_max_idle_closed_total", "Connections closed due to database.max_idle_conns.", true,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"users_db_max_idle_time_closed_total", "Connections closed due to database.conn_max_idle_time.", true,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{"users_db_max_lifetime_closed_total", "Connections closed due to database.conn_max_lifetime.", true,
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+func init() {
+	expvar.Publish("users_db", expvar.Func(func() interface{} {
+		return Stats()
+	}))
+
+	for _, metric := range poolMetrics {
+		value := metric.value
+		collect := func(emit metrics.Emit) {
+			for pool, stats := range PoolStats() {
+				emit(value(stats), pool)
+			}
+		}
+		if metric.counter {
+			metrics.NewCounterFunc(metric.name, metric.help, []string{"pool"}, collect)
+		} else {
+			metrics.NewGaugeFunc(metric.name, metric.help, []string{"pool"}, collect)
+		}
+	}
+}
+
+// Stats reports the state of the connection pool, published under users_db among
+// the expvar metrics. It is empty until the database is initialized.
+func Stats() sql.DBStats {
+	if Client == nil {
+		return sql.DBStats{}
+	}
+	return Client.Stats()
+}
+
+// PoolStats reports the state of the primary pool and of every replica pool, keyed by
+// replica name. It is empty until the database is initialized.
+func PoolStats() map[string]sql.DBStats {
+	result := map[string]sql.DBStats{}
+	if Client == nil {
+		return result
+	}
+	result[primaryPool] = Client.Stats()
+	for _, replica := range Replicas.replicas {
+		result[replica.name] = replica.db.Stats()
+	}
+	return result
+}
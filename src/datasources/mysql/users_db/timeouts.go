@@ -0,0 +1,48 @@
+package users_db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultTimeout = 3 * time.Second
+)
+
+var (
+	// Timeouts bounds every database operation. Operations missing from Operations
+	// get Default.
+	Timeouts = OperationTimeouts{Default: DefaultTimeout, Operations: map[string]time.Duration{}}
+)
+
+type OperationTimeouts struct {
+	Default    time.Duration
+	Operations map[string]time.Duration
+}
+
+func (t OperationTimeouts) For(operation string) time.Duration {
+	if timeout, ok := t.Operations[operation]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+func (t OperationTimeouts) validate() error {
+	if t.Default <= 0 {
+		return errors.New("database.timeout must be above zero")
+	}
+	for operation, timeout := range t.Operations {
+		if timeout <= 0 {
+			return fmt.Errorf("timeout of %s must be above zero", operation)
+		}
+	}
+	return nil
+}
+
+// WithTimeout derives the context a database operation runs with. A deadline already
+// set on ctx wins when it is sooner.
+func WithTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, Timeouts.For(operation))
+}
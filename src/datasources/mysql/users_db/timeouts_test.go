@@ -0,0 +1,15 @@
+package users_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationTimeoutsFor(t *testing.T) {
+	timeouts := OperationTimeouts{Default: 2 * time.Second, Operations: map[string]time.Duration{"users.get": 500 * time.Millisecond}}
+
+	assert.Equal(t, 500*time.Millisecond, timeouts.For("users.get"))
+	assert.Equal(t, 2*time.Second, timeouts.For("users.delete"))
+}
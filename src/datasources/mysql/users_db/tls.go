@@ -0,0 +1,85 @@
+package users_db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	tlsConfigName = "users_db"
+)
+
+// TLSConfig describes how to secure the connection to MySQL. Mode takes the values
+// the driver understands: false, true, skip-verify or preferred. CA, Cert and Key are
+// paths to PEM files and, like ServerName, need Mode true or empty.
+type TLSConfig struct {
+	Mode       string
+	CA         string
+	Cert       string
+	Key        string
+	ServerName string
+}
+
+func (c TLSConfig) custom() bool {
+	return c.CA != "" || c.Cert != "" || c.Key != "" || c.ServerName != ""
+}
+
+func (c TLSConfig) validate() error {
+	if !c.custom() {
+		switch c.Mode {
+		case "", "false", "true", "skip-verify", "preferred":
+			return nil
+		}
+		return fmt.Errorf("invalid database.tls %q", c.Mode)
+	}
+
+	if c.Mode != "" && c.Mode != "true" {
+		return errors.New("database.tls must be true when certificates or a server name are given")
+	}
+	if (c.Cert == "") != (c.Key == "") {
+		return errors.New("database.tls_cert and database.tls_key go together")
+	}
+	return nil
+}
+
+// Register returns the value of the tls parameter of the DSN, registering a config
+// with the driver first when c needs one of its own.
+func (c TLSConfig) Register() (string, error) {
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+	if !c.custom() {
+		if c.Mode == "" {
+			return "false", nil
+		}
+		return c.Mode, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.ServerName}
+	if c.CA != "" {
+		pem, err := os.ReadFile(c.CA)
+		if err != nil {
+			return "", err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("no certificate found in %s", c.CA)
+		}
+	}
+	if c.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return "", err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if err := mysql.RegisterTLSConfig(tlsConfigName, config); err != nil {
+		return "", err
+	}
+	return tlsConfigName, nil
+}
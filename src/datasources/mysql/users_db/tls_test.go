@@ -0,0 +1,80 @@
+package users_db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCA(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mysql test ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSRegisterDriverModes(t *testing.T) {
+	for mode, expected := range map[string]string{"": "false", "true": "true", "skip-verify": "skip-verify", "preferred": "preferred"} {
+		param, err := TLSConfig{Mode: mode}.Register()
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, param)
+	}
+
+	_, err := TLSConfig{Mode: "always"}.Register()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `invalid database.tls "always"`, err.Error())
+}
+
+func TestTLSRegisterCustomConfig(t *testing.T) {
+	param, err := TLSConfig{CA: writeCA(t), ServerName: "mysql.internal"}.Register()
+
+	assert.Nil(t, err)
+	assert.Equal(t, tlsConfigName, param)
+}
+
+func TestTLSRegisterCustomConfigInvalid(t *testing.T) {
+	_, err := TLSConfig{Mode: "skip-verify", CA: "ca.pem"}.Register()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "database.tls must be true when certificates or a server name are given", err.Error())
+
+	_, err = TLSConfig{Cert: "client.pem"}.Register()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "database.tls_cert and database.tls_key go together", err.Error())
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte{}, 0600)
+	_, err = TLSConfig{CA: empty}.Register()
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,87 @@
+// Package access defines the roles a user can have and the permissions each grants.
+package access
+
+import (
+	"strings"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	// RoleUser is every user signing up: it acts on its own account only.
+	RoleUser = "user"
+	// RoleSupport looks users up and suspends them, but does not change roles.
+	RoleSupport = "support"
+	// RoleAdmin can do anything.
+	RoleAdmin = "admin"
+	// RoleService is for the accounts of other services, which read users and call
+	// the /internal routes.
+	RoleService = "service"
+
+	PermissionUsersRead      = "users.read"
+	PermissionUsersEdit      = "users.edit"
+	PermissionUsersSuspend   = "users.suspend"
+	PermissionRolesChange    = "roles.change"
+	PermissionAuditRead      = "audit.read"
+	PermissionPlansManage    = "plans.manage"
+	PermissionWebhooksManage = "webhooks.manage"
+	// PermissionUsersImpersonate lets staff act as a regular user for a while.
+	PermissionUsersImpersonate = "users.impersonate"
+	// PermissionInternalCall opens the /internal routes other services rely on.
+	PermissionInternalCall = "internal.call"
+	// PermissionDebugRead shows the configuration and statistics under /debug.
+	PermissionDebugRead = "debug.read"
+)
+
+var permissions = map[string]map[string]bool{
+	RoleUser: {},
+	RoleSupport: {
+		PermissionUsersRead:    true,
+		PermissionUsersSuspend: true,
+		PermissionAuditRead:    true,
+	},
+	RoleAdmin: {
+		PermissionUsersRead:        true,
+		PermissionUsersEdit:        true,
+		PermissionUsersSuspend:     true,
+		PermissionRolesChange:      true,
+		PermissionAuditRead:        true,
+		PermissionPlansManage:      true,
+		PermissionWebhooksManage:   true,
+		PermissionUsersImpersonate: true,
+		PermissionInternalCall:     true,
+		PermissionDebugRead:        true,
+	},
+	RoleService: {
+		PermissionUsersRead:    true,
+		PermissionInternalCall: true,
+	},
+}
+
+// ValidRole tells whether role is one of the roles above.
+func ValidRole(role string) bool {
+	_, ok := permissions[role]
+	return ok
+}
+
+// Can tells whether the role grants permission. Unknown roles grant nothing.
+func Can(role string, permission string) bool {
+	return permissions[role][permission]
+}
+
+// IsStaff tells whether role grants more than a regular user has.
+func IsStaff(role string) bool {
+	return ValidRole(role) && role != RoleUser
+}
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (request *RoleRequest) Validate() rest_errors.RestErr {
+	request.Role = strings.TrimSpace(strings.ToLower(request.Role))
+	if !ValidRole(request.Role) {
+		return rest_errors.NewBadRequestError("invalid role " + request.Role)
+	}
+	return nil
+}
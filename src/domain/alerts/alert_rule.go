@@ -0,0 +1,78 @@
+package alerts
+
+import (
+	"strings"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	TypePriceAbove    = "price_above"
+	TypePriceBelow    = "price_below"
+	TypePercentChange = "percent_change"
+	TypeVolumeSpike   = "volume_spike"
+)
+
+type AlertRule struct {
+	Id              int64   `json:"id"`
+	UserId          int64   `json:"user_id"`
+	Token           string  `json:"token"`
+	Type            string  `json:"type"`
+	Threshold       float64 `json:"threshold"`
+	WindowMinutes   int64   `json:"window_minutes"`
+	CooldownMinutes int64   `json:"cooldown_minutes"`
+	// Enabled is nil when an update leaves it out, keeping the stored value.
+	Enabled     *bool  `json:"enabled"`
+	ExpiresAt   string `json:"expires_at"`
+	DateCreated string `json:"date_created"`
+}
+
+type AlertRules []AlertRule
+
+func (rule *AlertRule) Validate() rest_errors.RestErr {
+	rule.Token = strings.TrimSpace(strings.ToLower(rule.Token))
+	rule.Type = strings.TrimSpace(strings.ToLower(rule.Type))
+	rule.ExpiresAt = strings.TrimSpace(rule.ExpiresAt)
+	if rule.Token == "" {
+		return rest_errors.NewBadRequestError("invalid token")
+	}
+
+	switch rule.Type {
+	case TypePriceAbove, TypePriceBelow:
+		if rule.Threshold <= 0 {
+			return rest_errors.NewBadRequestError("price threshold must be greater than zero")
+		}
+	case TypePercentChange:
+		if rule.Threshold == 0 {
+			return rest_errors.NewBadRequestError("percent change threshold must not be zero")
+		}
+		if rule.WindowMinutes <= 0 {
+			return rest_errors.NewBadRequestError("percent change rules require a window")
+		}
+	case TypeVolumeSpike:
+		if rule.Threshold <= 1 {
+			return rest_errors.NewBadRequestError("volume spike threshold must be greater than one")
+		}
+		if rule.WindowMinutes <= 0 {
+			return rest_errors.NewBadRequestError("volume spike rules require a window")
+		}
+	default:
+		return rest_errors.NewBadRequestError("invalid alert rule type")
+	}
+
+	if rule.CooldownMinutes < 0 {
+		return rest_errors.NewBadRequestError("invalid cooldown")
+	}
+
+	if rule.ExpiresAt != "" {
+		expiresAt, err := date_utils.ParseDBFormat(rule.ExpiresAt)
+		if err != nil {
+			return rest_errors.NewBadRequestError("invalid expiration date")
+		}
+		if !expiresAt.After(date_utils.GetNow()) {
+			return rest_errors.NewBadRequestError("expiration date must be in the future")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	ActionLoginSucceeded  = "login.succeeded"
+	ActionLoginFailed     = "login.failed"
+	ActionUserCreated     = "user.created"
+	ActionProfileUpdated  = "user.profile_updated"
+	ActionPasswordChanged = "user.password_changed"
+	ActionStatusChanged   = "user.status_changed"
+	ActionUserDeleted     = "user.deleted"
+	ActionPlanAssigned    = "admin.plan_assigned"
+	ActionWebhookCreated  = "admin.webhook_created"
+	ActionWebhookDeleted  = "admin.webhook_deleted"
+	ActionRoleChanged     = "admin.role_changed"
+	ActionAccessDenied    = "admin.access_denied"
+
+	ActionImpersonationStarted = "admin.impersonation_started"
+	ActionImpersonationDenied  = "admin.impersonation_denied"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var actions = map[string]bool{
+	ActionLoginSucceeded:  true,
+	ActionLoginFailed:     true,
+	ActionUserCreated:     true,
+	ActionProfileUpdated:  true,
+	ActionPasswordChanged: true,
+	ActionStatusChanged:   true,
+	ActionUserDeleted:     true,
+	ActionPlanAssigned:    true,
+	ActionWebhookCreated:  true,
+	ActionWebhookDeleted:  true,
+	ActionRoleChanged:     true,
+	ActionAccessDenied:    true,
+
+	ActionImpersonationStarted: true,
+	ActionImpersonationDenied:  true,
+}
+
+// Change is the value of a field before and after an action.
+type Change struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Entry records one security relevant action. Entries are only ever appended. The
+// actor is the authenticated caller, zero when unknown, such as for a failed login;
+// the target is the user acted upon, zero when the action is not about a user. The
+// impersonator is set when the actor was impersonated by a member of staff.
+type Entry struct {
+	Id             int64             `json:"id"`
+	Action         string            `json:"action"`
+	Outcome        string            `json:"outcome"`
+	ActorId        int64             `json:"actor_id"`
+	TargetUserId   int64             `json:"target_user_id"`
+	ImpersonatorId int64             `json:"impersonator_id,omitempty"`
+	RequestId      string            `json:"request_id,omitempty"`
+	Changes        map[string]Change `json:"changes,omitempty"`
+	Details        map[string]string `json:"details,omitempty"`
+	DateCreated    string            `json:"date_created"`
+}
+
+type Entries []Entry
+
+// Filter selects entries, newest first. Zero values match everything. BeforeId
+// pages through the results: pass the id of the last entry of the previous page.
+type Filter struct {
+	Action         string
+	Outcome        string
+	ActorId        int64
+	TargetUserId   int64
+	ImpersonatorId int64
+	From           string
+	Until          string
+	BeforeId       int64
+	Limit          int
+}
+
+func (filter *Filter) Validate() rest_errors.RestErr {
+	filter.Action = strings.TrimSpace(strings.ToLower(filter.Action))
+	filter.Outcome = strings.TrimSpace(strings.ToLower(filter.Outcome))
+	if filter.Action != "" && !actions[filter.Action] {
+		return rest_errors.NewBadRequestError("invalid action " + filter.Action)
+	}
+	if filter.Outcome != "" && filter.Outcome != OutcomeSuccess && filter.Outcome != OutcomeFailure {
+		return rest_errors.NewBadRequestError("invalid outcome " + filter.Outcome)
+	}
+	if filter.ActorId < 0 || filter.TargetUserId < 0 || filter.ImpersonatorId < 0 || filter.BeforeId < 0 {
+		return rest_errors.NewBadRequestError("ids can not be negative")
+	}
+	if filter.From != "" {
+		if _, err := date_utils.ParseDBFormat(filter.From); err != nil {
+			return rest_errors.NewBadRequestError("invalid from date")
+		}
+	}
+	if filter.Until != "" {
+		if _, err := date_utils.ParseDBFormat(filter.Until); err != nil {
+			return rest_errors.NewBadRequestError("invalid until date")
+		}
+	}
+	if filter.Limit < 0 || filter.Limit > MaxLimit {
+		return rest_errors.NewBadRequestError("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLimit
+	}
+	return nil
+}
+
+// ProfileChanges returns the fields that differ between two versions of a user.
+// Only fields safe to keep forever are compared; the password never is.
+func ProfileChanges(before, after users.User) map[string]Change {
+	changes := map[string]Change{}
+	compare := func(field, before, after string) {
+		if before != after {
+			changes[field] = Change{Before: before, After: after}
+		}
+	}
+	compare("name", before.Name, after.Name)
+	compare("email", before.Email, after.Email)
+	compare("telegram_user", before.TelegramUser, after.TelegramUser)
+	compare("time_zone", before.TimeZone, after.TimeZone)
+	compare("status", before.Status, after.Status)
+	compare("role", before.Role, after.Role)
+	return changes
+}
+
+// Actor is the caller a request acts for, as far as the audit log is concerned. Role
+// is only known once a permission check has loaded the caller. ImpersonatorId is set
+// when a member of staff acts as the user.
+type Actor struct {
+	UserId         int64
+	Role           string
+	ImpersonatorId int64
+}
+
+// Impersonated tells whether a member of staff is acting as the user.
+func (actor Actor) Impersonated() bool {
+	return actor.ImpersonatorId != 0
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx acting for actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of ctx, the zero Actor when the caller is unknown.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	CloudEventsSource      = "/tokenalert/user-api"
+	cloudEventsTypePrefix  = "com.tokenalert."
+	cloudEventsContentType = "application/json"
+)
+
+// CloudEvent is the structured mode JSON envelope of the CloudEvents 1.0 spec, used
+// for everything published on the message bus.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// CloudEvent wraps the event keeping its id, so consumers can drop the duplicates
+// that at-least-once delivery produces. The subject is the id of the user.
+func (e Event) CloudEvent() CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Id:              e.Id,
+		Source:          CloudEventsSource,
+		Type:            cloudEventsTypePrefix + e.Type,
+		Subject:         strconv.FormatInt(e.UserId, 10),
+		Time:            e.OccurredAt,
+		DataContentType: cloudEventsContentType,
+		Data:            e.Data,
+	}
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+	"tokenalert_user-api/src/utils/crypto_utils"
+	"tokenalert_user-api/src/utils/date_utils"
+)
+
+const (
+	TypeUserCreated     = "user.created"
+	TypeUserUpdated     = "user.updated"
+	TypeUserDeleted     = "user.deleted"
+	TypeUserLoggedIn    = "user.logged_in"
+	TypePasswordChanged = "user.password_changed"
+	TypeTelegramLinked  = "user.telegram_linked"
+	TypeStatusChanged   = "user.status_changed"
+	TypeRoleChanged     = "user.role_changed"
+)
+
+type Event struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	UserId     int64           `json:"user_id"`
+	OccurredAt string          `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func New(eventType string, userId int64, data interface{}) (*Event, error) {
+	id, err := crypto_utils.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		Id:         id,
+		Type:       eventType,
+		UserId:     userId,
+		OccurredAt: date_utils.GetNowString(),
+		Data:       payload,
+	}, nil
+}
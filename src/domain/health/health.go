@@ -0,0 +1,26 @@
+package health
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check reports one dependency. Duration is in milliseconds; Error is empty when the
+// dependency is up.
+type Check struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Duration int64  `json:"duration_ms"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is up only when every check is up and the API is not shutting down.
+type Report struct {
+	Status       string  `json:"status"`
+	ShuttingDown bool    `json:"shutting_down,omitempty"`
+	Checks       []Check `json:"checks"`
+}
+
+func (report Report) Up() bool {
+	return report.Status == StatusUp
+}
@@ -0,0 +1,113 @@
+package notifications
+
+import (
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+)
+
+type Channel struct {
+	Type     string `json:"type"`
+	Target   string `json:"target"`
+	Enabled  bool   `json:"enabled"`
+	Verified bool   `json:"verified"`
+}
+
+type Channels []Channel
+
+type Settings struct {
+	UserId         int64    `json:"user_id"`
+	PrimaryChannel string   `json:"primary_channel"`
+	Channels       Channels `json:"channels"`
+}
+
+func (channels Channels) Find(channelType string) *Channel {
+	for index := range channels {
+		if channels[index].Type == channelType {
+			return &channels[index]
+		}
+	}
+	return nil
+}
+
+// TelegramTarget returns the telegram channel target of the telegram user a user
+// signed up with, which may come with or without its leading @.
+func TelegramTarget(telegramUser string) string {
+	target := strings.TrimSpace(strings.ToLower(telegramUser))
+	if target == "" || strings.HasPrefix(target, "@") {
+		return target
+	}
+	if _, err := strconv.ParseInt(target, 10, 64); err == nil {
+		return target
+	}
+	return "@" + target
+}
+
+func (channel *Channel) Validate() rest_errors.RestErr {
+	channel.Type = strings.TrimSpace(strings.ToLower(channel.Type))
+	channel.Target = strings.TrimSpace(channel.Target)
+	if channel.Target == "" {
+		return rest_errors.NewBadRequestError("invalid " + channel.Type + " channel target")
+	}
+
+	switch channel.Type {
+	case ChannelTelegram:
+		channel.Target = strings.ToLower(channel.Target)
+		if _, err := strconv.ParseInt(channel.Target, 10, 64); err != nil && !strings.HasPrefix(channel.Target, "@") {
+			return rest_errors.NewBadRequestError("telegram target must be a chat id or an @username")
+		}
+	case ChannelEmail:
+		channel.Target = strings.ToLower(channel.Target)
+		address, err := mail.ParseAddress(channel.Target)
+		if err != nil || address.Address != channel.Target {
+			return rest_errors.NewBadRequestError("invalid email channel target")
+		}
+	case ChannelWebhook:
+		target, err := url.Parse(channel.Target)
+		if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+			return rest_errors.NewBadRequestError("webhook target must be an absolute http(s) url")
+		}
+	default:
+		return rest_errors.NewBadRequestError("invalid notification channel type")
+	}
+	return nil
+}
+
+func (settings *Settings) Validate() rest_errors.RestErr {
+	settings.PrimaryChannel = strings.TrimSpace(strings.ToLower(settings.PrimaryChannel))
+	seen := make(map[string]bool)
+	for index := range settings.Channels {
+		channel := &settings.Channels[index]
+		if err := channel.Validate(); err != nil {
+			return err
+		}
+		if seen[channel.Type] {
+			return rest_errors.NewBadRequestError("duplicated " + channel.Type + " channel")
+		}
+		seen[channel.Type] = true
+	}
+
+	if settings.PrimaryChannel == "" {
+		if len(settings.Channels) > 0 {
+			return rest_errors.NewBadRequestError("a primary channel is required")
+		}
+		return nil
+	}
+	primary := settings.Channels.Find(settings.PrimaryChannel)
+	if primary == nil {
+		return rest_errors.NewBadRequestError("primary channel is not configured")
+	}
+	if !primary.Enabled {
+		return rest_errors.NewBadRequestError("primary channel must be enabled")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	ReasonAvailable  = "available"
+	ReasonQuietHours = "quiet_hours"
+	ReasonUrgent     = "urgent_bypass"
+
+	minutesPerDay = 24 * 60
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// QuietWindow silences notifications on Weekday from Start to End, both in "HH:MM"
+// local time. A window whose End is before its Start runs past midnight into the
+// following day, and "24:00" can be used as End to cover the rest of the day.
+type QuietWindow struct {
+	Weekday string `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+type QuietHours struct {
+	UserId       int64         `json:"user_id"`
+	Enabled      bool          `json:"enabled"`
+	UrgentBypass bool          `json:"urgent_bypass"`
+	Windows      []QuietWindow `json:"windows"`
+}
+
+type Availability struct {
+	UserId    int64  `json:"user_id"`
+	CanNotify bool   `json:"can_notify"`
+	Reason    string `json:"reason"`
+	TimeZone  string `json:"time_zone"`
+	LocalTime string `json:"local_time"`
+}
+
+func parseClock(value string, allowEndOfDay bool) (int, error) {
+	var hours, minutes int
+	if len(value) != 5 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	if _, err := fmt.Sscanf(value, "%02d:%02d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	if hours == 24 && minutes == 0 && allowEndOfDay {
+		return minutesPerDay, nil
+	}
+	if hours < 0 || hours > 23 || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+func (window *QuietWindow) Validate() rest_errors.RestErr {
+	window.Weekday = strings.TrimSpace(strings.ToLower(window.Weekday))
+	window.Start = strings.TrimSpace(window.Start)
+	window.End = strings.TrimSpace(window.End)
+	if _, ok := weekdays[window.Weekday]; !ok {
+		return rest_errors.NewBadRequestError("invalid quiet hours weekday")
+	}
+
+	start, err := parseClock(window.Start, false)
+	if err != nil {
+		return rest_errors.NewBadRequestError("quiet hours start must be formatted as HH:MM")
+	}
+	end, err := parseClock(window.End, true)
+	if err != nil {
+		return rest_errors.NewBadRequestError("quiet hours end must be formatted as HH:MM")
+	}
+	if start == end {
+		return rest_errors.NewBadRequestError("quiet hours start and end must differ")
+	}
+	return nil
+}
+
+// Contains reports whether the local time falls inside the window, including the part
+// of an overnight window that spills into the next day.
+func (window QuietWindow) Contains(local time.Time) bool {
+	weekday := weekdays[window.Weekday]
+	start, _ := parseClock(window.Start, false)
+	end, _ := parseClock(window.End, true)
+	minute := local.Hour()*60 + local.Minute()
+
+	if end > start {
+		return local.Weekday() == weekday && minute >= start && minute < end
+	}
+	if local.Weekday() == weekday && minute >= start {
+		return true
+	}
+	return local.Weekday() == (weekday+1)%7 && minute < end
+}
+
+func (quietHours *QuietHours) Validate() rest_errors.RestErr {
+	for index := range quietHours.Windows {
+		if err := quietHours.Windows[index].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (quietHours *QuietHours) IsQuiet(local time.Time) bool {
+	if !quietHours.Enabled {
+		return false
+	}
+	for _, window := range quietHours.Windows {
+		if window.Contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check decides whether a notification can be delivered at the given local time.
+func (quietHours *QuietHours) Check(local time.Time, urgent bool) (bool, string) {
+	if !quietHours.IsQuiet(local) {
+		return true, ReasonAvailable
+	}
+	if urgent && quietHours.UrgentBypass {
+		return true, ReasonUrgent
+	}
+	return false, ReasonQuietHours
+}
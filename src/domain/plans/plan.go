@@ -0,0 +1,93 @@
+package plans
+
+import (
+	"strings"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+)
+
+type Plan struct {
+	Code                    string `json:"code"`
+	Name                    string `json:"name"`
+	MaxWatchlistTokens      int    `json:"max_watchlist_tokens"`
+	MaxAlertRules           int    `json:"max_alert_rules"`
+	MaxNotificationChannels int    `json:"max_notification_channels"`
+}
+
+var catalog = map[string]Plan{
+	PlanFree: {Code: PlanFree, Name: "Free", MaxWatchlistTokens: 5, MaxAlertRules: 10, MaxNotificationChannels: 1},
+	PlanPro:  {Code: PlanPro, Name: "Pro", MaxWatchlistTokens: 50, MaxAlertRules: 200, MaxNotificationChannels: 3},
+}
+
+func Get(code string) (*Plan, bool) {
+	plan, ok := catalog[code]
+	if !ok {
+		return nil, false
+	}
+	return &plan, true
+}
+
+func Default() *Plan {
+	plan, _ := Get(PlanFree)
+	return plan
+}
+
+// Assignment puts a user on a plan from EffectiveFrom until EffectiveUntil, or with
+// no end when EffectiveUntil is empty.
+type Assignment struct {
+	Id             int64  `json:"id"`
+	UserId         int64  `json:"user_id"`
+	Plan           string `json:"plan"`
+	EffectiveFrom  string `json:"effective_from"`
+	EffectiveUntil string `json:"effective_until"`
+	DateCreated    string `json:"date_created"`
+}
+
+type Assignments []Assignment
+
+func (assignment *Assignment) Validate() rest_errors.RestErr {
+	assignment.Plan = strings.TrimSpace(strings.ToLower(assignment.Plan))
+	assignment.EffectiveFrom = strings.TrimSpace(assignment.EffectiveFrom)
+	assignment.EffectiveUntil = strings.TrimSpace(assignment.EffectiveUntil)
+	if _, ok := Get(assignment.Plan); !ok {
+		return rest_errors.NewBadRequestError("invalid plan")
+	}
+
+	if assignment.EffectiveFrom == "" {
+		assignment.EffectiveFrom = date_utils.GetNowDBFormat()
+	}
+	from, err := date_utils.ParseDBFormat(assignment.EffectiveFrom)
+	if err != nil {
+		return rest_errors.NewBadRequestError("invalid effective from date")
+	}
+
+	if assignment.EffectiveUntil != "" {
+		until, err := date_utils.ParseDBFormat(assignment.EffectiveUntil)
+		if err != nil {
+			return rest_errors.NewBadRequestError("invalid effective until date")
+		}
+		if !until.After(from) {
+			return rest_errors.NewBadRequestError("effective until must be after effective from")
+		}
+	}
+	return nil
+}
+
+type Quota struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+type Usage struct {
+	UserId               int64 `json:"user_id"`
+	Plan                 *Plan `json:"plan"`
+	WatchlistTokens      Quota `json:"watchlist_tokens"`
+	AlertRules           Quota `json:"alert_rules"`
+	NotificationChannels Quota `json:"notification_channels"`
+}
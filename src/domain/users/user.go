@@ -1,13 +1,18 @@
 package users
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
 )
 
 const (
-	StatusActive = "active"
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+
+	DefaultTimeZone = "UTC"
 )
 
 type User struct {
@@ -15,7 +20,9 @@ type User struct {
 	Name         string `json:"name"`
 	Email        string `json:"email"`
 	TelegramUser string `json:"telegram_user"`
+	TimeZone     string `json:"time_zone"`
 	Status       string `json:"status"`
+	Role         string `json:"role"`
 	DateCreated  string `json:"date_created"`
 	Password     string `json:"password"`
 }
@@ -23,6 +30,19 @@ type User struct {
 type Users []User
 
 func (user *User) Validate() rest_errors.RestErr {
+	if err := user.ValidateProfile(); err != nil {
+		return err
+	}
+
+	user.Password = strings.TrimSpace(user.Password)
+	if user.Password == "" {
+		return rest_errors.NewBadRequestError("invalid password")
+	}
+	return nil
+}
+
+// ValidateProfile checks the fields a user can edit after signing up.
+func (user *User) ValidateProfile() rest_errors.RestErr {
 	user.Name = strings.TrimSpace(user.Name)
 	user.Email = strings.TrimSpace(strings.ToLower(user.Email))
 	user.TelegramUser = strings.TrimSpace(strings.ToLower(user.TelegramUser))
@@ -30,9 +50,25 @@ func (user *User) Validate() rest_errors.RestErr {
 		return rest_errors.NewBadRequestError("invalid email address")
 	}
 
-	user.Password = strings.TrimSpace(user.Password)
-	if user.Password == "" {
-		return rest_errors.NewBadRequestError("invalid password")
+	user.TimeZone = strings.TrimSpace(user.TimeZone)
+	if user.TimeZone == "" {
+		user.TimeZone = DefaultTimeZone
+	}
+	if _, err := user.Location(); err != nil {
+		return rest_errors.NewBadRequestError("invalid time zone")
 	}
 	return nil
 }
+
+// Location resolves the IANA time zone of the user, falling back to UTC for users
+// created before time zones were stored.
+func (user *User) Location() (*time.Location, error) {
+	if user.TimeZone == "" {
+		return time.UTC, nil
+	}
+	// time.LoadLocation also accepts "Local", which is not an IANA zone.
+	if user.TimeZone == "Local" {
+		return nil, errors.New("unknown time zone Local")
+	}
+	return time.LoadLocation(user.TimeZone)
+}
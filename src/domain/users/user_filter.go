@@ -0,0 +1,50 @@
+package users
+
+import (
+	"strings"
+	"tokenalert_user-api/src/domain/access"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	DefaultFilterLimit = 100
+	MaxFilterLimit     = 1000
+)
+
+// Filter selects users, newest first. Zero values match everything. BeforeId pages
+// through the results: pass the id of the last user of the previous page.
+type Filter struct {
+	Status   string
+	Role     string
+	Email    string
+	BeforeId int64
+	Limit    int
+}
+
+func (filter *Filter) Validate() rest_errors.RestErr {
+	filter.Status = strings.TrimSpace(strings.ToLower(filter.Status))
+	filter.Role = strings.TrimSpace(strings.ToLower(filter.Role))
+	filter.Email = strings.TrimSpace(strings.ToLower(filter.Email))
+	if filter.Status != "" && filter.Status != StatusActive && filter.Status != StatusSuspended {
+		return rest_errors.NewBadRequestError("invalid status " + filter.Status)
+	}
+	if filter.Role != "" && !access.ValidRole(filter.Role) {
+		return rest_errors.NewBadRequestError("invalid role " + filter.Role)
+	}
+	if filter.BeforeId < 0 {
+		return rest_errors.NewBadRequestError("before_id can not be negative")
+	}
+	if filter.Limit < 0 || filter.Limit > MaxFilterLimit {
+		return rest_errors.NewBadRequestError("limit must be between 1 and 1000")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultFilterLimit
+	}
+	return nil
+}
+
+// Can tells whether the user may act with permission. Only active users can.
+func (user *User) Can(permission string) bool {
+	return user.Status == StatusActive && access.Can(user.Role, permission)
+}
@@ -3,4 +3,4 @@ package users
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
-}
\ No newline at end of file
+}
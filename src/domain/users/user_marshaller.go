@@ -2,6 +2,7 @@ package users
 
 import (
 	"encoding/json"
+	"tokenalert_user-api/src/domain/notifications"
 )
 
 type PublicUser struct {
@@ -9,10 +10,17 @@ type PublicUser struct {
 	Name         string `json:"name"`
 	Email        string `json:"email"`
 	TelegramUser string `json:"telegram_user"`
+	TimeZone     string `json:"time_zone"`
 	Status       string `json:"status"`
+	Role         string `json:"role"`
 	DateCreated  string `json:"date_created"`
 }
 
+type InternalUser struct {
+	PublicUser
+	NotificationSettings *notifications.Settings `json:"notification_settings"`
+}
+
 func (users Users) Marshall(isPublic bool) []interface{} {
 	result := make([]interface{}, len(users))
 	for index, user := range users {
@@ -27,3 +35,11 @@ func (user *User) Marshall(isPublic bool) interface{} {
 	json.Unmarshal(userJson, &publicUser)
 	return publicUser
 }
+
+func (user *User) MarshallInternal(settings *notifications.Settings) InternalUser {
+	userJson, _ := json.Marshal(user)
+	var internalUser InternalUser
+	json.Unmarshal(userJson, &internalUser.PublicUser)
+	internalUser.NotificationSettings = settings
+	return internalUser
+}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"net/url"
+	"strings"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/utils/crypto_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var eventTypes = map[string]bool{
+	events.TypeUserCreated:     true,
+	events.TypeUserUpdated:     true,
+	events.TypeUserDeleted:     true,
+	events.TypeUserLoggedIn:    true,
+	events.TypePasswordChanged: true,
+	events.TypeTelegramLinked:  true,
+	events.TypeStatusChanged:   true,
+	events.TypeRoleChanged:     true,
+}
+
+type Webhook struct {
+	Id          int64    `json:"id"`
+	Url         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"`
+	EventTypes  []string `json:"event_types"`
+	Enabled     bool     `json:"enabled"`
+	DateCreated string   `json:"date_created"`
+}
+
+type Webhooks []Webhook
+
+// Delivery records a single attempt to send an event to a webhook.
+type Delivery struct {
+	Id          int64  `json:"id"`
+	WebhookId   int64  `json:"webhook_id"`
+	EventId     string `json:"event_id"`
+	EventType   string `json:"event_type"`
+	Attempt     int    `json:"attempt"`
+	StatusCode  int    `json:"status_code"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error"`
+	DateCreated string `json:"date_created"`
+}
+
+type Deliveries []Delivery
+
+// Pending is a delivery of an event to a webhook waiting in the queue for its next
+// attempt. Attempts counts those already made.
+type Pending struct {
+	Id            int64  `json:"id"`
+	WebhookId     int64  `json:"webhook_id"`
+	EventId       string `json:"event_id"`
+	EventType     string `json:"event_type"`
+	Payload       string `json:"payload"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	DateCreated   string `json:"date_created"`
+}
+
+func (webhook *Webhook) Validate() rest_errors.RestErr {
+	webhook.Url = strings.TrimSpace(webhook.Url)
+	target, err := url.Parse(webhook.Url)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		return rest_errors.NewBadRequestError("webhook url must be an absolute http(s) url")
+	}
+
+	for index, eventType := range webhook.EventTypes {
+		eventType = strings.TrimSpace(strings.ToLower(eventType))
+		if !eventTypes[eventType] {
+			return rest_errors.NewBadRequestError("invalid event type " + eventType)
+		}
+		webhook.EventTypes[index] = eventType
+	}
+	return nil
+}
+
+// Subscribes reports whether the webhook wants the given event type. Webhooks without
+// event types receive every event.
+func (webhook *Webhook) Subscribes(eventType string) bool {
+	if len(webhook.EventTypes) == 0 {
+		return true
+	}
+	for _, subscribed := range webhook.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshall hides the signing secret, which is only shown when the webhook is created.
+func (webhook *Webhook) Marshall(includeSecret bool) Webhook {
+	result := *webhook
+	if !includeSecret {
+		result.Secret = ""
+	}
+	return result
+}
+
+func (webhooks Webhooks) Marshall(includeSecret bool) []Webhook {
+	result := make([]Webhook, len(webhooks))
+	for index, webhook := range webhooks {
+		result[index] = webhook.Marshall(includeSecret)
+	}
+	return result
+}
+
+const (
+	HeaderEvent     = "X-TokenAlert-Event"
+	HeaderDelivery  = "X-TokenAlert-Delivery"
+	HeaderTimestamp = "X-TokenAlert-Timestamp"
+	HeaderSignature = "X-TokenAlert-Signature"
+)
+
+// Sign computes the value of the signature header: the hex HMAC-SHA256 of the
+// timestamp header, a dot and the raw body, keyed with the webhook secret.
+func Sign(secret string, timestamp string, body []byte) string {
+	payload := make([]byte, 0, len(timestamp)+1+len(body))
+	payload = append(payload, timestamp...)
+	payload = append(payload, '.')
+	payload = append(payload, body...)
+	return "sha256=" + crypto_utils.GetHmacSha256(secret, payload)
+}
@@ -0,0 +1,66 @@
+// Package logging ties log lines to the request they were written for, through the
+// request id and the trace id kept in its context, and to the member of staff
+// impersonating the user, if any.
+package logging
+
+import (
+	"context"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type requestIdKey struct{}
+
+type impersonatorIdKey struct{}
+
+// WithRequestId returns a copy of ctx carrying the id of the request it serves.
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestId returns the id of the request ctx serves, empty outside of requests.
+func RequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// WithImpersonatorId returns a copy of ctx serving a request made by a member of
+// staff impersonating the user.
+func WithImpersonatorId(ctx context.Context, impersonatorId int64) context.Context {
+	return context.WithValue(ctx, impersonatorIdKey{}, impersonatorId)
+}
+
+// ImpersonatorId returns the id of the member of staff impersonating the user, zero
+// when nobody is.
+func ImpersonatorId(ctx context.Context) int64 {
+	impersonatorId, _ := ctx.Value(impersonatorIdKey{}).(int64)
+	return impersonatorId
+}
+
+// Fields returns the request id, impersonator id and trace id found in ctx, as log
+// fields.
+func Fields(ctx context.Context) []zap.Field {
+	fields := make([]zap.Field, 0, 3)
+	if requestId := RequestId(ctx); requestId != "" {
+		fields = append(fields, zap.String("request_id", requestId))
+	}
+	if impersonatorId := ImpersonatorId(ctx); impersonatorId != 0 {
+		fields = append(fields, zap.Int64("impersonator_id", impersonatorId))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields = append(fields, zap.String("trace_id", span.TraceID().String()))
+	}
+	return fields
+}
+
+// Error is logger.Error with the fields of ctx added.
+func Error(ctx context.Context, msg string, err error, tags ...zap.Field) {
+	logger.Error(msg, err, append(tags, Fields(ctx)...)...)
+}
+
+// Info is logger.Info with the fields of ctx added.
+func Info(ctx context.Context, msg string, tags ...zap.Field) {
+	logger.Info(msg, append(tags, Fields(ctx)...)...)
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestFields(t *testing.T) {
+	assert.Empty(t, Fields(context.Background()))
+
+	ctx := WithRequestId(context.Background(), "abc-123")
+	assert.Equal(t, "abc-123", RequestId(ctx))
+	assert.Equal(t, []zap.Field{zap.String("request_id", "abc-123")}, Fields(ctx))
+
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId}))
+	assert.Equal(t, []zap.Field{zap.String("request_id", "abc-123"), zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736")}, Fields(ctx))
+
+	ctx = WithImpersonatorId(ctx, 1)
+	assert.Equal(t, int64(1), ImpersonatorId(ctx))
+	assert.Equal(t, zap.Int64("impersonator_id", 1), Fields(ctx)[1])
+}
@@ -1,7 +1,16 @@
 package main
 
-import "tokenalert_user-api/src/app"
+import (
+	"os"
+	"tokenalert_user-api/src/app"
+
+	// Embeds the IANA time zone database so user time zones resolve on hosts without it.
+	_ "time/tzdata"
+)
 
 func main() {
-	app.StartApplication()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(app.RunMigrate(os.Args[2:], os.Stdout))
+	}
+	app.StartApplication(os.Args[1:])
 }
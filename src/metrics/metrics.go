@@ -0,0 +1,326 @@
+// Package metrics keeps counters, histograms and gauges in memory and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var (
+	// Default holds every metric created with the package functions and is the one
+	// Handler serves.
+	Default = NewRegistry()
+
+	// LatencyBuckets, in seconds, suit requests and queries answered within seconds.
+	LatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// family is a metric with all its series, each one a set of label values.
+type family interface {
+	describe() (name, help, kind string)
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+	names    map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// register adds a family. Metrics are created once, at package level, so a name used
+// twice is a programming error.
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name, _, _ := f.describe()
+	if r.names[name] {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	r.names[name] = true
+	r.families = append(r.families, f)
+}
+
+// ServeHTTP writes every family, sorted by name.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool {
+		first, _, _ := families[i].describe()
+		second, _, _ := families[j].describe()
+		return first < second
+	})
+
+	w.Header().Set("Content-Type", contentType)
+	writer := bufio.NewWriter(w)
+	for _, f := range families {
+		name, help, kind := f.describe()
+		fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+		f.write(writer)
+	}
+	writer.Flush()
+}
+
+func Handler() http.Handler {
+	return Default
+}
+
+// labelSet names the labels of a family and keys its series by their values.
+type labelSet []string
+
+func (l labelSet) key(values []string) string {
+	if len(values) != len(l) {
+		panic(fmt.Sprintf("got %d label values for labels %v", len(values), []string(l)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// format renders the labels with the given values, plus an extra pair when extraName
+// is set, as histogram buckets need.
+func (l labelSet) format(values []string, extraName, extraValue string) string {
+	pairs := make([]string, 0, len(l)+1)
+	for i, name := range l {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+escapeLabel(extraValue)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter only goes up. Series appear on their first increment.
+type Counter struct {
+	name   string
+	help   string
+	labels labelSet
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+	Default.register(counter)
+	return counter
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("counter " + c.name + " can not decrease")
+	}
+	key := c.labels.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	series, ok := c.series[key]
+	if !ok {
+		series = &counterSeries{values: append([]string(nil), labelValues...)}
+		c.series[key] = series
+	}
+	series.value += delta
+}
+
+// Value returns the count of a series, zero when it never moved.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.labels.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if series, ok := c.series[key]; ok {
+		return series.value
+	}
+	return 0
+}
+
+func (c *Counter) describe() (string, string, string) {
+	return c.name, c.help, typeCounter
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.series) {
+		series := c.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labels.format(series.values, "", ""), formatValue(series.value))
+	}
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	name    string
+	help    string
+	labels  labelSet
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates a histogram with the given upper bounds, in increasing order.
+// The +Inf bucket is implied.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("buckets of " + name + " are not sorted")
+	}
+	histogram := &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	Default.register(histogram)
+	return histogram
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.labels.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{values: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+	if index := sort.SearchFloat64s(h.buckets, value); index < len(h.buckets) {
+		series.counts[index]++
+	}
+	series.count++
+	series.sum += value
+}
+
+// Count returns the number of observations of a series.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.labels.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if series, ok := h.series[key]; ok {
+		return series.count
+	}
+	return 0
+}
+
+func (h *Histogram) describe() (string, string, string) {
+	return h.name, h.help, typeHistogram
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.series) {
+		series := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels.format(series.values, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels.format(series.values, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labels.format(series.values, "", ""), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels.format(series.values, "", ""), series.count)
+	}
+}
+
+// Emit reports one series of a metric read at scrape time.
+type Emit func(value float64, labelValues ...string)
+
+// funcFamily reads its series from somewhere else on every scrape, such as the pool
+// statistics database/sql keeps.
+type funcFamily struct {
+	name    string
+	help    string
+	kind    string
+	labels  labelSet
+	collect func(Emit)
+}
+
+// NewGaugeFunc creates a gauge whose series collect emits on every scrape.
+func NewGaugeFunc(name, help string, labels []string, collect func(Emit)) {
+	Default.register(&funcFamily{name: name, help: help, kind: typeGauge, labels: labels, collect: collect})
+}
+
+// NewCounterFunc is NewGaugeFunc for values that only go up, such as totals kept by a
+// library.
+func NewCounterFunc(name, help string, labels []string, collect func(Emit)) {
+	Default.register(&funcFamily{name: name, help: help, kind: typeCounter, labels: labels, collect: collect})
+}
+
+func (f *funcFamily) describe() (string, string, string) {
+	return f.name, f.help, f.kind
+}
+
+// write sorts the series, since collect may emit them in any order.
+func (f *funcFamily) write(w *bufio.Writer) {
+	lines := make([]string, 0)
+	f.collect(func(value float64, labelValues ...string) {
+		f.labels.key(labelValues)
+		lines = append(lines, fmt.Sprintf("%s%s %s\n", f.name, f.labels.format(labelValues, "", ""), formatValue(value)))
+	})
+	sort.Strings(lines)
+	for _, line := range lines {
+		w.WriteString(line)
+	}
+}
+
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
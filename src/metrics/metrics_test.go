@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T) string {
+	response := httptest.NewRecorder()
+	Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, contentType, response.Header().Get("Content-Type"))
+	return response.Body.String()
+}
+
+func TestCounter(t *testing.T) {
+	counter := NewCounter("test_logins_total", "Logins by result.", "result")
+
+	counter.Inc("success")
+	counter.Inc("success")
+	counter.Add(3, "failure")
+
+	assert.Equal(t, float64(2), counter.Value("success"))
+	assert.Equal(t, float64(0), counter.Value("locked"))
+	assert.Contains(t, scrape(t), `# HELP test_logins_total Logins by result.
+# TYPE test_logins_total counter
+test_logins_total{result="failure"} 3
+test_logins_total{result="success"} 2
+`)
+}
+
+func TestCounterWithoutLabels(t *testing.T) {
+	counter := NewCounter("test_created_total", "Created.")
+
+	counter.Inc()
+
+	assert.Contains(t, scrape(t), "test_created_total 1\n")
+}
+
+func TestCounterEscapesLabelValues(t *testing.T) {
+	counter := NewCounter("test_escaped_total", "Escaped.", "route")
+
+	counter.Inc("a\"b\\c\nd")
+
+	assert.Contains(t, scrape(t), `test_escaped_total{route="a\"b\\c\nd"} 1`)
+}
+
+func TestCounterPanicsOnWrongLabelCount(t *testing.T) {
+	counter := NewCounter("test_labels_total", "Labels.", "method", "route")
+
+	assert.Panics(t, func() { counter.Inc("GET") })
+}
+
+func TestHistogram(t *testing.T) {
+	histogram := NewHistogram("test_duration_seconds", "Durations.", []float64{0.1, 1}, "operation")
+
+	histogram.Observe(0.05, "users.get")
+	histogram.Observe(0.1, "users.get")
+	histogram.Observe(0.5, "users.get")
+	histogram.Observe(2, "users.get")
+
+	assert.Equal(t, uint64(4), histogram.Count("users.get"))
+	assert.Contains(t, scrape(t), `# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{operation="users.get",le="0.1"} 2
+test_duration_seconds_bucket{operation="users.get",le="1"} 3
+test_duration_seconds_bucket{operation="users.get",le="+Inf"} 4
+test_duration_seconds_sum{operation="users.get"} 2.65
+test_duration_seconds_count{operation="users.get"} 4
+`)
+}
+
+func TestGaugeFunc(t *testing.T) {
+	open := 3.0
+	NewGaugeFunc("test_open_connections", "Open connections.", []string{"pool"}, func(emit Emit) {
+		emit(1, "replica-1:3306")
+		emit(open, "primary")
+	})
+
+	assert.Contains(t, scrape(t), `# TYPE test_open_connections gauge
+test_open_connections{pool="primary"} 3
+test_open_connections{pool="replica-1:3306"} 1
+`)
+	open = 5
+	assert.Contains(t, scrape(t), `test_open_connections{pool="primary"} 5`)
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	NewCounter("test_twice_total", "Twice.")
+
+	assert.Panics(t, func() { NewCounter("test_twice_total", "Twice.") })
+}
+
+func TestFamiliesAreSortedByName(t *testing.T) {
+	NewCounter("test_sorted_b_total", "B.").Inc()
+	NewCounter("test_sorted_a_total", "A.").Inc()
+
+	body := scrape(t)
+
+	assert.Less(t, strings.Index(body, "test_sorted_a_total"), strings.Index(body, "test_sorted_b_total"))
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UserIdKey is where handlers that learn who the user is, such as login, leave the
+// user id for the access log, as an int64. Routes with a :user_id parameter need not.
+const UserIdKey = "user_id"
+
+var (
+	logRequest = logging.Info
+)
+
+// AccessLog writes a JSON line per request once it is answered, with its route,
+// status, latency and user, plus the request and trace ids that the logs written
+// while serving it carry too.
+func AccessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	fields := []zap.Field{
+		zap.String("method", c.Request.Method),
+		zap.String("route", route),
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("status", c.Writer.Status()),
+		zap.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		zap.Int("bytes", bodySize(c)),
+		zap.String("client_ip", c.ClientIP()),
+	}
+	if userId, ok := requestUserId(c); ok {
+		fields = append(fields, zap.Int64("user_id", userId))
+	}
+	if len(c.Errors) > 0 {
+		fields = append(fields, zap.String("errors", c.Errors.String()))
+	}
+	logRequest(c.Request.Context(), "request", fields...)
+}
+
+// bodySize is the size of the response body, which gin reports as -1 when nothing
+// was written.
+func bodySize(c *gin.Context) int {
+	if size := c.Writer.Size(); size > 0 {
+		return size
+	}
+	return 0
+}
+
+func requestUserId(c *gin.Context) (int64, bool) {
+	if value, ok := c.Get(UserIdKey); ok {
+		userId, ok := value.(int64)
+		return userId, ok
+	}
+	userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	return userId, err == nil
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type loggedRequest struct {
+	requestId string
+	fields    map[string]interface{}
+}
+
+// captureRequests makes AccessLog hand its lines to the returned slice.
+func captureRequests(t *testing.T) *[]loggedRequest {
+	logged := []loggedRequest{}
+	logRequest = func(ctx context.Context, msg string, tags ...zap.Field) {
+		encoder := zapcore.NewMapObjectEncoder()
+		for _, tag := range tags {
+			tag.AddTo(encoder)
+		}
+		logged = append(logged, loggedRequest{requestId: logging.RequestId(ctx), fields: encoder.Fields})
+	}
+	t.Cleanup(func() { logRequest = logging.Info })
+	return &logged
+}
+
+func TestAccessLog(t *testing.T) {
+	logged := captureRequests(t)
+	router := gin.New()
+	router.Use(RequestId, AccessLog)
+	router.GET("/users/:user_id", func(c *gin.Context) {
+		c.String(http.StatusOK, "found")
+	})
+	request := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	request.Header.Set(RequestIdHeader, "abc-123")
+
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.Equal(t, 1, len(*logged))
+	line := (*logged)[0]
+	assert.Equal(t, "abc-123", line.requestId)
+	assert.Equal(t, http.MethodGet, line.fields["method"])
+	assert.Equal(t, "/users/:user_id", line.fields["route"])
+	assert.Equal(t, "/users/42", line.fields["path"])
+	assert.EqualValues(t, http.StatusOK, line.fields["status"])
+	assert.EqualValues(t, 5, line.fields["bytes"])
+	assert.EqualValues(t, 42, line.fields["user_id"])
+	assert.GreaterOrEqual(t, line.fields["latency_ms"], float64(0))
+}
+
+func TestAccessLogTakesUserIdFromHandler(t *testing.T) {
+	logged := captureRequests(t)
+	router := gin.New()
+	router.Use(AccessLog)
+	router.POST("/users/login", func(c *gin.Context) {
+		c.Set(UserIdKey, int64(7))
+		c.Status(http.StatusOK)
+	})
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/login", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	assert.Equal(t, 2, len(*logged))
+	assert.EqualValues(t, 7, (*logged)[0].fields["user_id"])
+	assert.EqualValues(t, 0, (*logged)[1].fields["bytes"])
+	assert.NotContains(t, (*logged)[1].fields, "user_id")
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"strconv"
+	"tokenalert_user-api/src/domain/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallerIdHeader carries the id of the user the gateway authenticated the request
+// for. The gateway sets it, replacing whatever the client sent.
+const CallerIdHeader = "X-Caller-Id"
+
+// Caller makes the user of X-Caller-Id the actor of the audit entries the request
+// produces, and the user of its access log line. Requests without one act for no
+// one in particular.
+func Caller(c *gin.Context) {
+	callerId, err := strconv.ParseInt(c.GetHeader(CallerIdHeader), 10, 64)
+	if err == nil && callerId > 0 {
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), audit.Actor{UserId: callerId}))
+		c.Set(UserIdKey, callerId)
+	}
+	c.Next()
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func actorOf(header string) audit.Actor {
+	router := gin.New()
+	router.Use(Caller)
+	var actor audit.Actor
+	router.GET("/users/:user_id", func(c *gin.Context) {
+		actor = audit.ActorFrom(c.Request.Context())
+	})
+	request := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	if header != "" {
+		request.Header.Set(CallerIdHeader, header)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	return actor
+}
+
+func TestCaller(t *testing.T) {
+	assert.Equal(t, audit.Actor{UserId: 42}, actorOf("42"))
+	assert.Equal(t, audit.Actor{}, actorOf(""))
+	assert.Equal(t, audit.Actor{}, actorOf("admin"))
+	assert.Equal(t, audit.Actor{}, actorOf("-1"))
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ImpersonationTokenHeader carries the token of POST /admin/impersonations. The
+// gateway still authenticates the staff member, in X-Caller-Id.
+const ImpersonationTokenHeader = "X-Impersonation-Token"
+
+// Impersonation makes requests with a valid impersonation token act as the user of
+// the token: the audit entries they produce have that user as actor and the staff
+// member as impersonator, and their logs and span carry the impersonator id. Routes
+// about a user only accept the user of the token.
+func Impersonation(c *gin.Context) {
+	token := c.GetHeader(ImpersonationTokenHeader)
+	if token == "" {
+		c.Next()
+		return
+	}
+
+	ctx := c.Request.Context()
+	claims, err := services.ImpersonationService.Verify(ctx, token, audit.ActorFrom(ctx).UserId)
+	if err != nil {
+		c.AbortWithStatusJSON(err.Status(), err)
+		return
+	}
+	if userId := c.Param("user_id"); userId != "" && userId != strconv.FormatInt(claims.UserId, 10) {
+		restErr := rest_errors.NewRestError("impersonation token is for another user", http.StatusForbidden, "forbidden", nil)
+		c.AbortWithStatusJSON(restErr.Status(), restErr)
+		return
+	}
+
+	ctx = audit.WithActor(ctx, audit.Actor{UserId: claims.UserId, ImpersonatorId: claims.ImpersonatorId})
+	ctx = logging.WithImpersonatorId(ctx, claims.ImpersonatorId)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("enduser.impersonator_id", claims.ImpersonatorId))
+	c.Request = c.Request.WithContext(ctx)
+	c.Set(UserIdKey, claims.UserId)
+	c.Next()
+}
+
+// ForbidImpersonation refuses the route to impersonated requests.
+func ForbidImpersonation(c *gin.Context) {
+	if err := services.ImpersonationService.Restrict(c.Request.Context(), c.Request.Method+" "+c.FullPath()); err != nil {
+		c.AbortWithStatusJSON(err.Status(), err)
+		return
+	}
+	c.Next()
+}
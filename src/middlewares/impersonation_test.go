@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/impersonation"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type impersonationServiceMock struct {
+	tokens map[string]impersonation.Claims
+}
+
+func (m *impersonationServiceMock) Start(context.Context, impersonation.Request) (*impersonation.Grant, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (m *impersonationServiceMock) Verify(ctx context.Context, token string, callerId int64) (*impersonation.Claims, rest_errors.RestErr) {
+	claims, ok := m.tokens[token]
+	if !ok || claims.ImpersonatorId != callerId {
+		return nil, rest_errors.NewUnauthorizedError("invalid impersonation token")
+	}
+	return &claims, nil
+}
+
+func (m *impersonationServiceMock) Restrict(ctx context.Context, operation string) rest_errors.RestErr {
+	if audit.ActorFrom(ctx).Impersonated() {
+		return rest_errors.NewRestError(operation+" is not allowed while impersonating", http.StatusForbidden, "forbidden", nil)
+	}
+	return nil
+}
+
+func TestImpersonation(t *testing.T) {
+	services.ImpersonationService = &impersonationServiceMock{tokens: map[string]impersonation.Claims{
+		"valid": {TokenId: "abc", UserId: 7, ImpersonatorId: 1},
+	}}
+	router := gin.New()
+	router.Use(Caller)
+	router.Use(Impersonation)
+	var actor audit.Actor
+	var impersonatorId int64
+	handler := func(c *gin.Context) {
+		actor = audit.ActorFrom(c.Request.Context())
+		impersonatorId = logging.ImpersonatorId(c.Request.Context())
+		c.Status(http.StatusOK)
+	}
+	router.GET("/users/:user_id", handler)
+	router.DELETE("/users/:user_id", ForbidImpersonation, handler)
+	call := func(method string, path string, token string) int {
+		actor, impersonatorId = audit.Actor{}, 0
+		response := httptest.NewRecorder()
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set(CallerIdHeader, "1")
+		if token != "" {
+			request.Header.Set(ImpersonationTokenHeader, token)
+		}
+		router.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/users/7", ""))
+	assert.Equal(t, audit.Actor{UserId: 1}, actor)
+	assert.Equal(t, int64(0), impersonatorId)
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/users/7", "valid"))
+	assert.Equal(t, audit.Actor{UserId: 7, ImpersonatorId: 1}, actor)
+	assert.Equal(t, int64(1), impersonatorId)
+
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/users/8", "valid"))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/users/7", "forged"))
+	assert.Equal(t, audit.Actor{}, actor)
+
+	assert.Equal(t, http.StatusOK, call(http.MethodDelete, "/users/7", ""))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete, "/users/7", "valid"))
+	assert.Equal(t, audit.Actor{}, actor)
+}
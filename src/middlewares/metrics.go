@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+	"tokenalert_user-api/src/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels the requests no route matched, so scanners hitting random
+// paths do not create a series each.
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = metrics.NewCounter("http_requests_total",
+		"HTTP requests answered, by method, route and status.", "method", "route", "status")
+	httpRequestDuration = metrics.NewHistogram("http_request_duration_seconds",
+		"Time taken to answer HTTP requests, by method, route and status.", metrics.LatencyBuckets, "method", "route", "status")
+)
+
+// Metrics counts and times every request under its route pattern, such as
+// /users/:user_id, rather than its path.
+func Metrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	status := strconv.Itoa(c.Writer.Status())
+	httpRequests.Inc(c.Request.Method, route, status)
+	httpRequestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsLabelsRequestsByRoute(t *testing.T) {
+	router := gin.New()
+	router.Use(Metrics)
+	router.GET("/users/:user_id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wp-admin", nil))
+
+	assert.Equal(t, float64(2), httpRequests.Value(http.MethodGet, "/users/:user_id", "404"))
+	assert.Equal(t, uint64(2), httpRequestDuration.Count(http.MethodGet, "/users/:user_id", "404"))
+	assert.Equal(t, float64(1), httpRequests.Value(http.MethodGet, unmatchedRoute, "404"))
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"strconv"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets the request through only when the caller, as set by Caller,
+// is an active user whose role grants permission. The role of the caller is added to
+// the actor of the request, for the checks services make on their own.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := audit.ActorFrom(c.Request.Context())
+		caller, err := services.AdminService.Authorize(c.Request.Context(), actor.UserId, permission)
+		if err != nil {
+			c.AbortWithStatusJSON(err.Status(), err)
+			return
+		}
+		actor.Role = caller.Role
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
+
+// RequireOwnerOrPermission lets the caller act on their own account, the one of the
+// user_id parameter, and otherwise asks for permission as RequirePermission does.
+func RequireOwnerOrPermission(permission string) gin.HandlerFunc {
+	requirePermission := RequirePermission(permission)
+	return func(c *gin.Context) {
+		callerId := audit.ActorFrom(c.Request.Context()).UserId
+		if callerId != 0 && c.Param("user_id") == strconv.FormatInt(callerId, 10) {
+			c.Next()
+			return
+		}
+		requirePermission(c)
+	}
+}
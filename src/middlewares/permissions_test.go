@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminServiceMock struct {
+	roles map[int64]string
+}
+
+func (m *adminServiceMock) Authorize(ctx context.Context, callerId int64, permission string) (*users.User, rest_errors.RestErr) {
+	role, ok := m.roles[callerId]
+	if !ok {
+		return nil, rest_errors.NewUnauthorizedError("unknown caller")
+	}
+	if !access.Can(role, permission) {
+		return nil, rest_errors.NewRestError("missing permission "+permission, http.StatusForbidden, "forbidden", nil)
+	}
+	return &users.User{Id: callerId, Role: role, Status: users.StatusActive}, nil
+}
+
+func (m *adminServiceMock) SearchUsers(context.Context, users.Filter) (users.Users, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (m *adminServiceMock) ChangeStatus(context.Context, int64, string) (*users.User, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (m *adminServiceMock) ChangeRole(context.Context, int64, access.RoleRequest) (*users.User, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	services.AdminService = &adminServiceMock{roles: map[int64]string{1: access.RoleAdmin, 2: access.RoleSupport}}
+	router := gin.New()
+	router.Use(Caller)
+	var actor audit.Actor
+	router.POST("/admin/users/:user_id/role", RequirePermission(access.PermissionRolesChange), func(c *gin.Context) {
+		actor = audit.ActorFrom(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	call := func(callerId string) int {
+		response := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/7/role", nil)
+		if callerId != "" {
+			request.Header.Set(CallerIdHeader, callerId)
+		}
+		router.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("1"))
+	assert.Equal(t, audit.Actor{UserId: 1, Role: access.RoleAdmin}, actor)
+
+	actor = audit.Actor{}
+	assert.Equal(t, http.StatusForbidden, call("2"))
+	assert.Equal(t, http.StatusUnauthorized, call(""))
+	assert.Equal(t, audit.Actor{}, actor)
+}
+
+func TestRequireOwnerOrPermission(t *testing.T) {
+	services.AdminService = &adminServiceMock{roles: map[int64]string{1: access.RoleAdmin, 2: access.RoleSupport, 7: access.RoleUser}}
+	router := gin.New()
+	router.Use(Caller)
+	router.DELETE("/users/:user_id", RequireOwnerOrPermission(access.PermissionUsersEdit), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	call := func(callerId string, userId string) int {
+		response := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodDelete, "/users/"+userId, nil)
+		if callerId != "" {
+			request.Header.Set(CallerIdHeader, callerId)
+		}
+		router.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("7", "7"))
+	assert.Equal(t, http.StatusOK, call("1", "7"))
+	assert.Equal(t, http.StatusForbidden, call("7", "8"))
+	assert.Equal(t, http.StatusForbidden, call("2", "7"))
+	assert.Equal(t, http.StatusUnauthorized, call("", "7"))
+}
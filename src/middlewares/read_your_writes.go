@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadYourWritesHeader lets a client that has just changed data ask for its next
+// reads to skip the replicas, which may still lag behind.
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+// ReadYourWrites sends every read of a mutating request to the primary, so the
+// request sees its own writes, and does the same for requests carrying
+// X-Read-Your-Writes: true.
+func ReadYourWrites(c *gin.Context) {
+	if mutating(c.Request.Method) || c.GetHeader(ReadYourWritesHeader) == "true" {
+		c.Request = c.Request.WithContext(users_db.WithPrimary(c.Request.Context()))
+	}
+	c.Next()
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
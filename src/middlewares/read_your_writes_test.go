@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func readsFromPrimary(method string, header string) bool {
+	router := gin.New()
+	router.Use(ReadYourWrites)
+
+	primary := false
+	router.Handle(method, "/users/1", func(c *gin.Context) {
+		primary = users_db.ReadsFromPrimary(c.Request.Context())
+	})
+
+	request := httptest.NewRequest(method, "/users/1", nil)
+	if header != "" {
+		request.Header.Set(ReadYourWritesHeader, header)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	return primary
+}
+
+func TestReadYourWrites(t *testing.T) {
+	assert.False(t, readsFromPrimary(http.MethodGet, ""))
+	assert.False(t, readsFromPrimary(http.MethodGet, "false"))
+	assert.True(t, readsFromPrimary(http.MethodGet, "true"))
+	assert.True(t, readsFromPrimary(http.MethodPut, ""))
+	assert.True(t, readsFromPrimary(http.MethodPost, ""))
+	assert.True(t, readsFromPrimary(http.MethodDelete, ""))
+}
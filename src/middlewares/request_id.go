@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/crypto_utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIdHeader carries the id of a request, set by the caller or by a proxy in
+	// front of the API, and sent back with the response.
+	RequestIdHeader = "X-Request-ID"
+
+	maxRequestIdLength = 128
+)
+
+// RequestId keeps the X-Request-ID of the caller, or makes one up when it is
+// missing or unfit for logs, puts it in the request context for logging and answers
+// with it.
+func RequestId(c *gin.Context) {
+	requestId := c.GetHeader(RequestIdHeader)
+	if !validRequestId(requestId) {
+		var err error
+		if requestId, err = crypto_utils.NewUUID(); err != nil {
+			panic(err)
+		}
+	}
+	ctx := logging.WithRequestId(c.Request.Context(), requestId)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", requestId))
+	c.Request = c.Request.WithContext(ctx)
+	c.Header(RequestIdHeader, requestId)
+	c.Next()
+}
+
+// validRequestId accepts ids of printable ASCII without spaces, so that a caller can
+// not break or bloat log lines through them.
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(requestId); i++ {
+		if requestId[i] <= ' ' || requestId[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// serveRequestId returns the request id the handler saw and the one answered.
+func serveRequestId(header string) (string, string) {
+	router := gin.New()
+	router.Use(RequestId)
+	seen := ""
+	router.GET("/users/:user_id", func(c *gin.Context) {
+		seen = logging.RequestId(c.Request.Context())
+	})
+	request := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	if header != "" {
+		request.Header.Set(RequestIdHeader, header)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return seen, response.Header().Get(RequestIdHeader)
+}
+
+func TestRequestIdKeepsCallerId(t *testing.T) {
+	seen, answered := serveRequestId("edge-7f3a:42")
+
+	assert.Equal(t, "edge-7f3a:42", seen)
+	assert.Equal(t, "edge-7f3a:42", answered)
+}
+
+func TestRequestIdGeneratesMissingId(t *testing.T) {
+	seen, answered := serveRequestId("")
+
+	assert.Regexp(t, uuidPattern, seen)
+	assert.Equal(t, seen, answered)
+}
+
+func TestRequestIdReplacesUnfitId(t *testing.T) {
+	for _, header := range []string{"id with spaces", "id\twith\ttabs", "café", strings.Repeat("a", maxRequestIdLength+1)} {
+		seen, answered := serveRequestId(header)
+
+		assert.Regexp(t, uuidPattern, seen, header)
+		assert.Equal(t, seen, answered)
+	}
+}
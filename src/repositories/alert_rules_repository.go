@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/sql_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryInsertAlertRule        = "INSERT INTO alert_rules(user_id, token, type, threshold, window_minutes, cooldown_minutes, enabled, expires_at, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	queryGetAlertRule           = "SELECT id, user_id, token, type, threshold, window_minutes, cooldown_minutes, enabled, expires_at, date_created FROM alert_rules WHERE id=?;"
+	queryFindAlertRulesByUser   = "SELECT id, user_id, token, type, threshold, window_minutes, cooldown_minutes, enabled, expires_at, date_created FROM alert_rules WHERE user_id=?;"
+	queryFindActiveAlertRules   = "SELECT id, user_id, token, type, threshold, window_minutes, cooldown_minutes, enabled, expires_at, date_created FROM alert_rules WHERE enabled=? AND (expires_at IS NULL OR expires_at > ?) ORDER BY id;"
+	queryUpdateAlertRule        = "UPDATE alert_rules SET token=?, type=?, threshold=?, window_minutes=?, cooldown_minutes=?, enabled=?, expires_at=? WHERE id=?;"
+	queryDeleteAlertRule        = "DELETE FROM alert_rules WHERE id=?;"
+	queryDeleteAlertRulesByUser = "DELETE FROM alert_rules WHERE user_id=?;"
+)
+
+var (
+	AlertRulesRepository alertRulesRepositoryInterface = &alertRulesRepository{}
+)
+
+type alertRulesRepository struct {
+	session
+}
+
+type alertRulesRepositoryInterface interface {
+	Save(context.Context, *alerts.AlertRule) rest_errors.RestErr
+	Get(context.Context, int64) (*alerts.AlertRule, rest_errors.RestErr)
+	FindByUserId(context.Context, int64) (alerts.AlertRules, rest_errors.RestErr)
+	FindActive(context.Context, string, func(alerts.AlertRule) error) rest_errors.RestErr
+	Update(context.Context, *alerts.AlertRule) rest_errors.RestErr
+	Delete(context.Context, int64) rest_errors.RestErr
+	DeleteByUserId(context.Context, int64) rest_errors.RestErr
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func scanAlertRule(row rowScanner) (*alerts.AlertRule, error) {
+	var rule alerts.AlertRule
+	if err := row.Scan(&rule.Id, &rule.UserId, &rule.Token, &rule.Type, &rule.Threshold, &rule.WindowMinutes,
+		&rule.CooldownMinutes, &rule.Enabled, dbDateTime{&rule.ExpiresAt}, dbDateTime{&rule.DateCreated}); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *alertRulesRepository) Save(ctx context.Context, rule *alerts.AlertRule) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "alert_rules.save")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "alert_rules.save")
+	defer cancel()
+
+	var ruleId int64
+	err := r.run(ctx, users_db.Current.ReturningId(queryInsertAlertRule), func(stmt *sql.Stmt) error {
+		var err error
+		ruleId, err = insertedId(ctx, stmt, rule.UserId, rule.Token, rule.Type, rule.Threshold, rule.WindowMinutes,
+			rule.CooldownMinutes, rule.Enabled, nullableString(rule.ExpiresAt), rule.DateCreated)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare save alert rule statement", err)
+			return databaseError(ctx, err, "error saving alert rule")
+		}
+		logging.Error(ctx, "error when trying to save alert rule", err)
+		return databaseError(ctx, err, "error saving alert rule")
+	}
+	rule.Id = ruleId
+	return nil
+}
+
+func (r *alertRulesRepository) Get(ctx context.Context, id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "alert_rules.get")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "alert_rules.get")
+	defer cancel()
+
+	var rule *alerts.AlertRule
+	err := r.run(ctx, queryGetAlertRule, func(stmt *sql.Stmt) error {
+		var err error
+		rule, err = scanAlertRule(stmt.QueryRowContext(ctx, id))
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare get alert rule statement", err)
+			return nil, databaseError(ctx, err, "error fetching alert rule")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
+			return nil, sql_utils.ParseError(err)
+		}
+		logging.Error(ctx, "error when trying to get alert rule by id", err)
+		return nil, databaseError(ctx, err, "error fetching alert rule")
+	}
+	return rule, nil
+}
+
+func (r *alertRulesRepository) FindByUserId(ctx context.Context, userId int64) (alerts.AlertRules, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "alert_rules.find_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "alert_rules.find_by_user_id")
+	defer cancel()
+
+	result := make(alerts.AlertRules, 0)
+	err := r.run(ctx, queryFindAlertRulesByUser, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, userId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rule, err := scanAlertRule(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, *rule)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare find alert rules by user statement", err)
+			return nil, databaseError(ctx, err, "error fetching alert rules")
+		}
+		logging.Error(ctx, "error when trying to find alert rules by user", err)
+		return nil, databaseError(ctx, err, "error fetching alert rules")
+	}
+	return result, nil
+}
+
+// FindActive walks every enabled, non expired rule in id order and hands each one to
+// the given callback, so callers can stream large result sets without buffering them.
+// The walk lasts as long as the callback needs, so it gets no operation timeout: only
+// ctx ends it.
+func (r *alertRulesRepository) FindActive(ctx context.Context, now string, callback func(alerts.AlertRule) error) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "alert_rules.find_active")
+	defer end()
+
+	var callbackErr error
+	err := r.run(ctx, queryFindActiveAlertRules, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, true, now)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rule, err := scanAlertRule(rows)
+			if err != nil {
+				return err
+			}
+			if callbackErr = callback(*rule); callbackErr != nil {
+				return callbackErr
+			}
+		}
+		return rows.Err()
+	})
+	if callbackErr != nil {
+		logging.Error(ctx, "error when handling active alert rule", callbackErr)
+		return rest_errors.NewInternalServerError("error streaming alert rules", callbackErr)
+	}
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare find active alert rules statement", err)
+			return databaseError(ctx, err, "error fetching alert rules")
+		}
+		logging.Error(ctx, "error when trying to find active alert rules", err)
+		return databaseError(ctx, err, "error fetching alert rules")
+	}
+	return nil
+}
+
+func (r *alertRulesRepository) Update(ctx context.Context, rule *alerts.AlertRule) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "alert_rules.update")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "alert_rules.update")
+	defer cancel()
+
+	err := r.run(ctx, queryUpdateAlertRule, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, rule.Token, rule.Type, rule.Threshold, rule.WindowMinutes, rule.CooldownMinutes,
+			rule.Enabled, nullableString(rule.ExpiresAt), rule.Id)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare update alert rule statement", err)
+			return databaseError(ctx, err, "error updating alert rule")
+		}
+		logging.Error(ctx, "error when trying to update alert rule", err)
+		return databaseError(ctx, err, "error updating alert rule")
+	}
+	return nil
+}
+
+func (r *alertRulesRepository) Delete(ctx context.Context, id int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "alert_rules.delete")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "alert_rules.delete")
+	defer cancel()
+
+	err := r.run(ctx, queryDeleteAlertRule, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, id)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare delete alert rule statement", err)
+			return databaseError(ctx, err, "error deleting alert rule")
+		}
+		logging.Error(ctx, "error when trying to delete alert rule", err)
+		return databaseError(ctx, err, "error deleting alert rule")
+	}
+	return nil
+}
+
+func (r *alertRulesRepository) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "alert_rules.delete_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "alert_rules.delete_by_user_id")
+	defer cancel()
+
+	err := r.run(ctx, queryDeleteAlertRulesByUser, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, userId)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare delete alert rules by user statement", err)
+			return databaseError(ctx, err, "error deleting alert rules")
+		}
+		logging.Error(ctx, "error when trying to delete alert rules by user", err)
+		return databaseError(ctx, err, "error deleting alert rules")
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/alerts"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var alertRuleColumns = []string{"id", "user_id", "token", "type", "threshold", "window_minutes", "cooldown_minutes", "enabled", "expires_at", "date_created"}
+
+func TestSaveAlertRuleOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	enabled := true
+	rule := alerts.AlertRule{UserId: 1, Token: "btc", Type: alerts.TypePriceAbove, Threshold: 30000, CooldownMinutes: 10, Enabled: &enabled, DateCreated: "2022-01-01 00:00:00"}
+
+	prep := mock.ExpectPrepare(queryInsertAlertRule)
+	prep.ExpectExec().WithArgs(rule.UserId, rule.Token, rule.Type, rule.Threshold, rule.WindowMinutes, rule.CooldownMinutes, true, nil, rule.DateCreated).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+
+	err := AlertRulesRepository.Save(context.Background(), &rule)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), rule.Id)
+}
+
+func TestSaveAlertRuleExecutionFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rule := alerts.AlertRule{UserId: 1, Token: "btc", Type: alerts.TypePriceAbove, Threshold: 30000, ExpiresAt: "2030-01-01 00:00:00"}
+
+	prep := mock.ExpectPrepare(queryInsertAlertRule)
+	prep.ExpectExec().WillReturnError(errors.New("database error"))
+
+	err := AlertRulesRepository.Save(context.Background(), &rule)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error saving alert rule", err.Message())
+}
+
+func TestGetAlertRuleOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(alertRuleColumns).
+		AddRow(12, 1, "btc", "price_above", 30000, 0, 10, true, nil, "2022-01-01 00:00:00")
+
+	prep := mock.ExpectPrepare(queryGetAlertRule)
+	prep.ExpectQuery().WithArgs(12).WillReturnRows(rows)
+
+	rule, err := AlertRulesRepository.Get(context.Background(), 12)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), rule.Id)
+	assert.Equal(t, "btc", rule.Token)
+	assert.Equal(t, "", rule.ExpiresAt)
+	assert.True(t, *rule.Enabled)
+}
+
+func TestGetAlertRuleNotFound(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryGetAlertRule)
+	prep.ExpectQuery().WithArgs(12).WillReturnRows(sqlmock.NewRows(alertRuleColumns))
+
+	_, err := AlertRulesRepository.Get(context.Background(), 12)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestFindAlertRulesByUserIdOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(alertRuleColumns).
+		AddRow(12, 1, "btc", "price_above", 30000, 0, 10, true, nil, "2022-01-01 00:00:00").
+		AddRow(13, 1, "eth", "percent_change", -5, 60, 0, false, "2030-01-01 00:00:00", "2022-01-01 00:00:00")
+
+	prep := mock.ExpectPrepare(queryFindAlertRulesByUser)
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(rows)
+
+	rules, err := AlertRulesRepository.FindByUserId(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rules))
+	assert.Equal(t, "2030-01-01 00:00:00", rules[1].ExpiresAt)
+}
+
+func TestFindActiveAlertRulesOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	now := "2022-01-01 00:00:00"
+	rows := sqlmock.NewRows(alertRuleColumns).
+		AddRow(12, 1, "btc", "price_above", 30000, 0, 10, true, nil, now).
+		AddRow(14, 2, "sol", "volume_spike", 3, 30, 0, true, nil, now)
+
+	prep := mock.ExpectPrepare(queryFindActiveAlertRules)
+	prep.ExpectQuery().WithArgs(true, now).WillReturnRows(rows)
+
+	var ids []int64
+	err := AlertRulesRepository.FindActive(context.Background(), now, func(rule alerts.AlertRule) error {
+		ids = append(ids, rule.Id)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{12, 14}, ids)
+}
+
+func TestFindActiveAlertRulesCallbackFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	now := "2022-01-01 00:00:00"
+	rows := sqlmock.NewRows(alertRuleColumns).
+		AddRow(12, 1, "btc", "price_above", 30000, 0, 10, true, nil, now)
+
+	prep := mock.ExpectPrepare(queryFindActiveAlertRules)
+	prep.ExpectQuery().WithArgs(true, now).WillReturnRows(rows)
+
+	err := AlertRulesRepository.FindActive(context.Background(), now, func(rule alerts.AlertRule) error {
+		return errors.New("broken pipe")
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error streaming alert rules", err.Message())
+}
+
+func TestUpdateAlertRuleOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	enabled := false
+	rule := alerts.AlertRule{Id: 12, UserId: 1, Token: "btc", Type: alerts.TypePriceBelow, Threshold: 20000, Enabled: &enabled}
+
+	prep := mock.ExpectPrepare(queryUpdateAlertRule)
+	prep.ExpectExec().WithArgs(rule.Token, rule.Type, rule.Threshold, rule.WindowMinutes, rule.CooldownMinutes, false, nil, rule.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := AlertRulesRepository.Update(context.Background(), &rule)
+
+	assert.Nil(t, err)
+}
+
+func TestDeleteAlertRulePrepareQueryFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectPrepare(queryDeleteAlertRule).WillReturnError(errors.New("database error"))
+
+	err := AlertRulesRepository.Delete(context.Background(), 12)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error deleting alert rule", err.Message())
+}
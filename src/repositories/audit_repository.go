@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryInsertAuditEntry = "INSERT INTO audit_log(action, outcome, actor_id, target_user_id, impersonator_id, request_id, changes, details, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	querySelectAuditLog   = "SELECT id, action, outcome, actor_id, target_user_id, impersonator_id, request_id, changes, details, date_created FROM audit_log"
+)
+
+var (
+	AuditRepository auditRepositoryInterface = &auditRepository{}
+)
+
+type auditRepository struct {
+	session
+}
+
+// auditRepositoryInterface has no way to change or remove entries; the tables refuse
+// it as well.
+type auditRepositoryInterface interface {
+	Append(context.Context, *audit.Entry) rest_errors.RestErr
+	Find(context.Context, audit.Filter) (audit.Entries, rest_errors.RestErr)
+	Walk(context.Context, audit.Filter, func(audit.Entry) error) rest_errors.RestErr
+}
+
+func (r *auditRepository) Append(ctx context.Context, entry *audit.Entry) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "audit.append")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "audit.append")
+	defer cancel()
+
+	changes, err := nullableJSON(len(entry.Changes) > 0, entry.Changes)
+	if err != nil {
+		logging.Error(ctx, "error when trying to encode audit changes", err)
+		return rest_errors.NewInternalServerError("error saving audit entry", errors.New("encoding error"))
+	}
+	details, err := nullableJSON(len(entry.Details) > 0, entry.Details)
+	if err != nil {
+		logging.Error(ctx, "error when trying to encode audit details", err)
+		return rest_errors.NewInternalServerError("error saving audit entry", errors.New("encoding error"))
+	}
+
+	var entryId int64
+	err = r.run(ctx, users_db.Current.ReturningId(queryInsertAuditEntry), func(stmt *sql.Stmt) error {
+		var err error
+		entryId, err = insertedId(ctx, stmt, entry.Action, entry.Outcome, entry.ActorId, entry.TargetUserId, entry.ImpersonatorId, entry.RequestId, changes, details, entry.DateCreated)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare save audit entry statement", err)
+			return databaseError(ctx, err, "error saving audit entry")
+		}
+		logging.Error(ctx, "error when trying to save audit entry", err)
+		return databaseError(ctx, err, "error saving audit entry")
+	}
+	entry.Id = entryId
+	return nil
+}
+
+// Find returns a page of the entries matching filter, newest first.
+func (r *auditRepository) Find(ctx context.Context, filter audit.Filter) (audit.Entries, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "audit.find")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "audit.find")
+	defer cancel()
+
+	result := make(audit.Entries, 0)
+	query, args := auditQuery(filter, "DESC", filter.Limit)
+	if err := r.scan(ctx, query, args, func(entry audit.Entry) error {
+		result = append(result, entry)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Walk hands every entry matching filter to callback, oldest first, ignoring the
+// limit, so exports can stream the whole log. It runs without the operation
+// timeout, for as long as the caller's context lasts.
+func (r *auditRepository) Walk(ctx context.Context, filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "audit.walk")
+	defer end()
+
+	query, args := auditQuery(filter, "ASC", 0)
+	return r.scan(ctx, query, args, callback)
+}
+
+func (r *auditRepository) scan(ctx context.Context, query string, args []interface{}, callback func(audit.Entry) error) rest_errors.RestErr {
+	var callbackErr error
+	err := r.read(ctx, query, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			entry, err := scanAuditEntry(rows)
+			if err != nil {
+				return err
+			}
+			if callbackErr = callback(*entry); callbackErr != nil {
+				return callbackErr
+			}
+		}
+		return rows.Err()
+	})
+	if callbackErr != nil {
+		logging.Error(ctx, "error when handling audit entry", callbackErr)
+		return rest_errors.NewInternalServerError("error streaming audit entries", callbackErr)
+	}
+	if err != nil {
+		logging.Error(ctx, "error when trying to find audit entries", err)
+		return databaseError(ctx, err, "error fetching audit entries")
+	}
+	return nil
+}
+
+// auditQuery builds the query of filter. Conditions only appear for the fields set,
+// so the statement cache holds one statement per combination actually used.
+func auditQuery(filter audit.Filter, order string, limit int) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(condition string, value interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, value)
+	}
+	if filter.Action != "" {
+		add("action=?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		add("outcome=?", filter.Outcome)
+	}
+	if filter.ActorId != 0 {
+		add("actor_id=?", filter.ActorId)
+	}
+	if filter.TargetUserId != 0 {
+		add("target_user_id=?", filter.TargetUserId)
+	}
+	if filter.ImpersonatorId != 0 {
+		add("impersonator_id=?", filter.ImpersonatorId)
+	}
+	if filter.From != "" {
+		add("date_created>=?", filter.From)
+	}
+	if filter.Until != "" {
+		add("date_created<?", filter.Until)
+	}
+	if filter.BeforeId != 0 {
+		add("id<?", filter.BeforeId)
+	}
+
+	var query strings.Builder
+	query.WriteString(querySelectAuditLog)
+	if len(conditions) > 0 {
+		query.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	query.WriteString(" ORDER BY id " + order)
+	if limit > 0 {
+		query.WriteString(" LIMIT ?")
+		args = append(args, limit)
+	}
+	query.WriteString(";")
+	return query.String(), args
+}
+
+func scanAuditEntry(row rowScanner) (*audit.Entry, error) {
+	var entry audit.Entry
+	var changes, details sql.NullString
+	if err := row.Scan(&entry.Id, &entry.Action, &entry.Outcome, &entry.ActorId, &entry.TargetUserId, &entry.ImpersonatorId, &entry.RequestId,
+		&changes, &details, dbDateTime{&entry.DateCreated}); err != nil {
+		return nil, err
+	}
+	if changes.Valid && changes.String != "" {
+		if err := json.Unmarshal([]byte(changes.String), &entry.Changes); err != nil {
+			return nil, err
+		}
+	}
+	if details.Valid && details.String != "" {
+		if err := json.Unmarshal([]byte(details.String), &entry.Details); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+// nullableJSON encodes value, or returns NULL when present is false.
+func nullableJSON(present bool, value interface{}) (interface{}, error) {
+	if !present {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/audit"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var auditColumns = []string{"id", "action", "outcome", "actor_id", "target_user_id", "impersonator_id", "request_id", "changes", "details", "date_created"}
+
+func TestAuditQueryOnlyFiltersFieldsSet(t *testing.T) {
+	query, args := auditQuery(audit.Filter{}, "ASC", 0)
+
+	assert.Equal(t, querySelectAuditLog+" ORDER BY id ASC;", query)
+	assert.Empty(t, args)
+
+	query, args = auditQuery(audit.Filter{Action: audit.ActionLoginFailed, TargetUserId: 7, ImpersonatorId: 1, From: "2022-09-06 00:00:00", BeforeId: 50}, "DESC", 20)
+
+	assert.Equal(t, querySelectAuditLog+" WHERE action=? AND target_user_id=? AND impersonator_id=? AND date_created>=? AND id<? ORDER BY id DESC LIMIT ?;", query)
+	assert.Equal(t, []interface{}{audit.ActionLoginFailed, int64(7), int64(1), "2022-09-06 00:00:00", int64(50), 20}, args)
+}
+
+func TestAppendAuditEntryOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	entry := audit.Entry{
+		Action:       audit.ActionProfileUpdated,
+		Outcome:      audit.OutcomeSuccess,
+		ActorId:      1,
+		TargetUserId: 7,
+		RequestId:    "req-1",
+		Changes:      map[string]audit.Change{"name": {Before: "John", After: "Johnny"}},
+		DateCreated:  "2022-09-06 10:00:00",
+	}
+
+	prep := mock.ExpectPrepare(queryInsertAuditEntry)
+	prep.ExpectExec().WithArgs(entry.Action, entry.Outcome, int64(1), int64(7), int64(0), "req-1", `{"name":{"before":"John","after":"Johnny"}}`, nil, entry.DateCreated).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+
+	err := AuditRepository.Append(context.Background(), &entry)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), entry.Id)
+}
+
+func TestFindAuditEntriesOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(auditColumns).
+		AddRow(12, audit.ActionLoginFailed, audit.OutcomeFailure, 0, 7, 0, "req-2", nil, `{"reason":"invalid_credentials"}`, "2022-09-06 10:00:00").
+		AddRow(11, audit.ActionProfileUpdated, audit.OutcomeSuccess, 7, 7, 1, "", `{"name":{"before":"John","after":"Johnny"}}`, nil, "2022-09-06 09:00:00")
+
+	query, _ := auditQuery(audit.Filter{TargetUserId: 7}, "DESC", 2)
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().WithArgs(int64(7), 2).WillReturnRows(rows)
+
+	result, err := AuditRepository.Find(context.Background(), audit.Filter{TargetUserId: 7, Limit: 2})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, map[string]string{"reason": "invalid_credentials"}, result[0].Details)
+	assert.Nil(t, result[0].Changes)
+	assert.Equal(t, audit.Change{Before: "John", After: "Johnny"}, result[1].Changes["name"])
+	assert.Equal(t, int64(1), result[1].ImpersonatorId)
+}
+
+func TestWalkAuditEntriesStopsOnCallbackError(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(auditColumns).
+		AddRow(1, audit.ActionUserCreated, audit.OutcomeSuccess, 0, 1, 0, "", nil, nil, "2022-09-06 10:00:00").
+		AddRow(2, audit.ActionUserCreated, audit.OutcomeSuccess, 0, 2, 0, "", nil, nil, "2022-09-06 10:00:00")
+
+	query, _ := auditQuery(audit.Filter{}, "ASC", 0)
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().WillReturnRows(rows)
+
+	walked := 0
+	err := AuditRepository.Walk(context.Background(), audit.Filter{}, func(audit.Entry) error {
+		walked++
+		return context.Canceled
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, 1, walked)
+}
@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	usersCacheKeyPrefix = "users:"
+)
+
+// cachedUsersRepository reads users through a cache, remembering missing ids as well
+// for a shorter while. Every write drops the entry of the user once it is done, but a
+// read from a lagging replica right after can cache the row as it was before the
+// write: an entry may be as old as the TTL plus the replica lag. Reads that can not
+// afford that go to the primary, which skips the lookup. A failing cache is logged
+// and skipped.
+type cachedUsersRepository struct {
+	userRepositoryInterface
+	store       cache.Store
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// NewCachedUsersRepository caches the lookups by id of next in store. Logins are not
+// cached.
+func NewCachedUsersRepository(next userRepositoryInterface, store cache.Store, ttl time.Duration, negativeTTL time.Duration) userRepositoryInterface {
+	return &cachedUsersRepository{userRepositoryInterface: next, store: store, ttl: ttl, negativeTTL: negativeTTL}
+}
+
+// Get skips the cache lookup when ctx asks to read from the primary, since the
+// request may have just changed the user, but still refreshes the entry.
+func (r *cachedUsersRepository) Get(ctx context.Context, id int64) (*users.User, rest_errors.RestErr) {
+	key := usersCacheKey(id)
+	if !users_db.ReadsFromPrimary(ctx) {
+		if user, restErr, found := r.lookup(ctx, key); found {
+			return user, restErr
+		}
+	}
+
+	user, restErr := r.userRepositoryInterface.Get(ctx, id)
+	switch {
+	case restErr == nil:
+		if value, err := json.Marshal(user); err == nil {
+			r.set(ctx, key, value, r.ttl)
+		}
+	case restErr.Status() == http.StatusNotFound && r.negativeTTL > 0:
+		r.set(ctx, key, []byte{}, r.negativeTTL)
+	}
+	return user, restErr
+}
+
+// Save drops the entry as well, in case the new id was remembered as missing.
+func (r *cachedUsersRepository) Save(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	restErr := r.userRepositoryInterface.Save(ctx, user, eventTypes...)
+	if restErr == nil {
+		r.invalidate(user.Id)
+	}
+	return restErr
+}
+
+// Update, UpdatePassword, UpdateAccess and Delete drop the entry even when they fail, since a
+// timeout does not tell whether the change was committed.
+func (r *cachedUsersRepository) Update(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	defer r.invalidate(user.Id)
+	return r.userRepositoryInterface.Update(ctx, user, eventTypes...)
+}
+
+func (r *cachedUsersRepository) UpdatePassword(ctx context.Context, user *users.User, password string, eventTypes ...string) rest_errors.RestErr {
+	defer r.invalidate(user.Id)
+	return r.userRepositoryInterface.UpdatePassword(ctx, user, password, eventTypes...)
+}
+
+func (r *cachedUsersRepository) UpdateAccess(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	defer r.invalidate(user.Id)
+	return r.userRepositoryInterface.UpdateAccess(ctx, user, eventTypes...)
+}
+
+func (r *cachedUsersRepository) Delete(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	defer r.invalidate(user.Id)
+	return r.userRepositoryInterface.Delete(ctx, user, eventTypes...)
+}
+
+// inTransaction wraps next, bound to a transaction, so the users it writes are
+// recorded instead of dropped right away: a concurrent read could otherwise cache
+// them again before the commit. The returned func drops them and must be called once
+// the transaction is over.
+func (r *cachedUsersRepository) inTransaction(next userRepositoryInterface) (userRepositoryInterface, func()) {
+	written := &writtenUsers{userRepositoryInterface: next}
+	return written, func() {
+		for _, id := range written.ids() {
+			r.invalidate(id)
+		}
+	}
+}
+
+func (r *cachedUsersRepository) lookup(ctx context.Context, key string) (*users.User, rest_errors.RestErr, bool) {
+	value, found, err := r.store.Get(ctx, key)
+	if err != nil {
+		logging.Error(ctx, "error when trying to read user from cache", err)
+		return nil, nil, false
+	}
+	if !found {
+		return nil, nil, false
+	}
+	if len(value) == 0 {
+		return nil, rest_errors.NewNotFoundError("user not found"), true
+	}
+
+	var user users.User
+	if err := json.Unmarshal(value, &user); err != nil {
+		logging.Error(ctx, "error when trying to decode cached user", err)
+		return nil, nil, false
+	}
+	return &user, nil, true
+}
+
+func (r *cachedUsersRepository) set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if err := r.store.Set(ctx, key, value, ttl); err != nil {
+		logging.Error(ctx, "error when trying to cache user", err)
+	}
+}
+
+// invalidate does not run with the context of the write, which may be over by the
+// time it returns: a lost invalidation leaves the entry stale until it expires. The
+// store bounds the call with a timeout of its own.
+func (r *cachedUsersRepository) invalidate(id int64) {
+	if err := r.store.Delete(context.Background(), usersCacheKey(id)); err != nil {
+		logger.Error("error when trying to drop cached user "+strconv.FormatInt(id, 10), err)
+	}
+}
+
+func usersCacheKey(id int64) string {
+	return usersCacheKeyPrefix + strconv.FormatInt(id, 10)
+}
+
+// writtenUsers records the ids of the users written through it.
+type writtenUsers struct {
+	userRepositoryInterface
+	mutex   sync.Mutex
+	written []int64
+}
+
+func (w *writtenUsers) record(id int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.written = append(w.written, id)
+}
+
+func (w *writtenUsers) ids() []int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]int64(nil), w.written...)
+}
+
+func (w *writtenUsers) Save(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	defer func() { w.record(user.Id) }()
+	return w.userRepositoryInterface.Save(ctx, user, eventTypes...)
+}
+
+func (w *writtenUsers) Update(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	w.record(user.Id)
+	return w.userRepositoryInterface.Update(ctx, user, eventTypes...)
+}
+
+func (w *writtenUsers) UpdatePassword(ctx context.Context, user *users.User, password string, eventTypes ...string) rest_errors.RestErr {
+	w.record(user.Id)
+	return w.userRepositoryInterface.UpdatePassword(ctx, user, password, eventTypes...)
+}
+
+func (w *writtenUsers) UpdateAccess(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	w.record(user.Id)
+	return w.userRepositoryInterface.UpdateAccess(ctx, user, eventTypes...)
+}
+
+func (w *writtenUsers) Delete(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	w.record(user.Id)
+	return w.userRepositoryInterface.Delete(ctx, user, eventTypes...)
+}
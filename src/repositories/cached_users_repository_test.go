@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/users"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingUsersRepository counts the lookups reaching the repository it wraps.
+type countingUsersRepository struct {
+	userRepositoryInterface
+	gets int
+}
+
+func (r *countingUsersRepository) Get(ctx context.Context, id int64) (*users.User, rest_errors.RestErr) {
+	r.gets++
+	return r.userRepositoryInterface.Get(ctx, id)
+}
+
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Delete(context.Context, ...string) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Close() error {
+	return nil
+}
+
+func newCachedUsers(store cache.Store) (*cachedUsersRepository, *countingUsersRepository) {
+	next := &countingUsersRepository{userRepositoryInterface: NewMemoryUsersRepository()}
+	return NewCachedUsersRepository(next, store, time.Minute, 10*time.Second).(*cachedUsersRepository), next
+}
+
+func saveJohn(t *testing.T, repository userRepositoryInterface) users.User {
+	user := users.User{Name: "John", Email: "john@mail.com", Status: users.StatusActive, Password: "hash"}
+	if err := repository.Save(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCachedUsersRepositoryContract(t *testing.T) {
+	testUsersRepositoryContract(t, func(t *testing.T) userRepositoryInterface {
+		repository, _ := newCachedUsers(cache.NewMemoryStore(100))
+		return repository
+	})
+}
+
+func TestCachedUsersRepositoryGetReadsThroughCache(t *testing.T) {
+	repository, next := newCachedUsers(cache.NewMemoryStore(100))
+	user := saveJohn(t, repository)
+
+	first, err := repository.Get(context.Background(), user.Id)
+	assert.Nil(t, err)
+	second, err := repository.Get(context.Background(), user.Id)
+	assert.Nil(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, next.gets)
+}
+
+func TestCachedUsersRepositoryRemembersMissingIds(t *testing.T) {
+	repository, next := newCachedUsers(cache.NewMemoryStore(100))
+
+	for i := 0; i < 2; i++ {
+		_, err := repository.Get(context.Background(), 1)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.Status())
+		assert.Equal(t, "user not found", err.Message())
+	}
+	assert.Equal(t, 1, next.gets)
+
+	user := saveJohn(t, repository)
+	found, err := repository.Get(context.Background(), user.Id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "John", found.Name)
+}
+
+func TestCachedUsersRepositoryInvalidatesOnWrites(t *testing.T) {
+	repository, next := newCachedUsers(cache.NewMemoryStore(100))
+	ctx := context.Background()
+	user := saveJohn(t, repository)
+	repository.Get(ctx, user.Id)
+
+	user.Name = "Johnny"
+	assert.Nil(t, repository.Update(ctx, &user))
+	found, _ := repository.Get(ctx, user.Id)
+	assert.Equal(t, "Johnny", found.Name)
+
+	assert.Nil(t, repository.UpdatePassword(ctx, &user, "new-hash"))
+	repository.Get(ctx, user.Id)
+
+	assert.Nil(t, repository.Delete(ctx, &user))
+	_, err := repository.Get(ctx, user.Id)
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+
+	assert.Equal(t, 4, next.gets)
+}
+
+func TestCachedUsersRepositoryPrimaryReadsSkipCache(t *testing.T) {
+	repository, next := newCachedUsers(cache.NewMemoryStore(100))
+	user := saveJohn(t, repository)
+	repository.Get(context.Background(), user.Id)
+
+	_, err := repository.Get(users_db.WithPrimary(context.Background()), user.Id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, next.gets)
+}
+
+func TestCachedUsersRepositoryFailingStore(t *testing.T) {
+	repository, next := newCachedUsers(failingStore{})
+	user := saveJohn(t, repository)
+
+	found, err := repository.Get(context.Background(), user.Id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "John", found.Name)
+	assert.Equal(t, 1, next.gets)
+	assert.Nil(t, repository.Delete(context.Background(), &user))
+}
+
+func TestCachedUsersRepositoryInvalidatesAfterTransaction(t *testing.T) {
+	store := cache.NewMemoryStore(100)
+	repository, _ := newCachedUsers(store)
+	ctx := context.Background()
+	user := saveJohn(t, repository)
+	repository.Get(ctx, user.Id)
+
+	bound, invalidate := repository.inTransaction(repository.userRepositoryInterface)
+	assert.Nil(t, bound.Delete(ctx, &user))
+
+	_, cached, _ := store.Get(ctx, usersCacheKey(user.Id))
+	assert.True(t, cached)
+
+	invalidate()
+	_, cached, _ = store.Get(ctx, usersCacheKey(user.Id))
+	assert.False(t, cached)
+}
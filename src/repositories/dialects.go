@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/utils/date_utils"
+)
+
+// insertedId runs an INSERT prepared from users_db.Current.ReturningId and returns
+// the id generated for the row.
+func insertedId(ctx context.Context, stmt *sql.Stmt, args ...interface{}) (int64, error) {
+	if users_db.Current.Returning {
+		var id int64
+		err := stmt.QueryRowContext(ctx, args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// dbDateTime scans a date column into the layout MySQL returns it in, whatever the
+// driver hands over: text from MySQL and SQLite, time.Time from PostgreSQL.
+type dbDateTime struct {
+	value *string
+}
+
+func (d dbDateTime) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case time.Time:
+		*d.value = date_utils.FormatDB(value)
+	case []byte:
+		*d.value = string(value)
+	case string:
+		*d.value = value
+	case nil:
+		*d.value = ""
+	default:
+		return fmt.Errorf("can not scan %T into a date", src)
+	}
+	return nil
+}
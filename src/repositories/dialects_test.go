@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/users"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func withPostgreSQL(t *testing.T) sqlmock.Sqlmock {
+	db, mock := NewMock()
+	previousClient, previousDialect := users_db.Client, users_db.Current
+	users_db.Client, users_db.Current = db, users_db.PostgreSQL
+	t.Cleanup(func() {
+		users_db.Client, users_db.Current = previousClient, previousDialect
+		db.Close()
+	})
+	return mock
+}
+
+func TestSaveOnPostgreSQLReturnsId(t *testing.T) {
+	mock := withPostgreSQL(t)
+
+	user := users.User{Name: "John", Email: "john@mail.com", Status: users.StatusActive, Password: "hash", DateCreated: "2022-01-01 10:00:00"}
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "INSERT INTO users(name, email, telegram_user, time_zone, status, role, password, date_created) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;").
+		ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(667))
+	expectTxPrepare(mock, "INSERT INTO outbox(event_id, event_type, user_id, payload, date_created) VALUES($1, $2, $3, $4, $5);").
+		ExpectExec().WithArgs(sqlmock.AnyArg(), events.TypeUserCreated, int64(667), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := UsersRepository.Save(context.Background(), &user, events.TypeUserCreated)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(667), user.Id)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOnPostgreSQLFormatsDates(t *testing.T) {
+	mock := withPostgreSQL(t)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "time_zone", "status", "role", "date_created"}).
+		AddRow(1, "John", "john@mail.com", "", "UTC", "active", "user", time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC))
+	mock.ExpectPrepare("SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE id=$1;").
+		ExpectQuery().WithArgs(1).WillReturnRows(rows)
+
+	user, err := UsersRepository.Get(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "2022-01-01 10:00:00", user.DateCreated)
+}
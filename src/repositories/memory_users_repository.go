@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/users"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+// MemoryUsersRepository keeps users in memory, for tests and demos that should not
+// need a database. It follows the rules of the SQL backends, checked by the shared
+// contract tests: emails are unique and only active users can log in. The events the
+// SQL backends write to the outbox are kept in Events instead.
+type MemoryUsersRepository struct {
+	mutex  sync.RWMutex
+	lastId int64
+	users  map[int64]users.User
+	emails map[string]int64
+	events []events.Event
+}
+
+func NewMemoryUsersRepository() *MemoryUsersRepository {
+	return &MemoryUsersRepository{users: map[int64]users.User{}, emails: map[string]int64{}}
+}
+
+func (r *MemoryUsersRepository) Save(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	if err := ctx.Err(); err != nil {
+		return databaseError(ctx, err, "error saving user")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, taken := r.emails[user.Email]; taken {
+		return rest_errors.NewBadRequestError("email already registered")
+	}
+
+	user.Id = r.lastId + 1
+	if err := r.appendEvents(user, eventTypes, "error saving user"); err != nil {
+		user.Id = 0
+		return err
+	}
+	r.lastId = user.Id
+	r.users[user.Id] = *user
+	r.emails[user.Email] = user.Id
+	return nil
+}
+
+func (r *MemoryUsersRepository) Get(ctx context.Context, id int64) (*users.User, rest_errors.RestErr) {
+	if err := ctx.Err(); err != nil {
+		return nil, databaseError(ctx, err, "error fetching user")
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	stored, ok := r.users[id]
+	if !ok {
+		return nil, rest_errors.NewNotFoundError("user not found")
+	}
+	// The SQL backends do not read the password back either.
+	stored.Password = ""
+	return &stored, nil
+}
+
+// Update changes the profile fields of the user, leaving status, role and password as
+// they are. Like an UPDATE matching no row, it does nothing for an unknown user.
+func (r *MemoryUsersRepository) Update(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	if err := ctx.Err(); err != nil {
+		return databaseError(ctx, err, "error updating user")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if owner, taken := r.emails[user.Email]; taken && owner != user.Id {
+		return rest_errors.NewBadRequestError("email already registered")
+	}
+	if err := r.appendEvents(user, eventTypes, "error updating user"); err != nil {
+		return err
+	}
+
+	if stored, ok := r.users[user.Id]; ok {
+		delete(r.emails, stored.Email)
+		stored.Name = user.Name
+		stored.Email = user.Email
+		stored.TelegramUser = user.TelegramUser
+		stored.TimeZone = user.TimeZone
+		r.users[user.Id] = stored
+		r.emails[stored.Email] = stored.Id
+	}
+	return nil
+}
+
+func (r *MemoryUsersRepository) UpdatePassword(ctx context.Context, user *users.User, password string, eventTypes ...string) rest_errors.RestErr {
+	if err := ctx.Err(); err != nil {
+		return databaseError(ctx, err, "error updating user password")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.appendEvents(user, eventTypes, "error updating user password"); err != nil {
+		return err
+	}
+
+	if stored, ok := r.users[user.Id]; ok {
+		stored.Password = password
+		r.users[user.Id] = stored
+	}
+	return nil
+}
+
+func (r *MemoryUsersRepository) UpdateAccess(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	if err := ctx.Err(); err != nil {
+		return databaseError(ctx, err, "error updating user")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.appendEvents(user, eventTypes, "error updating user"); err != nil {
+		return err
+	}
+
+	if stored, ok := r.users[user.Id]; ok {
+		stored.Status = user.Status
+		stored.Role = user.Role
+		r.users[user.Id] = stored
+	}
+	return nil
+}
+
+func (r *MemoryUsersRepository) Delete(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	if err := ctx.Err(); err != nil {
+		return databaseError(ctx, err, "error deleting user")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.appendEvents(user, eventTypes, "error deleting user"); err != nil {
+		return err
+	}
+
+	if stored, ok := r.users[user.Id]; ok {
+		delete(r.emails, stored.Email)
+		delete(r.users, user.Id)
+	}
+	return nil
+}
+
+func (r *MemoryUsersRepository) FindByEmailAndPassword(ctx context.Context, login users.LoginRequest) (*users.User, rest_errors.RestErr) {
+	if err := ctx.Err(); err != nil {
+		return nil, databaseError(ctx, err, "error when trying to find user")
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	stored, ok := r.users[r.emails[login.Email]]
+	if !ok || stored.Password != login.Password || stored.Status != users.StatusActive {
+		return nil, rest_errors.NewNotFoundError("invalid user credentials")
+	}
+	stored.Password = ""
+	stored.DateCreated = ""
+	return &stored, nil
+}
+
+func (r *MemoryUsersRepository) Search(ctx context.Context, filter users.Filter) (users.Users, rest_errors.RestErr) {
+	if err := ctx.Err(); err != nil {
+		return nil, databaseError(ctx, err, "error searching users")
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	result := make(users.Users, 0)
+	for id := r.lastId; id > 0 && len(result) < filter.Limit; id-- {
+		stored, ok := r.users[id]
+		if !ok || (filter.BeforeId != 0 && id >= filter.BeforeId) ||
+			(filter.Status != "" && stored.Status != filter.Status) ||
+			(filter.Role != "" && stored.Role != filter.Role) ||
+			(filter.Email != "" && stored.Email != filter.Email) {
+			continue
+		}
+		stored.Password = ""
+		result = append(result, stored)
+	}
+	return result, nil
+}
+
+// Events returns the events appended so far, oldest first.
+func (r *MemoryUsersRepository) Events() []events.Event {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+// appendEvents builds the events of the given types before anything is changed, so a
+// failure leaves the repository as it was, like a rolled back transaction.
+func (r *MemoryUsersRepository) appendEvents(user *users.User, eventTypes []string, message string) rest_errors.RestErr {
+	built := make([]events.Event, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		event, err := events.New(eventType, user.Id, user.Marshall(false))
+		if err != nil {
+			logger.Error("error when trying to build event "+eventType, err)
+			return rest_errors.NewInternalServerError(message, errors.New("database error"))
+		}
+		built = append(built, *event)
+	}
+	r.events = append(r.events, built...)
+	return nil
+}
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"tokenalert_user-api/src/metrics"
+	"tokenalert_user-api/src/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	queryDuration = metrics.NewHistogram("repository_query_duration_seconds",
+		"Time taken by repository methods, by operation.", metrics.LatencyBuckets, "operation")
+)
+
+// observeQuery times a repository method, from the call to the deferred function it
+// returns. Operations are named <repository>.<method>, as in
+// database.operation_timeouts:
+//
+//	defer observeQuery("users.get")()
+func observeQuery(operation string) func() {
+	start := time.Now()
+	return func() {
+		queryDuration.Observe(time.Since(start).Seconds(), operation)
+	}
+}
+
+// startQuery is observeQuery for methods given a context, which also get a client
+// span, child of the request's. databaseError marks it failed.
+//
+//	ctx, end := startQuery(ctx, "users.get")
+//	defer end()
+func startQuery(ctx context.Context, operation string) (context.Context, func()) {
+	observe := observeQuery(operation)
+	ctx, span := tracing.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.operation", operation)))
+	return ctx, func() {
+		span.End()
+		observe()
+	}
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRepositoryMethodsObserveQueryDuration(t *testing.T) {
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+	usersGet, alertRulesGet := queryDuration.Count("users.get"), queryDuration.Count("alert_rules.get")
+
+	mock.ExpectPrepare(queryGetUser).ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+	UsersRepository.Get(context.Background(), 1)
+	mock.ExpectPrepare(queryGetAlertRule).WillReturnError(errors.New("prepare failed"))
+	AlertRulesRepository.Get(context.Background(), 1)
+
+	assert.Equal(t, usersGet+1, queryDuration.Count("users.get"))
+	assert.Equal(t, alertRulesGet+1, queryDuration.Count("alert_rules.get"))
+}
+
+func TestUsersRepositoryRecordsFailedQueriesOnSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectPrepare(queryGetUser).WillReturnError(errors.New("connection refused"))
+	ctx, request := tracing.Start(context.Background(), "GET /users/:user_id")
+	UsersRepository.Get(ctx, 1)
+	request.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "users.get", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, request.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryFindNotificationChannels   = "SELECT type, target, enabled, verified, is_primary FROM notification_channels WHERE user_id=? ORDER BY type;"
+	queryDeleteNotificationChannels = "DELETE FROM notification_channels WHERE user_id=?;"
+	queryInsertNotificationChannel  = "INSERT INTO notification_channels(user_id, type, target, enabled, verified, is_primary) VALUES(?, ?, ?, ?, ?, ?);"
+	queryVerifyNotificationChannel  = "UPDATE notification_channels SET verified=? WHERE user_id=? AND type=?;"
+)
+
+var (
+	NotificationSettingsRepository notificationSettingsRepositoryInterface = &notificationSettingsRepository{}
+)
+
+type notificationSettingsRepository struct {
+	session
+}
+
+type notificationSettingsRepositoryInterface interface {
+	GetByUserId(context.Context, int64) (*notifications.Settings, rest_errors.RestErr)
+	Save(context.Context, *notifications.Settings) rest_errors.RestErr
+	MarkVerified(context.Context, int64, string) rest_errors.RestErr
+	DeleteByUserId(context.Context, int64) rest_errors.RestErr
+}
+
+func (r *notificationSettingsRepository) GetByUserId(ctx context.Context, userId int64) (*notifications.Settings, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "notification_settings.get_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "notification_settings.get_by_user_id")
+	defer cancel()
+
+	settings := notifications.Settings{UserId: userId, Channels: make(notifications.Channels, 0)}
+	err := r.run(ctx, queryFindNotificationChannels, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, userId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var channel notifications.Channel
+			var isPrimary bool
+			if err := rows.Scan(&channel.Type, &channel.Target, &channel.Enabled, &channel.Verified, &isPrimary); err != nil {
+				return err
+			}
+			if isPrimary {
+				settings.PrimaryChannel = channel.Type
+			}
+			settings.Channels = append(settings.Channels, channel)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare find notification channels statement", err)
+			return nil, databaseError(ctx, err, "error fetching notification settings")
+		}
+		logging.Error(ctx, "error when trying to find notification channels", err)
+		return nil, databaseError(ctx, err, "error fetching notification settings")
+	}
+	return &settings, nil
+}
+
+// Save replaces every channel stored for the user with the ones in settings.
+func (r *notificationSettingsRepository) Save(ctx context.Context, settings *notifications.Settings) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "notification_settings.save")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "notification_settings.save")
+	defer cancel()
+
+	err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+		if err := deleteNotificationChannels(ctx, tx, settings.UserId); err != nil {
+			return err
+		}
+		if len(settings.Channels) == 0 {
+			return nil
+		}
+
+		err := runIn(ctx, tx, queryInsertNotificationChannel, func(insertStmt *sql.Stmt) error {
+			for _, channel := range settings.Channels {
+				if _, err := insertStmt.ExecContext(ctx, settings.UserId, channel.Type, channel.Target, channel.Enabled, channel.Verified,
+					channel.Type == settings.PrimaryChannel); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if errors.As(err, &prepareError{}) {
+				logging.Error(ctx, "error when trying to prepare save notification channel statement", err)
+				return err
+			}
+			logging.Error(ctx, "error when trying to save notification channel", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return databaseError(ctx, err, "error saving notification settings")
+	}
+	return nil
+}
+
+func (r *notificationSettingsRepository) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "notification_settings.delete_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "notification_settings.delete_by_user_id")
+	defer cancel()
+
+	if err := deleteNotificationChannels(ctx, r.tx, userId); err != nil {
+		return databaseError(ctx, err, "error deleting notification settings")
+	}
+	return nil
+}
+
+// deleteNotificationChannels runs in tx, or on its own when tx is nil.
+func deleteNotificationChannels(ctx context.Context, tx *sql.Tx, userId int64) error {
+	err := runIn(ctx, tx, queryDeleteNotificationChannels, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, userId)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare delete notification channels statement", err)
+			return err
+		}
+		logging.Error(ctx, "error when trying to delete notification channels", err)
+		return err
+	}
+	return nil
+}
+
+func (r *notificationSettingsRepository) MarkVerified(ctx context.Context, userId int64, channelType string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "notification_settings.mark_verified")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "notification_settings.mark_verified")
+	defer cancel()
+
+	err := r.run(ctx, queryVerifyNotificationChannel, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, true, userId, channelType)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare verify notification channel statement", err)
+			return databaseError(ctx, err, "error verifying notification channel")
+		}
+		logging.Error(ctx, "error when trying to verify notification channel", err)
+		return databaseError(ctx, err, "error verifying notification channel")
+	}
+	return nil
+}
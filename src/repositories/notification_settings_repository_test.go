@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/notifications"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNotificationSettingsOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"type", "target", "enabled", "verified", "is_primary"}).
+		AddRow("email", "john@mail.com", true, true, false).
+		AddRow("telegram", "@john", true, false, true)
+
+	prep := mock.ExpectPrepare(queryFindNotificationChannels)
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(rows)
+
+	settings, err := NotificationSettingsRepository.GetByUserId(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), settings.UserId)
+	assert.Equal(t, notifications.ChannelTelegram, settings.PrimaryChannel)
+	assert.Equal(t, 2, len(settings.Channels))
+	assert.True(t, settings.Channels.Find(notifications.ChannelEmail).Verified)
+}
+
+func TestGetNotificationSettingsExecutionFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryFindNotificationChannels)
+	prep.ExpectQuery().WithArgs(1).WillReturnError(errors.New("database error"))
+
+	_, err := NotificationSettingsRepository.GetByUserId(context.Background(), 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error fetching notification settings", err.Message())
+}
+
+func TestSaveNotificationSettingsOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	settings := notifications.Settings{
+		UserId:         1,
+		PrimaryChannel: notifications.ChannelEmail,
+		Channels: notifications.Channels{
+			{Type: notifications.ChannelEmail, Target: "john@mail.com", Enabled: true},
+			{Type: notifications.ChannelWebhook, Target: "https://example.com/hook", Enabled: false},
+		},
+	}
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryDeleteNotificationChannels).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	prep := expectTxPrepare(mock, queryInsertNotificationChannel)
+	prep.ExpectExec().WithArgs(1, "email", "john@mail.com", true, false, true).WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().WithArgs(1, "webhook", "https://example.com/hook", false, false, false).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err := NotificationSettingsRepository.Save(context.Background(), &settings)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveNotificationSettingsInsertFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	settings := notifications.Settings{
+		UserId:   1,
+		Channels: notifications.Channels{{Type: notifications.ChannelEmail, Target: "john@mail.com"}},
+	}
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryDeleteNotificationChannels).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTxPrepare(mock, queryInsertNotificationChannel).ExpectExec().WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	err := NotificationSettingsRepository.Save(context.Background(), &settings)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error saving notification settings", err.Message())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkNotificationChannelVerifiedOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectPrepare(queryVerifyNotificationChannel).ExpectExec().WithArgs(true, 1, "email").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := NotificationSettingsRepository.MarkVerified(context.Background(), 1, notifications.ChannelEmail)
+
+	assert.Nil(t, err)
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryInsertOutboxEvent         = "INSERT INTO outbox(event_id, event_type, user_id, payload, date_created) VALUES(?, ?, ?, ?, ?);"
+	queryFindPendingOutboxEvents   = "SELECT id, payload, claimed_until FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT ?;"
+	queryClaimOutboxEvent          = "UPDATE outbox SET claimed_until=? WHERE id=?;"
+	queryReleaseOutboxEvent        = "UPDATE outbox SET claimed_until=NULL WHERE id=?;"
+	queryMarkOutboxEventDispatched = "UPDATE outbox SET dispatched_at=?, claimed_until=NULL WHERE id=?;"
+)
+
+var (
+	OutboxRepository outboxRepositoryInterface = &outboxRepository{}
+)
+
+type outboxRepository struct {
+	session
+}
+
+type outboxRepositoryInterface interface {
+	Append(context.Context, events.Event) rest_errors.RestErr
+	ProcessPending(context.Context, int, time.Duration, func(events.Event) error) (int, rest_errors.RestErr)
+}
+
+// insertOutboxEvent runs in tx, or on its own when tx is nil.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return runIn(ctx, tx, queryInsertOutboxEvent, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, event.Id, event.Type, event.UserId, string(payload), date_utils.GetNowDBFormat())
+		return err
+	})
+}
+
+// appendUserEvents stores one event per type, carrying the public view of the user,
+// using the transaction of the mutation that produced them.
+func appendUserEvents(ctx context.Context, tx *sql.Tx, user *users.User, eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		event, err := events.New(eventType, user.Id, user.Marshall(false))
+		if err != nil {
+			logging.Error(ctx, "error when trying to build event "+eventType, err)
+			return err
+		}
+		if err := insertOutboxEvent(ctx, tx, *event); err != nil {
+			logging.Error(ctx, "error when trying to append event to the outbox", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *outboxRepository) Append(ctx context.Context, event events.Event) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "outbox.append")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "outbox.append")
+	defer cancel()
+
+	if err := insertOutboxEvent(ctx, r.tx, event); err != nil {
+		logging.Error(ctx, "error when trying to append event to the outbox", err)
+		return databaseError(ctx, err, "error saving event")
+	}
+	return nil
+}
+
+// ProcessPending claims up to limit undispatched events in insertion order and hands
+// them to handler one at a time, stopping at the first failure so later events are
+// never published ahead of an earlier one. The claim is committed before handler runs,
+// so no lock is held while events are published; it lasts for lease, and while it does
+// other relays leave the outbox alone. Every handled event is marked as dispatched
+// right away. A relay that dies before that leaves its claim to expire and the events
+// are published again, giving at-least-once delivery.
+func (r *outboxRepository) ProcessPending(ctx context.Context, limit int, lease time.Duration, handler func(events.Event) error) (int, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "outbox.process_pending")
+	defer end()
+
+	claimed, err := r.claimPending(ctx, limit, lease)
+	if err != nil {
+		logging.Error(ctx, "error when trying to claim pending outbox events", err)
+		return 0, databaseError(ctx, err, "error processing outbox")
+	}
+
+	for index, row := range claimed {
+		if handlerErr := handler(row.event); handlerErr != nil {
+			logging.Error(ctx, "error when trying to publish outbox event "+row.event.Id, handlerErr)
+			r.release(ctx, claimed[index:])
+			return index, nil
+		}
+		if err := r.markDispatched(ctx, row.id); err != nil {
+			logging.Error(ctx, "error when trying to mark outbox event "+row.event.Id+" as dispatched", err)
+			return index, databaseError(ctx, err, "error processing outbox")
+		}
+	}
+	return len(claimed), nil
+}
+
+type outboxRow struct {
+	id           int64
+	event        events.Event
+	claimedUntil string
+}
+
+// claimPending claims the oldest pending events unless another relay holds a claim
+// that has not expired yet. Claims are always taken from the oldest event on, so
+// checking that one is enough.
+func (r *outboxRepository) claimPending(ctx context.Context, limit int, lease time.Duration) ([]outboxRow, error) {
+	ctx, cancel := users_db.WithTimeout(ctx, "outbox.process_pending")
+	defer cancel()
+
+	claimed := make([]outboxRow, 0)
+	err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+		pending, err := findPendingOutboxEvents(ctx, tx, limit)
+		if err != nil {
+			return err
+		}
+		now := date_utils.GetNow()
+		if len(pending) == 0 || pending[0].claimedUntil > date_utils.FormatDB(now) {
+			return nil
+		}
+
+		until := date_utils.FormatDB(now.Add(lease))
+		err = runIn(ctx, tx, queryClaimOutboxEvent, func(stmt *sql.Stmt) error {
+			for _, row := range pending {
+				if _, err := stmt.ExecContext(ctx, until, row.id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		claimed = pending
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *outboxRepository) markDispatched(ctx context.Context, id int64) error {
+	ctx, cancel := users_db.WithTimeout(ctx, "outbox.process_pending")
+	defer cancel()
+
+	return r.run(ctx, queryMarkOutboxEventDispatched, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, date_utils.GetNowDBFormat(), id)
+		return err
+	})
+}
+
+// release gives up the claim on events that were not published, so the next batch
+// does not have to wait for it to expire.
+func (r *outboxRepository) release(ctx context.Context, rows []outboxRow) {
+	ctx, cancel := users_db.WithTimeout(ctx, "outbox.process_pending")
+	defer cancel()
+
+	err := r.run(ctx, queryReleaseOutboxEvent, func(stmt *sql.Stmt) error {
+		for _, row := range rows {
+			if _, err := stmt.ExecContext(ctx, row.id); err != nil {
+				logging.Error(ctx, "error when trying to release outbox event "+row.event.Id, err)
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.As(err, &prepareError{}) {
+		logging.Error(ctx, "error when trying to prepare release outbox event statement", err)
+	}
+}
+
+func findPendingOutboxEvents(ctx context.Context, tx *sql.Tx, limit int) ([]outboxRow, error) {
+	pending := make([]outboxRow, 0)
+	err := runIn(ctx, tx, users_db.Current.ForUpdate(queryFindPendingOutboxEvents), func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row outboxRow
+			var payload string
+			if err := rows.Scan(&row.id, &payload, dbDateTime{&row.claimedUntil}); err != nil {
+				return err
+			}
+			if err := json.Unmarshal([]byte(payload), &row.event); err != nil {
+				return err
+			}
+			pending = append(pending, row)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
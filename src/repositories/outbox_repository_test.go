@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/events"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var outboxColumns = []string{"id", "payload", "claimed_until"}
+
+func TestAppendOutboxEventOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	event := events.Event{Id: "evt-1", Type: events.TypeUserLoggedIn, UserId: 667, OccurredAt: "2022-09-10T10:00:00Z"}
+
+	prep := mock.ExpectPrepare(queryInsertOutboxEvent)
+	prep.ExpectExec().WithArgs("evt-1", events.TypeUserLoggedIn, int64(667), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := OutboxRepository.Append(context.Background(), event)
+
+	assert.Nil(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessPendingClaimsBatchThenMarksHandledEvents(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(outboxColumns).
+		AddRow(1, `{"id":"evt-1","type":"user.created","user_id":667,"occurred_at":"2022-09-10T10:00:00Z","data":{}}`, nil).
+		AddRow(2, `{"id":"evt-2","type":"user.deleted","user_id":667,"occurred_at":"2022-09-10T10:05:00Z","data":{}}`, nil)
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "SELECT id, payload, claimed_until FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT ? FOR UPDATE;").
+		ExpectQuery().WithArgs(10).WillReturnRows(rows)
+	claim := expectTxPrepare(mock, queryClaimOutboxEvent)
+	claim.ExpectExec().WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	claim.ExpectExec().WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mark := mock.ExpectPrepare(queryMarkOutboxEventDispatched)
+	mark.ExpectExec().WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mark.ExpectExec().WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	handled := make([]string, 0)
+	processed, err := OutboxRepository.ProcessPending(context.Background(), 10, time.Minute, func(event events.Event) error {
+		handled = append(handled, event.Id)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, processed)
+	assert.Equal(t, []string{"evt-1", "evt-2"}, handled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessPendingReleasesEventsAfterHandlerFailure(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(outboxColumns).
+		AddRow(1, `{"id":"evt-1","type":"user.created","user_id":667,"occurred_at":"2022-09-10T10:00:00Z","data":{}}`, nil).
+		AddRow(2, `{"id":"evt-2","type":"user.deleted","user_id":667,"occurred_at":"2022-09-10T10:05:00Z","data":{}}`, nil).
+		AddRow(3, `{"id":"evt-3","type":"user.created","user_id":668,"occurred_at":"2022-09-10T10:06:00Z","data":{}}`, "2000-01-01 00:00:00")
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "SELECT id, payload, claimed_until FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT ? FOR UPDATE;").
+		ExpectQuery().WithArgs(10).WillReturnRows(rows)
+	claim := expectTxPrepare(mock, queryClaimOutboxEvent)
+	for id := 1; id <= 3; id++ {
+		claim.ExpectExec().WithArgs(sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+	mock.ExpectPrepare(queryMarkOutboxEventDispatched).ExpectExec().WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	release := mock.ExpectPrepare(queryReleaseOutboxEvent)
+	release.ExpectExec().WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	release.ExpectExec().WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := OutboxRepository.ProcessPending(context.Background(), 10, time.Minute, func(event events.Event) error {
+		if event.Id == "evt-2" {
+			return errors.New("publish error")
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, processed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessPendingLeavesBatchClaimedByAnotherRelay(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(outboxColumns).
+		AddRow(1, `{"id":"evt-1","type":"user.created","user_id":667,"occurred_at":"2022-09-10T10:00:00Z","data":{}}`, "2999-01-01 00:00:00")
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "SELECT id, payload, claimed_until FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT ? FOR UPDATE;").
+		ExpectQuery().WithArgs(10).WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	processed, err := OutboxRepository.ProcessPending(context.Background(), 10, time.Minute, func(event events.Event) error {
+		t.Fatal("handler called for a claimed event")
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, processed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessPendingDoesNotLockRowsOnSQLite(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	users_db.Current = users_db.SQLite
+	defer func() {
+		users_db.Current = users_db.MySQL
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryFindPendingOutboxEvents).ExpectQuery().WithArgs(10).WillReturnRows(sqlmock.NewRows(outboxColumns))
+	mock.ExpectCommit()
+
+	processed, err := OutboxRepository.ProcessPending(context.Background(), 10, time.Minute, func(events.Event) error { return nil })
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, processed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessPendingQueryFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "SELECT id, payload, claimed_until FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT ? FOR UPDATE;").
+		ExpectQuery().WithArgs(10).WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	processed, err := OutboxRepository.ProcessPending(context.Background(), 10, time.Minute, func(events.Event) error { return nil })
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error processing outbox", err.Message())
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/sql_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryInsertPlanAssignment        = "INSERT INTO user_plans(user_id, plan, effective_from, effective_until, date_created) VALUES(?, ?, ?, ?, ?);"
+	queryGetActivePlanAssignment     = "SELECT id, user_id, plan, effective_from, effective_until, date_created FROM user_plans WHERE user_id=? AND effective_from <= ? AND (effective_until IS NULL OR effective_until > ?) ORDER BY effective_from DESC, id DESC LIMIT 1;"
+	queryFindPlanAssignmentsByUser   = "SELECT id, user_id, plan, effective_from, effective_until, date_created FROM user_plans WHERE user_id=? ORDER BY effective_from DESC, id DESC;"
+	queryDeletePlanAssignmentsByUser = "DELETE FROM user_plans WHERE user_id=?;"
+	queryLockUserQuota               = "SELECT id FROM users WHERE id=?;"
+)
+
+var (
+	PlanAssignmentsRepository planAssignmentsRepositoryInterface = &planAssignmentsRepository{}
+)
+
+type planAssignmentsRepository struct {
+	session
+}
+
+type planAssignmentsRepositoryInterface interface {
+	Save(context.Context, *plans.Assignment) rest_errors.RestErr
+	GetActive(context.Context, int64, string) (*plans.Assignment, rest_errors.RestErr)
+	FindByUserId(context.Context, int64) (plans.Assignments, rest_errors.RestErr)
+	DeleteByUserId(context.Context, int64) rest_errors.RestErr
+	LockQuota(context.Context, int64) rest_errors.RestErr
+}
+
+func scanPlanAssignment(row rowScanner) (*plans.Assignment, error) {
+	var assignment plans.Assignment
+	if err := row.Scan(&assignment.Id, &assignment.UserId, &assignment.Plan, dbDateTime{&assignment.EffectiveFrom},
+		dbDateTime{&assignment.EffectiveUntil}, dbDateTime{&assignment.DateCreated}); err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r *planAssignmentsRepository) Save(ctx context.Context, assignment *plans.Assignment) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "plan_assignments.save")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "plan_assignments.save")
+	defer cancel()
+
+	var assignmentId int64
+	err := r.run(ctx, users_db.Current.ReturningId(queryInsertPlanAssignment), func(stmt *sql.Stmt) error {
+		var err error
+		assignmentId, err = insertedId(ctx, stmt, assignment.UserId, assignment.Plan, assignment.EffectiveFrom,
+			nullableString(assignment.EffectiveUntil), assignment.DateCreated)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare save plan assignment statement", err)
+			return databaseError(ctx, err, "error saving plan assignment")
+		}
+		logging.Error(ctx, "error when trying to save plan assignment", err)
+		return databaseError(ctx, err, "error saving plan assignment")
+	}
+	assignment.Id = assignmentId
+	return nil
+}
+
+// GetActive returns the assignment in effect at the given date, or a not found error
+// when the user has none.
+func (r *planAssignmentsRepository) GetActive(ctx context.Context, userId int64, now string) (*plans.Assignment, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "plan_assignments.get_active")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "plan_assignments.get_active")
+	defer cancel()
+
+	var assignment *plans.Assignment
+	err := r.run(ctx, queryGetActivePlanAssignment, func(stmt *sql.Stmt) error {
+		var err error
+		assignment, err = scanPlanAssignment(stmt.QueryRowContext(ctx, userId, now, now))
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare get active plan assignment statement", err)
+			return nil, databaseError(ctx, err, "error fetching plan assignment")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
+			return nil, rest_errors.NewNotFoundError("no active plan assignment")
+		}
+		logging.Error(ctx, "error when trying to get active plan assignment", err)
+		return nil, databaseError(ctx, err, "error fetching plan assignment")
+	}
+	return assignment, nil
+}
+
+func (r *planAssignmentsRepository) FindByUserId(ctx context.Context, userId int64) (plans.Assignments, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "plan_assignments.find_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "plan_assignments.find_by_user_id")
+	defer cancel()
+
+	result := make(plans.Assignments, 0)
+	err := r.run(ctx, queryFindPlanAssignmentsByUser, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, userId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			assignment, err := scanPlanAssignment(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, *assignment)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare find plan assignments statement", err)
+			return nil, databaseError(ctx, err, "error fetching plan assignments")
+		}
+		logging.Error(ctx, "error when trying to find plan assignments", err)
+		return nil, databaseError(ctx, err, "error fetching plan assignments")
+	}
+	return result, nil
+}
+
+func (r *planAssignmentsRepository) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "plan_assignments.delete_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "plan_assignments.delete_by_user_id")
+	defer cancel()
+
+	err := r.run(ctx, queryDeletePlanAssignmentsByUser, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, userId)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare delete plan assignments by user statement", err)
+			return databaseError(ctx, err, "error deleting plan assignments")
+		}
+		logging.Error(ctx, "error when trying to delete plan assignments by user", err)
+		return databaseError(ctx, err, "error deleting plan assignments")
+	}
+	return nil
+}
+
+// LockQuota locks the row of the user until the transaction of the repository ends,
+// so the quota checks of a user run one at a time. Users without an assignment are on
+// the default plan, which is why the user row is locked instead of a user_plans one.
+func (r *planAssignmentsRepository) LockQuota(ctx context.Context, userId int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "plan_assignments.lock_quota")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "plan_assignments.lock_quota")
+	defer cancel()
+
+	var id int64
+	err := r.run(ctx, users_db.Current.ForUpdate(queryLockUserQuota), func(stmt *sql.Stmt) error {
+		return stmt.QueryRowContext(ctx, userId).Scan(&id)
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare lock quota statement", err)
+			return databaseError(ctx, err, "error checking quota")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
+			return rest_errors.NewNotFoundError("user not found")
+		}
+		logging.Error(ctx, "error when trying to lock quota", err)
+		return databaseError(ctx, err, "error checking quota")
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/plans"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var planAssignmentColumns = []string{"id", "user_id", "plan", "effective_from", "effective_until", "date_created"}
+
+func TestSavePlanAssignmentOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	assignment := plans.Assignment{UserId: 1, Plan: plans.PlanPro, EffectiveFrom: "2022-09-01 00:00:00", DateCreated: "2022-08-30 10:00:00"}
+
+	prep := mock.ExpectPrepare(queryInsertPlanAssignment)
+	prep.ExpectExec().WithArgs(1, "pro", "2022-09-01 00:00:00", nil, "2022-08-30 10:00:00").WillReturnResult(sqlmock.NewResult(7, 1))
+
+	err := PlanAssignmentsRepository.Save(context.Background(), &assignment)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), assignment.Id)
+}
+
+func TestGetActivePlanAssignmentOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	now := "2022-09-06 10:00:00"
+	rows := sqlmock.NewRows(planAssignmentColumns).
+		AddRow(7, 1, "pro", "2022-09-01 00:00:00", "2022-10-01 00:00:00", "2022-08-30 10:00:00")
+
+	prep := mock.ExpectPrepare(queryGetActivePlanAssignment)
+	prep.ExpectQuery().WithArgs(1, now, now).WillReturnRows(rows)
+
+	assignment, err := PlanAssignmentsRepository.GetActive(context.Background(), 1, now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, plans.PlanPro, assignment.Plan)
+	assert.Equal(t, "2022-10-01 00:00:00", assignment.EffectiveUntil)
+}
+
+func TestGetActivePlanAssignmentNotFound(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	now := "2022-09-06 10:00:00"
+	prep := mock.ExpectPrepare(queryGetActivePlanAssignment)
+	prep.ExpectQuery().WithArgs(1, now, now).WillReturnRows(sqlmock.NewRows(planAssignmentColumns))
+
+	_, err := PlanAssignmentsRepository.GetActive(context.Background(), 1, now)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestFindPlanAssignmentsByUserIdExecutionFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryFindPlanAssignmentsByUser)
+	prep.ExpectQuery().WithArgs(1).WillReturnError(errors.New("database error"))
+
+	_, err := PlanAssignmentsRepository.FindByUserId(context.Background(), 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error fetching plan assignments", err.Message())
+}
+
+func TestLockQuotaLocksUserRowInTransaction(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "SELECT id FROM users WHERE id=? FOR UPDATE;").ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectTxPrepare(mock, queryFindAlertRulesByUser).ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows(alertRuleColumns))
+	mock.ExpectCommit()
+
+	err := TransactionManager.Run(context.Background(), func(repos Repositories) rest_errors.RestErr {
+		if err := repos.PlanAssignments.LockQuota(context.Background(), 1); err != nil {
+			return err
+		}
+		_, err := repos.AlertRules.FindByUserId(context.Background(), 1)
+		return err
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestLockQuotaUserNotFound(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare("SELECT id FROM users WHERE id=? FOR UPDATE;")
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err := PlanAssignmentsRepository.LockQuota(context.Background(), 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
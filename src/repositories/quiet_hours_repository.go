@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/sql_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryGetQuietHours    = "SELECT enabled, urgent_bypass, windows FROM quiet_hours WHERE user_id=?;"
+	queryUpsertQuietHours = "INSERT INTO quiet_hours(user_id, enabled, urgent_bypass, windows) VALUES(?, ?, ?, ?) ON DUPLICATE KEY UPDATE enabled=VALUES(enabled), urgent_bypass=VALUES(urgent_bypass), windows=VALUES(windows);"
+	queryDeleteQuietHours = "DELETE FROM quiet_hours WHERE user_id=?;"
+
+	// queryUpsertQuietHoursOnConflict is the upsert of PostgreSQL and SQLite, which
+	// have no ON DUPLICATE KEY UPDATE.
+	queryUpsertQuietHoursOnConflict = "INSERT INTO quiet_hours(user_id, enabled, urgent_bypass, windows) VALUES(?, ?, ?, ?) ON CONFLICT(user_id) DO UPDATE SET enabled=excluded.enabled, urgent_bypass=excluded.urgent_bypass, windows=excluded.windows;"
+)
+
+var (
+	QuietHoursRepository quietHoursRepositoryInterface = &quietHoursRepository{}
+)
+
+type quietHoursRepository struct {
+	session
+}
+
+type quietHoursRepositoryInterface interface {
+	GetByUserId(context.Context, int64) (*notifications.QuietHours, rest_errors.RestErr)
+	Save(context.Context, *notifications.QuietHours) rest_errors.RestErr
+	DeleteByUserId(context.Context, int64) rest_errors.RestErr
+}
+
+// GetByUserId returns disabled quiet hours without windows for users that never
+// configured them.
+func (r *quietHoursRepository) GetByUserId(ctx context.Context, userId int64) (*notifications.QuietHours, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "quiet_hours.get_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "quiet_hours.get_by_user_id")
+	defer cancel()
+
+	quietHours := notifications.QuietHours{UserId: userId, Windows: []notifications.QuietWindow{}}
+	var windows string
+	err := r.run(ctx, queryGetQuietHours, func(stmt *sql.Stmt) error {
+		return stmt.QueryRowContext(ctx, userId).Scan(&quietHours.Enabled, &quietHours.UrgentBypass, &windows)
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare get quiet hours statement", err)
+			return nil, databaseError(ctx, err, "error fetching quiet hours")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
+			return &quietHours, nil
+		}
+		logging.Error(ctx, "error when trying to get quiet hours by user id", err)
+		return nil, databaseError(ctx, err, "error fetching quiet hours")
+	}
+
+	if err := json.Unmarshal([]byte(windows), &quietHours.Windows); err != nil {
+		logging.Error(ctx, "error when trying to unmarshal quiet hours windows", err)
+		return nil, rest_errors.NewInternalServerError("error fetching quiet hours", errors.New("database error"))
+	}
+	return &quietHours, nil
+}
+
+func (r *quietHoursRepository) Save(ctx context.Context, quietHours *notifications.QuietHours) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "quiet_hours.save")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "quiet_hours.save")
+	defer cancel()
+
+	windows, err := json.Marshal(quietHours.Windows)
+	if err != nil {
+		logging.Error(ctx, "error when trying to marshal quiet hours windows", err)
+		return rest_errors.NewInternalServerError("error saving quiet hours", errors.New("database error"))
+	}
+
+	query := queryUpsertQuietHours
+	if users_db.Current.Name != users_db.MySQL.Name {
+		query = queryUpsertQuietHoursOnConflict
+	}
+	err = r.run(ctx, query, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, quietHours.UserId, quietHours.Enabled, quietHours.UrgentBypass, string(windows))
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare save quiet hours statement", err)
+			return databaseError(ctx, err, "error saving quiet hours")
+		}
+		logging.Error(ctx, "error when trying to save quiet hours", err)
+		return databaseError(ctx, err, "error saving quiet hours")
+	}
+	return nil
+}
+
+func (r *quietHoursRepository) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "quiet_hours.delete_by_user_id")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "quiet_hours.delete_by_user_id")
+	defer cancel()
+
+	err := r.run(ctx, queryDeleteQuietHours, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, userId)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare delete quiet hours by user statement", err)
+			return databaseError(ctx, err, "error deleting quiet hours")
+		}
+		logging.Error(ctx, "error when trying to delete quiet hours by user", err)
+		return databaseError(ctx, err, "error deleting quiet hours")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/notifications"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetQuietHoursOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"enabled", "urgent_bypass", "windows"}).
+		AddRow(true, true, `[{"weekday":"monday","start":"22:00","end":"07:00"}]`)
+
+	prep := mock.ExpectPrepare(queryGetQuietHours)
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(rows)
+
+	quietHours, err := QuietHoursRepository.GetByUserId(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.True(t, quietHours.Enabled)
+	assert.True(t, quietHours.UrgentBypass)
+	assert.Equal(t, 1, len(quietHours.Windows))
+	assert.Equal(t, "22:00", quietHours.Windows[0].Start)
+}
+
+func TestGetQuietHoursNotConfigured(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryGetQuietHours)
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"enabled", "urgent_bypass", "windows"}))
+
+	quietHours, err := QuietHoursRepository.GetByUserId(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.False(t, quietHours.Enabled)
+	assert.Equal(t, 0, len(quietHours.Windows))
+}
+
+func TestGetQuietHoursExecutionFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryGetQuietHours)
+	prep.ExpectQuery().WithArgs(1).WillReturnError(errors.New("database error"))
+
+	_, err := QuietHoursRepository.GetByUserId(context.Background(), 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error fetching quiet hours", err.Message())
+}
+
+func TestSaveQuietHoursOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	quietHours := notifications.QuietHours{UserId: 1, Enabled: true, Windows: []notifications.QuietWindow{
+		{Weekday: "friday", Start: "23:00", End: "24:00"},
+	}}
+
+	prep := mock.ExpectPrepare(queryUpsertQuietHours)
+	prep.ExpectExec().WithArgs(1, true, false, `[{"weekday":"friday","start":"23:00","end":"24:00"}]`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := QuietHoursRepository.Save(context.Background(), &quietHours)
+
+	assert.Nil(t, err)
+}
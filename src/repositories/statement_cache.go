@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+)
+
+// mysqlErrNeedReprepare is returned by MySQL for a statement prepared before a
+// change to the tables it reads.
+const mysqlErrNeedReprepare = 1615
+
+var (
+	statements = &statementCache{}
+)
+
+// statementCache keeps one prepared statement per query and connection pool, so
+// each call costs a single round trip instead of a prepare, an execution and a close.
+// database/sql takes care of preparing a statement again on connections of the pool
+// that have not seen it yet.
+type statementCache struct {
+	mutex      sync.RWMutex
+	statements map[*sql.DB]map[string]*sql.Stmt
+}
+
+// prepare returns the statement of query on db, rebound for users_db.Current and
+// prepared on the first call. The statement is prepared without holding the lock, so
+// a slow round trip never holds up the lookups of other queries; when two calls race
+// to prepare the same query, the one storing it first wins and the other closes its
+// copy.
+func (c *statementCache) prepare(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	c.mutex.RLock()
+	stmt, ok := c.statements[db][query]
+	c.mutex.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	prepared, err := db.PrepareContext(ctx, users_db.Current.Rebind(query))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if stmt, ok := c.statements[db][query]; ok {
+		if closeErr := prepared.Close(); closeErr != nil {
+			logger.Error("error when trying to close duplicated statement", closeErr)
+		}
+		return stmt, nil
+	}
+	stmt = prepared
+	if c.statements == nil {
+		c.statements = map[*sql.DB]map[string]*sql.Stmt{}
+	}
+	if c.statements[db] == nil {
+		c.statements[db] = map[string]*sql.Stmt{}
+	}
+	c.statements[db][query] = stmt
+	return stmt, nil
+}
+
+// invalidate drops the statement of query on db when err shows it can no longer be
+// used, so the next call prepares it again. It reports whether the statement was dropped.
+func (c *statementCache) invalidate(db *sql.DB, query string, err error) bool {
+	if !staleStatement(err) {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stmt, ok := c.statements[db][query]
+	if !ok {
+		return false
+	}
+	delete(c.statements[db], query)
+	if closeErr := stmt.Close(); closeErr != nil {
+		logger.Error("error when trying to close invalidated statement", closeErr)
+	}
+	return true
+}
+
+// close closes every statement and empties the cache, before the pools they were
+// prepared on are closed.
+func (c *statementCache) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, pool := range c.statements {
+		for _, stmt := range pool {
+			if err := stmt.Close(); err != nil {
+				logger.Error("error when trying to close cached statement", err)
+			}
+		}
+	}
+	c.statements = nil
+}
+
+// CloseStatements closes the statements cached by every repository. Calls made after
+// it prepare their statements again.
+func CloseStatements() {
+	statements.close()
+}
+
+func staleStatement(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrNeedReprepare
+	}
+	// database/sql has no exported error for a closed statement.
+	return err != nil && err.Error() == "sql: statement is closed"
+}
+
+// prepare returns the cached statement of query, bound to tx when there is one.
+// Callers must not close it: the cache owns the statement and tx closes its copy
+// when it ends.
+func prepare(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	stmt, err := statements.prepare(ctx, users_db.Client, query)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		return tx.StmtContext(ctx, stmt), nil
+	}
+	return stmt, nil
+}
+
+// prepareError wraps the errors of preparing a statement, which callers report apart
+// from those of running it.
+type prepareError struct {
+	err error
+}
+
+func (e prepareError) Error() string { return e.err.Error() }
+func (e prepareError) Unwrap() error { return e.err }
+
+// runOn calls fn with the cached statement of query on db, dropping the statement
+// when it turns out to be stale.
+func runOn(ctx context.Context, db *sql.DB, query string, fn func(*sql.Stmt) error) error {
+	stmt, err := statements.prepare(ctx, db, query)
+	if err != nil {
+		return prepareError{err}
+	}
+	if err := fn(stmt); err != nil {
+		statements.invalidate(db, query, err)
+		return err
+	}
+	return nil
+}
+
+// runIn calls fn with the cached statement of query bound to tx, or on
+// users_db.Client when tx is nil, dropping the statement when it turns out to be stale.
+func runIn(ctx context.Context, tx *sql.Tx, query string, fn func(*sql.Stmt) error) error {
+	stmt, err := prepare(ctx, tx, query)
+	if err != nil {
+		return prepareError{err}
+	}
+	if err := fn(stmt); err != nil {
+		statements.invalidate(users_db.Client, query, err)
+		return err
+	}
+	return nil
+}
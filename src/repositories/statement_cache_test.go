@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/domain/notifications"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func userRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "time_zone", "status", "role", "date_created"}).
+		AddRow(1, "John", "john@mail.com", "@john", "UTC", "active", "user", "2022-01-01")
+}
+
+func TestStatementCachePreparesOnce(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryGetUser)
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+
+	for i := 0; i < 2; i++ {
+		user, err := UsersRepository.Get(context.Background(), 1)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), user.Id)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestStatementCacheKeepsStatementsPerPool(t *testing.T) {
+
+	first, firstMock := NewMock()
+	defer first.Close()
+	second, secondMock := NewMock()
+	defer second.Close()
+
+	firstMock.ExpectPrepare(queryGetUser)
+	secondMock.ExpectPrepare(queryGetUser)
+
+	firstStmt, err := statements.prepare(context.Background(), first, queryGetUser)
+	assert.Nil(t, err)
+	secondStmt, err := statements.prepare(context.Background(), second, queryGetUser)
+	assert.Nil(t, err)
+
+	assert.NotSame(t, firstStmt, secondStmt)
+	cached, err := statements.prepare(context.Background(), first, queryGetUser)
+	assert.Nil(t, err)
+	assert.Same(t, firstStmt, cached)
+	assert.Nil(t, firstMock.ExpectationsWereMet())
+	assert.Nil(t, secondMock.ExpectationsWereMet())
+}
+
+func TestStatementCacheServesLookupsWhilePreparing(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectPrepare(queryGetUser)
+	mock.ExpectPrepare(queryUpdateUser).WillDelayFor(200 * time.Millisecond)
+	cached, err := statements.prepare(context.Background(), db, queryGetUser)
+	assert.Nil(t, err)
+
+	prepared := make(chan error)
+	go func() {
+		_, err := statements.prepare(context.Background(), db, queryUpdateUser)
+		prepared <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	stmt, err := statements.prepare(context.Background(), db, queryGetUser)
+	assert.Nil(t, err)
+	assert.Same(t, cached, stmt)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Nil(t, <-prepared)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseStatementsClosesEveryPool(t *testing.T) {
+
+	first, firstMock := NewMock()
+	defer first.Close()
+	second, secondMock := NewMock()
+	defer second.Close()
+
+	firstMock.ExpectPrepare(queryGetUser).WillBeClosed()
+	secondMock.ExpectPrepare(queryGetUser).WillBeClosed()
+	firstMock.ExpectPrepare(queryGetUser)
+
+	firstStmt, err := statements.prepare(context.Background(), first, queryGetUser)
+	assert.Nil(t, err)
+	_, err = statements.prepare(context.Background(), second, queryGetUser)
+	assert.Nil(t, err)
+
+	CloseStatements()
+
+	assert.Nil(t, secondMock.ExpectationsWereMet())
+	prepared, err := statements.prepare(context.Background(), first, queryGetUser)
+	assert.Nil(t, err)
+	assert.NotSame(t, firstStmt, prepared)
+	assert.Nil(t, firstMock.ExpectationsWereMet())
+}
+
+func TestGetUserPreparesAgainAfterInvalidation(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectPrepare(queryGetUser).ExpectQuery().WithArgs(1).WillReturnError(&mysql.MySQLError{Number: mysqlErrNeedReprepare, Message: "Prepared statement needs to be re-prepared"})
+	mock.ExpectPrepare(queryGetUser).ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+
+	_, err := UsersRepository.Get(context.Background(), 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+
+	user, err := UsersRepository.Get(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), user.Id)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAlertRulePreparesAgainAfterInvalidation(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	enabled := true
+	rule := alerts.AlertRule{Id: 12, UserId: 1, Token: "btc", Type: alerts.TypePriceBelow, Threshold: 20000, Enabled: &enabled}
+	mock.ExpectPrepare(queryUpdateAlertRule).ExpectExec().WillReturnError(&mysql.MySQLError{Number: mysqlErrNeedReprepare, Message: "Prepared statement needs to be re-prepared"})
+	mock.ExpectPrepare(queryUpdateAlertRule).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := AlertRulesRepository.Update(context.Background(), &rule)
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+
+	err = AlertRulesRepository.Update(context.Background(), &rule)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveNotificationSettingsPreparesAgainAfterInvalidation(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	settings := notifications.Settings{UserId: 1}
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryDeleteNotificationChannels).ExpectExec().WithArgs(1).WillReturnError(errors.New("sql: statement is closed"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryDeleteNotificationChannels).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := NotificationSettingsRepository.Save(context.Background(), &settings)
+	assert.NotNil(t, err)
+
+	err = NotificationSettingsRepository.Save(context.Background(), &settings)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestStatementCacheKeepsStatementOnOtherErrors(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectPrepare(queryGetUser)
+	stmt, err := statements.prepare(context.Background(), db, queryGetUser)
+	assert.Nil(t, err)
+
+	assert.False(t, statements.invalidate(db, queryGetUser, &mysql.MySQLError{Number: 1062}))
+	cached, err := statements.prepare(context.Background(), db, queryGetUser)
+	assert.Nil(t, err)
+	assert.Same(t, stmt, cached)
+}
+
+// roundTrip is the latency the benchmarks give every request sent to the database.
+const roundTrip = 200 * time.Microsecond
+
+func init() {
+	sql.Register("latency", latencyDriver{})
+}
+
+// latencyDriver answers every query with one user row after waiting roundTrip, which
+// makes the cost of the extra round trips of preparing statements visible.
+type latencyDriver struct{}
+
+func (latencyDriver) Open(string) (driver.Conn, error) { return latencyConn{}, nil }
+
+type latencyConn struct{}
+
+func (latencyConn) Prepare(string) (driver.Stmt, error) {
+	time.Sleep(roundTrip)
+	return latencyStmt{}, nil
+}
+func (latencyConn) Close() error              { return nil }
+func (latencyConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type latencyStmt struct{}
+
+// Close sends COM_STMT_CLOSE, which MySQL does not answer.
+func (latencyStmt) Close() error  { return nil }
+func (latencyStmt) NumInput() int { return -1 }
+func (latencyStmt) Exec([]driver.Value) (driver.Result, error) {
+	time.Sleep(roundTrip)
+	return driver.RowsAffected(1), nil
+}
+func (latencyStmt) Query([]driver.Value) (driver.Rows, error) {
+	time.Sleep(roundTrip)
+	return &latencyRows{}, nil
+}
+
+type latencyRows struct {
+	done bool
+}
+
+func (r *latencyRows) Columns() []string {
+	return []string{"id", "name", "email", "telegram_user", "time_zone", "status", "role", "date_created"}
+}
+func (r *latencyRows) Close() error { return nil }
+func (r *latencyRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, []driver.Value{int64(1), "John", "john@mail.com", "@john", "UTC", "active", "user", "2022-01-01"})
+	return nil
+}
+
+func openLatencyDB(b *testing.B) *sql.DB {
+	db, err := sql.Open("latency", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+// BenchmarkGetUserPreparePerCall measures the way statements were run before the
+// cache: prepared, executed and closed on every call.
+func BenchmarkGetUserPreparePerCall(b *testing.B) {
+	db := openLatencyDB(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stmt, err := db.PrepareContext(ctx, queryGetUser)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var id int64
+		var name, email, telegramUser, timeZone, status, role, dateCreated string
+		if err := stmt.QueryRowContext(ctx, 1).Scan(&id, &name, &email, &telegramUser, &timeZone, &status, &role, &dateCreated); err != nil {
+			b.Fatal(err)
+		}
+		stmt.Close()
+	}
+}
+
+func BenchmarkGetUserCachedStatement(b *testing.B) {
+	users_db.Client = openLatencyDB(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := UsersRepository.Get(ctx, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/tracing"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+// StatusClientClosedRequest is the non standard status, introduced by nginx, for
+// requests abandoned by the client before the response was ready.
+const StatusClientClosedRequest = 499
+
+var (
+	TransactionManager transactionManagerInterface = &transactionManager{}
+)
+
+// session is embedded by every repository. Without a transaction it runs on
+// users_db.Client; repositories handed out by TransactionManager.Run carry the
+// transaction of the unit of work instead.
+type session struct {
+	tx *sql.Tx
+}
+
+// run calls fn with the statement of query on the transaction of the session, or on
+// users_db.Client without one.
+func (s session) run(ctx context.Context, query string, fn func(*sql.Stmt) error) error {
+	return runIn(ctx, s.tx, query, fn)
+}
+
+// read calls fn with the statement of a read-only query on the pool picked by
+// users_db.ReadClient, trying again on the primary when a replica can not be reached.
+// Inside a transaction it runs on the transaction like any other query.
+func (s session) read(ctx context.Context, query string, fn func(*sql.Stmt) error) error {
+	if s.tx != nil {
+		return runIn(ctx, s.tx, query, fn)
+	}
+
+	db := users_db.ReadClient(ctx)
+	err := runOn(ctx, db, query, fn)
+	if err != nil && db != users_db.Client && users_db.Replicas.Failed(db, err) {
+		return runOn(ctx, users_db.Client, query, fn)
+	}
+	return err
+}
+
+// inTransaction joins the transaction of the session, if any, and starts a new one
+// otherwise.
+func (s session) inTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return inTransaction(ctx, fn)
+}
+
+// inTransaction runs fn in a new transaction, committing when it succeeds and rolling
+// back otherwise. The transaction is rolled back as well when ctx ends first.
+func inTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := users_db.Client.BeginTx(ctx, nil)
+	if err != nil {
+		logging.Error(ctx, "error when trying to begin transaction", err)
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logging.Error(ctx, "error when trying to rollback transaction", rollbackErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logging.Error(ctx, "error when trying to commit transaction", err)
+		return err
+	}
+	return nil
+}
+
+// Repositories groups the repositories a unit of work can use.
+type Repositories struct {
+	Users                userRepositoryInterface
+	AlertRules           alertRulesRepositoryInterface
+	NotificationSettings notificationSettingsRepositoryInterface
+	QuietHours           quietHoursRepositoryInterface
+	PlanAssignments      planAssignmentsRepositoryInterface
+	Webhooks             webhooksRepositoryInterface
+	Outbox               outboxRepositoryInterface
+	Audit                auditRepositoryInterface
+}
+
+// Default returns the package level repositories, which run every call on its own.
+func Default() Repositories {
+	return Repositories{
+		Users:                UsersRepository,
+		AlertRules:           AlertRulesRepository,
+		NotificationSettings: NotificationSettingsRepository,
+		QuietHours:           QuietHoursRepository,
+		PlanAssignments:      PlanAssignmentsRepository,
+		Webhooks:             WebhooksRepository,
+		Outbox:               OutboxRepository,
+		Audit:                AuditRepository,
+	}
+}
+
+func bindRepositories(tx *sql.Tx) Repositories {
+	bound := session{tx: tx}
+	return Repositories{
+		Users:                &usersRepository{bound},
+		AlertRules:           &alertRulesRepository{bound},
+		NotificationSettings: &notificationSettingsRepository{bound},
+		QuietHours:           &quietHoursRepository{bound},
+		PlanAssignments:      &planAssignmentsRepository{bound},
+		Webhooks:             &webhooksRepository{bound},
+		Outbox:               &outboxRepository{bound},
+		Audit:                &auditRepository{bound},
+	}
+}
+
+type transactionManager struct{}
+
+type transactionManagerInterface interface {
+	Run(context.Context, func(Repositories) rest_errors.RestErr) rest_errors.RestErr
+}
+
+// Run calls fn with repositories sharing one transaction, which is committed when fn
+// succeeds and rolled back when it returns an error, panics or ctx ends.
+func (m *transactionManager) Run(ctx context.Context, fn func(Repositories) rest_errors.RestErr) rest_errors.RestErr {
+	tx, err := users_db.Client.BeginTx(ctx, nil)
+	if err != nil {
+		logging.Error(ctx, "error when trying to begin transaction", err)
+		return databaseError(ctx, err, "error starting transaction")
+	}
+
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logging.Error(ctx, "error when trying to rollback transaction", rollbackErr)
+		}
+	}()
+
+	bound := bindRepositories(tx)
+	if cached, ok := UsersRepository.(*cachedUsersRepository); ok {
+		var invalidate func()
+		bound.Users, invalidate = cached.inTransaction(bound.Users)
+		defer invalidate()
+	}
+
+	if restErr := fn(bound); restErr != nil {
+		return restErr
+	}
+
+	finished = true
+	if err := tx.Commit(); err != nil {
+		logging.Error(ctx, "error when trying to commit transaction", err)
+		return databaseError(ctx, err, "error committing transaction")
+	}
+	return nil
+}
+
+// databaseError maps an error returned by the database to the one given to callers.
+// Operations that ran out of time answer 504, those whose caller went away 499. ctx
+// is checked as well since drivers report an interrupted query with errors of their
+// own. The failure is recorded on the span of ctx.
+func databaseError(ctx context.Context, err error, message string) rest_errors.RestErr {
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	tracing.Fail(ctx, err)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return rest_errors.NewRestError("database timeout", http.StatusGatewayTimeout, "gateway_timeout", nil)
+	case errors.Is(err, context.Canceled):
+		return rest_errors.NewRestError("request canceled", StatusClientClosedRequest, "client_closed_request", nil)
+	}
+	return rest_errors.NewInternalServerError(message, errors.New("database error"))
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/users"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionManagerCommitsEveryRepositoryCall(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryDeleteAlertRulesByUser).ExpectExec().WithArgs(667).WillReturnResult(sqlmock.NewResult(0, 3))
+	expectTxPrepare(mock, queryDeleteUser).ExpectExec().WithArgs(667).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxPrepare(mock, queryInsertOutboxEvent).ExpectExec().WithArgs(sqlmock.AnyArg(), "user.deleted", int64(667), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := TransactionManager.Run(context.Background(), func(repos Repositories) rest_errors.RestErr {
+		if err := repos.AlertRules.DeleteByUserId(context.Background(), 667); err != nil {
+			return err
+		}
+		return repos.Users.Delete(context.Background(), &users.User{Id: 667}, "user.deleted")
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionManagerRollsBackOnError(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, queryDeleteAlertRulesByUser).ExpectExec().WithArgs(667).WillReturnResult(sqlmock.NewResult(0, 3))
+	expectTxPrepare(mock, queryDeleteQuietHours).ExpectExec().WithArgs(667).WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	err := TransactionManager.Run(context.Background(), func(repos Repositories) rest_errors.RestErr {
+		if err := repos.AlertRules.DeleteByUserId(context.Background(), 667); err != nil {
+			return err
+		}
+		return repos.QuietHours.DeleteByUserId(context.Background(), 667)
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error deleting quiet hours", err.Message())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionManagerRollsBackOnPanic(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	assert.Panics(t, func() {
+		TransactionManager.Run(context.Background(), func(repos Repositories) rest_errors.RestErr {
+			panic("unexpected")
+		})
+	})
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionManagerCommitFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("database error"))
+
+	err := TransactionManager.Run(context.Background(), func(repos Repositories) rest_errors.RestErr {
+		return nil
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error committing transaction", err.Message())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
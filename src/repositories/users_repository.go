@@ -1,94 +1,297 @@
 package repositories
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"strings"
 	"tokenalert_user-api/src/datasources/mysql/users_db"
 	"tokenalert_user-api/src/domain/users"
-	"tokenalert_user-api/src/utils/mysql_utils"
-	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/sql_utils"
 	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
 )
 
 const (
-	queryInsertUser             = "INSERT INTO users(name, email, telegram_user, status, password, date_created) VALUES(?, ?, ?, ?, ?, ?);"
-	queryGetUser                = "SELECT id, name, email, telegram_user, status, date_created FROM users WHERE id=?;"
-	queryFindByEmailAndPassword = "SELECT id, name, email, telegram_user, status FROM users WHERE email=? AND password=? AND status=?"
+	queryInsertUser             = "INSERT INTO users(name, email, telegram_user, time_zone, status, role, password, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?);"
+	queryGetUser                = "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE id=?;"
+	queryUpdateUser             = "UPDATE users SET name=?, email=?, telegram_user=?, time_zone=? WHERE id=?;"
+	queryUpdateUserPassword     = "UPDATE users SET password=? WHERE id=?;"
+	queryUpdateUserAccess       = "UPDATE users SET status=?, role=? WHERE id=?;"
+	queryDeleteUser             = "DELETE FROM users WHERE id=?;"
+	queryFindByEmailAndPassword = "SELECT id, name, email, telegram_user, time_zone, status, role FROM users WHERE email=? AND password=? AND status=?"
+	querySelectUsers            = "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users"
 )
 
 var (
 	UsersRepository userRepositoryInterface = &usersRepository{}
 )
 
-type usersRepository struct{}
+type usersRepository struct {
+	session
+}
 
 type userRepositoryInterface interface {
-	Save(*users.User) rest_errors.RestErr
-	Get(int64) (*users.User, rest_errors.RestErr)
-	FindByEmailAndPassword(users.LoginRequest) (*users.User, rest_errors.RestErr)
+	Save(context.Context, *users.User, ...string) rest_errors.RestErr
+	Get(context.Context, int64) (*users.User, rest_errors.RestErr)
+	Update(context.Context, *users.User, ...string) rest_errors.RestErr
+	UpdatePassword(context.Context, *users.User, string, ...string) rest_errors.RestErr
+	UpdateAccess(context.Context, *users.User, ...string) rest_errors.RestErr
+	Delete(context.Context, *users.User, ...string) rest_errors.RestErr
+	FindByEmailAndPassword(context.Context, users.LoginRequest) (*users.User, rest_errors.RestErr)
+	Search(context.Context, users.Filter) (users.Users, rest_errors.RestErr)
 }
 
-func (u *usersRepository) Save(user *users.User) rest_errors.RestErr {
+// Save inserts the user and, in the same transaction, appends an outbox event of each
+// of the given types.
+func (u *usersRepository) Save(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "users.save")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.save")
+	defer cancel()
+
+	err := u.inTransaction(ctx, func(tx *sql.Tx) error {
+		query := users_db.Current.ReturningId(queryInsertUser)
+		stmt, err := prepare(ctx, tx, query)
+		if err != nil {
+			logging.Error(ctx, "error when trying to prepare save user statement", err)
+			return err
+		}
+
+		userId, err := insertedId(ctx, stmt, &user.Name, user.Email, user.TelegramUser, user.TimeZone, user.Status, user.Role, user.Password, user.DateCreated)
+		if err != nil {
+			statements.invalidate(users_db.Client, query, err)
+			logging.Error(ctx, "error when trying to save user", err)
+			return err
+		}
+		user.Id = userId
 
-	stmt, err := users_db.Client.Prepare(queryInsertUser)
+		return appendUserEvents(ctx, tx, user, eventTypes)
+	})
 	if err != nil {
-		logger.Error("error when trying to prepare save user statement", err)
-		return rest_errors.NewInternalServerError("error saving user", errors.New("database error"))
+		if sql_utils.IsUniqueViolation(err) {
+			return rest_errors.NewBadRequestError("email already registered")
+		}
+		return databaseError(ctx, err, "error saving user")
 	}
-	defer stmt.Close()
+	return nil
+}
 
-	insertResult, saveErr := stmt.Exec(&user.Name, user.Email, user.TelegramUser, user.Status, user.Password, user.DateCreated)
-	if saveErr != nil {
-		logger.Error("error when trying to save user", saveErr)
-		return rest_errors.NewInternalServerError("error saving user", errors.New("database error"))
+// Get reads from a replica unless ctx asks for the primary.
+func (u *usersRepository) Get(ctx context.Context, id int64) (*users.User, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "users.get")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.get")
+	defer cancel()
+
+	var user users.User
+	err := u.read(ctx, queryGetUser, func(stmt *sql.Stmt) error {
+		result := stmt.QueryRowContext(ctx, id)
+		return result.Scan(&user.Id, &user.Name, &user.Email, &user.TelegramUser, &user.TimeZone, &user.Status, &user.Role, dbDateTime{&user.DateCreated})
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare get user statement", err)
+			return nil, databaseError(ctx, err, "error fetching user")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
+			return nil, rest_errors.NewNotFoundError("user not found")
+		}
+		logging.Error(ctx, "error when trying to get user by id", err)
+		return nil, databaseError(ctx, err, "error fetching user")
 	}
+	return &user, nil
+}
 
-	userId, err := insertResult.LastInsertId()
+func (u *usersRepository) Update(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "users.update")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.update")
+	defer cancel()
+
+	err := u.inTransaction(ctx, func(tx *sql.Tx) error {
+		stmt, err := prepare(ctx, tx, queryUpdateUser)
+		if err != nil {
+			logging.Error(ctx, "error when trying to prepare update user statement", err)
+			return err
+		}
+
+		if _, err = stmt.ExecContext(ctx, user.Name, user.Email, user.TelegramUser, user.TimeZone, user.Id); err != nil {
+			logging.Error(ctx, "error when trying to update user", err)
+			return err
+		}
+		return appendUserEvents(ctx, tx, user, eventTypes)
+	})
 	if err != nil {
-		logger.Error("error when trying to get last insert id after creating a new user", err)
-		return rest_errors.NewInternalServerError("error saving user", errors.New("database error"))
+		if sql_utils.IsUniqueViolation(err) {
+			return rest_errors.NewBadRequestError("email already registered")
+		}
+		return databaseError(ctx, err, "error updating user")
 	}
-	user.Id = userId
 	return nil
 }
 
-func (u *usersRepository) Get(id int64) (*users.User, rest_errors.RestErr) {
+func (u *usersRepository) UpdatePassword(ctx context.Context, user *users.User, password string, eventTypes ...string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "users.update_password")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.update_password")
+	defer cancel()
+
+	err := u.inTransaction(ctx, func(tx *sql.Tx) error {
+		stmt, err := prepare(ctx, tx, queryUpdateUserPassword)
+		if err != nil {
+			logging.Error(ctx, "error when trying to prepare update user password statement", err)
+			return err
+		}
 
-	stmt, err := users_db.Client.Prepare(queryGetUser)
+		if _, err = stmt.ExecContext(ctx, password, user.Id); err != nil {
+			logging.Error(ctx, "error when trying to update user password", err)
+			return err
+		}
+		return appendUserEvents(ctx, tx, user, eventTypes)
+	})
 	if err != nil {
-		logger.Error("error when trying to prepare get user statement", err)
-		return nil, rest_errors.NewInternalServerError("error fetching user", errors.New("database error"))
+		return databaseError(ctx, err, "error updating user password")
 	}
-	defer stmt.Close()
+	return nil
+}
 
-	result := stmt.QueryRow(id)
+// UpdateAccess changes the fields only staff can change: status and role.
+func (u *usersRepository) UpdateAccess(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "users.update_access")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.update_access")
+	defer cancel()
 
-	var user users.User
-	if getErr := result.Scan(&user.Id, &user.Name, &user.Email, &user.TelegramUser, &user.DateCreated, &user.Status); getErr != nil {
-		logger.Error("error when trying to get user by id", getErr)
-		return nil, rest_errors.NewInternalServerError("error fetching user", errors.New("database error"))
+	err := u.inTransaction(ctx, func(tx *sql.Tx) error {
+		stmt, err := prepare(ctx, tx, queryUpdateUserAccess)
+		if err != nil {
+			logging.Error(ctx, "error when trying to prepare update user access statement", err)
+			return err
+		}
+
+		if _, err = stmt.ExecContext(ctx, user.Status, user.Role, user.Id); err != nil {
+			logging.Error(ctx, "error when trying to update user access", err)
+			return err
+		}
+		return appendUserEvents(ctx, tx, user, eventTypes)
+	})
+	if err != nil {
+		return databaseError(ctx, err, "error updating user")
 	}
-	return &user, nil
+	return nil
 }
 
-func (u *usersRepository) FindByEmailAndPassword(login users.LoginRequest) (*users.User, rest_errors.RestErr) {
+func (u *usersRepository) Delete(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "users.delete")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.delete")
+	defer cancel()
 
-	stmt, err := users_db.Client.Prepare(queryFindByEmailAndPassword)
+	err := u.inTransaction(ctx, func(tx *sql.Tx) error {
+		stmt, err := prepare(ctx, tx, queryDeleteUser)
+		if err != nil {
+			logging.Error(ctx, "error when trying to prepare delete user statement", err)
+			return err
+		}
+
+		if _, err = stmt.ExecContext(ctx, user.Id); err != nil {
+			logging.Error(ctx, "error when trying to delete user", err)
+			return err
+		}
+		return appendUserEvents(ctx, tx, user, eventTypes)
+	})
 	if err != nil {
-		logger.Error("error when trying to prepare get user by email and password statement", err)
-		return nil,  rest_errors.NewInternalServerError("error when tying to find user", errors.New("database error"))
+		return databaseError(ctx, err, "error deleting user")
 	}
-	defer stmt.Close()
+	return nil
+}
+
+// FindByEmailAndPassword reads from a replica unless ctx asks for the primary.
+func (u *usersRepository) FindByEmailAndPassword(ctx context.Context, login users.LoginRequest) (*users.User, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "users.find_by_credentials")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.find_by_credentials")
+	defer cancel()
 
 	var user users.User
-	result := stmt.QueryRow(login.Email, login.Password, users.StatusActive)
-	if getErr := result.Scan(&user.Id, &user.Name, &user.Email, &user.TelegramUser, &user.Status); getErr != nil {
-		if strings.Contains(getErr.Error(), mysql_utils.ErrorNoRows) {
+	err := u.read(ctx, queryFindByEmailAndPassword, func(stmt *sql.Stmt) error {
+		result := stmt.QueryRowContext(ctx, login.Email, login.Password, users.StatusActive)
+		return result.Scan(&user.Id, &user.Name, &user.Email, &user.TelegramUser, &user.TimeZone, &user.Status, &user.Role)
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare get user by email and password statement", err)
+			return nil, databaseError(ctx, err, "error when tying to find user")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
 			return nil, rest_errors.NewNotFoundError("invalid user credentials")
 		}
-		logger.Error("error when trying to get user by email and password", getErr)
-		return nil, rest_errors.NewInternalServerError("error when trying to find user", errors.New("database error"))
+		logging.Error(ctx, "error when trying to get user by email and password", err)
+		return nil, databaseError(ctx, err, "error when trying to find user")
 	}
 
 	return &user, nil
 }
+
+// Search returns a page of the users matching filter, newest first. It reads from a
+// replica unless ctx asks for the primary.
+func (u *usersRepository) Search(ctx context.Context, filter users.Filter) (users.Users, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "users.search")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "users.search")
+	defer cancel()
+
+	result := make(users.Users, 0)
+	query, args := usersQuery(filter)
+	err := u.read(ctx, query, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var user users.User
+			if err := rows.Scan(&user.Id, &user.Name, &user.Email, &user.TelegramUser, &user.TimeZone, &user.Status, &user.Role, dbDateTime{&user.DateCreated}); err != nil {
+				return err
+			}
+			result = append(result, user)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		logging.Error(ctx, "error when trying to search users", err)
+		return nil, databaseError(ctx, err, "error searching users")
+	}
+	return result, nil
+}
+
+// usersQuery builds the query of filter, with conditions only for the fields set.
+func usersQuery(filter users.Filter) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(condition string, value interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, value)
+	}
+	if filter.Status != "" {
+		add("status=?", filter.Status)
+	}
+	if filter.Role != "" {
+		add("role=?", filter.Role)
+	}
+	if filter.Email != "" {
+		add("email=?", filter.Email)
+	}
+	if filter.BeforeId != 0 {
+		add("id<?", filter.BeforeId)
+	}
+
+	query := querySelectUsers
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?;"
+	return query, append(args, filter.Limit)
+}
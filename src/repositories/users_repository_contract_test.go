@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/users"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testUsersRepositoryContract checks the rules every users repository backend has to
+// follow. newRepository returns an empty repository for each subtest.
+func testUsersRepositoryContract(t *testing.T, newRepository func(t *testing.T) userRepositoryInterface) {
+	ctx := context.Background()
+	newUser := func(email string) users.User {
+		return users.User{Name: "John", Email: email, TelegramUser: "@john", TimeZone: "UTC", Status: users.StatusActive, Role: access.RoleUser, Password: "hash", DateCreated: "2022-01-01 10:00:00"}
+	}
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+
+		assert.Nil(t, repository.Save(ctx, &user, events.TypeUserCreated))
+		assert.NotZero(t, user.Id)
+
+		found, err := repository.Get(ctx, user.Id)
+		assert.Nil(t, err)
+		user.Password = ""
+		assert.Equal(t, user, *found)
+	})
+
+	t.Run("SaveAssignsNewIds", func(t *testing.T) {
+		repository := newRepository(t)
+		first, second := newUser("john@mail.com"), newUser("jane@mail.com")
+
+		assert.Nil(t, repository.Save(ctx, &first))
+		assert.Nil(t, repository.Save(ctx, &second))
+		assert.NotEqual(t, first.Id, second.Id)
+	})
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		repository := newRepository(t)
+
+		found, err := repository.Get(ctx, 1)
+
+		assert.Nil(t, found)
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.Status())
+		assert.Equal(t, "user not found", err.Message())
+	})
+
+	t.Run("SaveDuplicateEmail", func(t *testing.T) {
+		repository := newRepository(t)
+		first := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &first))
+
+		second := newUser("john@mail.com")
+		err := repository.Save(ctx, &second)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.Status())
+		assert.Equal(t, "email already registered", err.Message())
+	})
+
+	t.Run("UpdateChangesProfileOnly", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		changed := user
+		changed.Name, changed.Email, changed.TelegramUser, changed.TimeZone = "Johnny", "johnny@mail.com", "@johnny", "America/Argentina/Buenos_Aires"
+		changed.Status, changed.Role, changed.Password = "inactive", access.RoleAdmin, "other"
+		assert.Nil(t, repository.Update(ctx, &changed, events.TypeUserUpdated))
+
+		found, err := repository.Get(ctx, user.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, "Johnny", found.Name)
+		assert.Equal(t, "johnny@mail.com", found.Email)
+		assert.Equal(t, "@johnny", found.TelegramUser)
+		assert.Equal(t, "America/Argentina/Buenos_Aires", found.TimeZone)
+		assert.Equal(t, users.StatusActive, found.Status)
+		assert.Equal(t, access.RoleUser, found.Role)
+
+		_, err = repository.FindByEmailAndPassword(ctx, users.LoginRequest{Email: "johnny@mail.com", Password: "hash"})
+		assert.Nil(t, err)
+	})
+
+	t.Run("UpdateAccessChangesStatusAndRoleOnly", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		changed := user
+		changed.Name, changed.Status, changed.Role = "Johnny", users.StatusSuspended, access.RoleSupport
+		assert.Nil(t, repository.UpdateAccess(ctx, &changed, events.TypeStatusChanged))
+
+		found, err := repository.Get(ctx, user.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, "John", found.Name)
+		assert.Equal(t, users.StatusSuspended, found.Status)
+		assert.Equal(t, access.RoleSupport, found.Role)
+
+		_, err = repository.FindByEmailAndPassword(ctx, users.LoginRequest{Email: "john@mail.com", Password: "hash"})
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.Status())
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		repository := newRepository(t)
+		john, jane, jim := newUser("john@mail.com"), newUser("jane@mail.com"), newUser("jim@mail.com")
+		jane.Role = access.RoleAdmin
+		jim.Status = users.StatusSuspended
+		for _, user := range []*users.User{&john, &jane, &jim} {
+			assert.Nil(t, repository.Save(ctx, user))
+		}
+		ids := func(found users.Users) []int64 {
+			result := make([]int64, 0, len(found))
+			for _, user := range found {
+				assert.Empty(t, user.Password)
+				result = append(result, user.Id)
+			}
+			return result
+		}
+
+		found, err := repository.Search(ctx, users.Filter{Limit: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{jim.Id, jane.Id}, ids(found))
+
+		found, err = repository.Search(ctx, users.Filter{BeforeId: jane.Id, Limit: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{john.Id}, ids(found))
+
+		found, err = repository.Search(ctx, users.Filter{Status: users.StatusActive, Role: access.RoleUser, Limit: 10})
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{john.Id}, ids(found))
+
+		found, err = repository.Search(ctx, users.Filter{Email: "jane@mail.com", Limit: 10})
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{jane.Id}, ids(found))
+		assert.Equal(t, access.RoleAdmin, found[0].Role)
+	})
+
+	t.Run("UpdateDuplicateEmail", func(t *testing.T) {
+		repository := newRepository(t)
+		first, second := newUser("john@mail.com"), newUser("jane@mail.com")
+		assert.Nil(t, repository.Save(ctx, &first))
+		assert.Nil(t, repository.Save(ctx, &second))
+
+		second.Email = first.Email
+		err := repository.Update(ctx, &second)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.Status())
+		assert.Equal(t, "email already registered", err.Message())
+	})
+
+	t.Run("UpdatePassword", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		assert.Nil(t, repository.UpdatePassword(ctx, &user, "new-hash", events.TypePasswordChanged))
+
+		_, err := repository.FindByEmailAndPassword(ctx, users.LoginRequest{Email: "john@mail.com", Password: "hash"})
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.Status())
+		found, err := repository.FindByEmailAndPassword(ctx, users.LoginRequest{Email: "john@mail.com", Password: "new-hash"})
+		assert.Nil(t, err)
+		assert.Equal(t, user.Id, found.Id)
+	})
+
+	t.Run("FindByEmailAndPassword", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		found, err := repository.FindByEmailAndPassword(ctx, users.LoginRequest{Email: "john@mail.com", Password: "hash"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, users.User{Id: user.Id, Name: "John", Email: "john@mail.com", TelegramUser: "@john", TimeZone: "UTC", Status: users.StatusActive, Role: access.RoleUser}, *found)
+	})
+
+	t.Run("FindByEmailAndPasswordInvalidCredentials", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		for _, login := range []users.LoginRequest{{Email: "john@mail.com", Password: "wrong"}, {Email: "jane@mail.com", Password: "hash"}} {
+			found, err := repository.FindByEmailAndPassword(ctx, login)
+
+			assert.Nil(t, found)
+			assert.NotNil(t, err)
+			assert.Equal(t, 404, err.Status())
+			assert.Equal(t, "invalid user credentials", err.Message())
+		}
+	})
+
+	t.Run("FindByEmailAndPasswordInactiveUser", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		user.Status = "pending"
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		found, err := repository.FindByEmailAndPassword(ctx, users.LoginRequest{Email: "john@mail.com", Password: "hash"})
+
+		assert.Nil(t, found)
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.Status())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &user))
+
+		assert.Nil(t, repository.Delete(ctx, &user, events.TypeUserDeleted))
+
+		_, err := repository.Get(ctx, user.Id)
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.Status())
+
+		again := newUser("john@mail.com")
+		assert.Nil(t, repository.Save(ctx, &again))
+	})
+
+	t.Run("DeleteNotFound", func(t *testing.T) {
+		repository := newRepository(t)
+
+		assert.Nil(t, repository.Delete(ctx, &users.User{Id: 1}))
+	})
+
+	t.Run("CanceledContext", func(t *testing.T) {
+		repository := newRepository(t)
+		user := newUser("john@mail.com")
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := repository.Save(canceled, &user)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, StatusClientClosedRequest, err.Status())
+
+		_, err = repository.Get(canceled, 1)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, StatusClientClosedRequest, err.Status())
+	})
+}
+
+func TestMemoryUsersRepositoryContract(t *testing.T) {
+	testUsersRepositoryContract(t, func(t *testing.T) userRepositoryInterface {
+		return NewMemoryUsersRepository()
+	})
+}
+
+func TestMemoryUsersRepositoryEvents(t *testing.T) {
+	repository := NewMemoryUsersRepository()
+	user := users.User{Name: "John", Email: "john@mail.com", Status: users.StatusActive}
+
+	assert.Nil(t, repository.Save(context.Background(), &user, events.TypeUserCreated))
+	assert.Nil(t, repository.Delete(context.Background(), &user, events.TypeUserDeleted))
+
+	recorded := repository.Events()
+	assert.Equal(t, 2, len(recorded))
+	assert.Equal(t, events.TypeUserCreated, recorded[0].Type)
+	assert.Equal(t, events.TypeUserDeleted, recorded[1].Type)
+	assert.Equal(t, user.Id, recorded[1].UserId)
+}
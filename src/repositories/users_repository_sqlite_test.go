@@ -0,0 +1,159 @@
+//go:build cgo
+
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/migrations"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/domain/webhooks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withSQLite runs the test against a migrated SQLite database in a temporary file,
+// the way the API runs locally with users_db_driver=sqlite.
+func withSQLite(t *testing.T) {
+	db, err := sql.Open(users_db.SQLite.Driver, "file:"+filepath.Join(t.TempDir(), "users.db")+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := migrations.Load(users_db.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.New(db, users_db.SQLite, all).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	previousClient, previousDialect := users_db.Client, users_db.Current
+	users_db.Client, users_db.Current = db, users_db.SQLite
+	t.Cleanup(func() {
+		users_db.Client, users_db.Current = previousClient, previousDialect
+		db.Close()
+	})
+}
+
+func TestUsersRepositoryContractOnSQLite(t *testing.T) {
+	testUsersRepositoryContract(t, func(t *testing.T) userRepositoryInterface {
+		withSQLite(t)
+		return UsersRepository
+	})
+}
+
+func TestUsersRepositoryOutboxOnSQLite(t *testing.T) {
+	withSQLite(t)
+	ctx := context.Background()
+
+	user := users.User{Name: "John", Email: "john@mail.com", Status: users.StatusActive, Password: "hash", DateCreated: "2022-01-01 10:00:00"}
+	assert.Nil(t, UsersRepository.Save(ctx, &user, events.TypeUserCreated))
+	assert.Nil(t, UsersRepository.Update(ctx, &user, events.TypeUserUpdated))
+	assert.Nil(t, UsersRepository.Delete(ctx, &user, events.TypeUserDeleted))
+
+	var outboxed int
+	assert.Nil(t, users_db.Client.QueryRow("SELECT COUNT(*) FROM outbox;").Scan(&outboxed))
+	assert.Equal(t, 3, outboxed)
+}
+
+func TestAuditLogIsAppendOnlyOnSQLite(t *testing.T) {
+	withSQLite(t)
+	ctx := context.Background()
+
+	first := audit.Entry{Action: audit.ActionLoginFailed, Outcome: audit.OutcomeFailure, TargetUserId: 7, Details: map[string]string{"reason": "invalid_credentials"}, DateCreated: "2022-09-06 10:00:00"}
+	second := audit.Entry{Action: audit.ActionLoginSucceeded, Outcome: audit.OutcomeSuccess, ActorId: 7, TargetUserId: 7, DateCreated: "2022-09-06 10:01:00"}
+	assert.Nil(t, AuditRepository.Append(ctx, &first))
+	assert.Nil(t, AuditRepository.Append(ctx, &second))
+
+	found, err := AuditRepository.Find(ctx, audit.Filter{Outcome: audit.OutcomeFailure, Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(found))
+	assert.Equal(t, "invalid_credentials", found[0].Details["reason"])
+
+	_, updateErr := users_db.Client.Exec("UPDATE audit_log SET outcome='success' WHERE id=?;", first.Id)
+	assert.NotNil(t, updateErr)
+	_, deleteErr := users_db.Client.Exec("DELETE FROM audit_log;")
+	assert.NotNil(t, deleteErr)
+
+	walked := make([]int64, 0)
+	assert.Nil(t, AuditRepository.Walk(ctx, audit.Filter{}, func(entry audit.Entry) error {
+		walked = append(walked, entry.Id)
+		return nil
+	}))
+	assert.Equal(t, []int64{first.Id, second.Id}, walked)
+}
+
+func TestAlertRulesAndPlansOnSQLite(t *testing.T) {
+	withSQLite(t)
+	ctx := context.Background()
+
+	enabled := true
+	rule := alerts.AlertRule{UserId: 7, Token: "BTC", Type: "price_above", Threshold: 50000, Enabled: &enabled, DateCreated: "2022-01-01 10:00:00"}
+	assert.Nil(t, AlertRulesRepository.Save(ctx, &rule))
+	assert.NotEqual(t, int64(0), rule.Id)
+
+	found, err := AlertRulesRepository.Get(ctx, rule.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "BTC", found.Token)
+	assert.True(t, *found.Enabled)
+	assert.Equal(t, "", found.ExpiresAt)
+	assert.Equal(t, "2022-01-01 10:00:00", found.DateCreated)
+
+	assignment := plans.Assignment{UserId: 7, Plan: "pro", EffectiveFrom: "2022-01-01 00:00:00", DateCreated: "2022-01-01 00:00:00"}
+	assert.Nil(t, PlanAssignmentsRepository.Save(ctx, &assignment))
+	active, err := PlanAssignmentsRepository.GetActive(ctx, 7, "2022-02-01 00:00:00")
+	assert.Nil(t, err)
+	assert.Equal(t, assignment.Id, active.Id)
+	assert.Equal(t, "", active.EffectiveUntil)
+}
+
+func TestQuietHoursUpsertOnSQLite(t *testing.T) {
+	withSQLite(t)
+	ctx := context.Background()
+
+	quietHours := notifications.QuietHours{UserId: 7, Enabled: true, Windows: []notifications.QuietWindow{{Weekday: "monday", Start: "22:00", End: "07:00"}}}
+	assert.Nil(t, QuietHoursRepository.Save(ctx, &quietHours))
+	quietHours.UrgentBypass = true
+	quietHours.Windows = []notifications.QuietWindow{}
+	assert.Nil(t, QuietHoursRepository.Save(ctx, &quietHours))
+
+	found, err := QuietHoursRepository.GetByUserId(ctx, 7)
+	assert.Nil(t, err)
+	assert.True(t, found.UrgentBypass)
+	assert.Equal(t, 0, len(found.Windows))
+}
+
+func TestWebhookQueueOnSQLite(t *testing.T) {
+	withSQLite(t)
+	ctx := context.Background()
+
+	webhook := webhooks.Webhook{Url: "https://example.com/hook", Secret: "secret", EventTypes: []string{events.TypeUserCreated}, Enabled: true, DateCreated: "2022-01-01 10:00:00"}
+	assert.Nil(t, WebhooksRepository.Save(ctx, &webhook))
+	assert.Nil(t, WebhooksRepository.Enqueue(ctx, []webhooks.Pending{
+		{WebhookId: webhook.Id, EventId: "1", EventType: events.TypeUserCreated, Payload: "{}", NextAttemptAt: "2022-01-01 10:00:00", DateCreated: "2022-01-01 10:00:00"},
+	}))
+
+	due, err := WebhooksRepository.ClaimDue(ctx, "2022-01-01 10:00:00", "2022-01-01 10:01:00", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(due))
+	again, err := WebhooksRepository.ClaimDue(ctx, "2022-01-01 10:00:30", "2022-01-01 10:01:30", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(again))
+
+	delivery := webhooks.Delivery{WebhookId: webhook.Id, EventId: "1", EventType: events.TypeUserCreated, Attempt: 1, StatusCode: 200, Success: true, DateCreated: "2022-01-01 10:00:01"}
+	assert.Nil(t, WebhooksRepository.SaveDelivery(ctx, &delivery))
+	assert.Nil(t, WebhooksRepository.Dequeue(ctx, due[0].Id))
+
+	deliveries, err := WebhooksRepository.FindDeliveries(ctx, webhook.Id, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, "2022-01-01 10:00:01", deliveries[0].DateCreated)
+}
@@ -1,13 +1,16 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 	"tokenalert_user-api/src/datasources/mysql/users_db"
 	"tokenalert_user-api/src/domain/users"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
 	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +25,13 @@ func NewMock() (*sql.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+// expectTxPrepare expects query to be prepared on the pool for the statement cache
+// and then on the connection of the running transaction.
+func expectTxPrepare(mock sqlmock.Sqlmock, query string) *sqlmock.ExpectedPrepare {
+	mock.ExpectPrepare(query)
+	return mock.ExpectPrepare(query)
+}
+
 func TestSaveOK(t *testing.T) {
 
 	db, mock := NewMock()
@@ -32,14 +42,19 @@ func TestSaveOK(t *testing.T) {
 
 	user := users.User{Name: "John", Email: "john@mail.com", TelegramUser: "@john", Password: "admin", DateCreated: "2022-01-01"}
 
-	query := "INSERT INTO users(name, email, telegram_user, status, password, date_created) VALUES(?, ?, ?, ?, ?, ?);"
-	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.Status, user.Password, user.DateCreated).WillReturnResult(sqlmock.NewResult(667, 1))
+	query := "INSERT INTO users(name, email, telegram_user, time_zone, status, role, password, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?);"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.TimeZone, user.Status, user.Role, user.Password, user.DateCreated).WillReturnResult(sqlmock.NewResult(667, 1))
+	outbox := expectTxPrepare(mock, "INSERT INTO outbox(event_id, event_type, user_id, payload, date_created) VALUES(?, ?, ?, ?, ?);")
+	outbox.ExpectExec().WithArgs(sqlmock.AnyArg(), "user.created", int64(667), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	err := UsersRepository.Save(&user)
+	err := UsersRepository.Save(context.Background(), &user, "user.created")
 	
 	assert.NoError(t, err)
 	assert.Equal(t, int64(667), user.Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestSavePrepareQueryFailed(t *testing.T) {
@@ -53,10 +68,11 @@ func TestSavePrepareQueryFailed(t *testing.T) {
 	user := users.User{Name: "John", Email: "john@mail.com", TelegramUser: "@john", Password: "admin", DateCreated: "2022-01-01"}
 
 	query := "INSERT INTO users(name, email, telegram_user, status, password, date_created) VALUES(?, ?, ?, ?, ?);"
-	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.Status, user.Password, user.DateCreated).WillReturnResult(sqlmock.NewResult(667, 1))
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.TimeZone, user.Status, user.Role, user.Password, user.DateCreated).WillReturnResult(sqlmock.NewResult(667, 1))
 
-	err := UsersRepository.Save(&user)
+	err := UsersRepository.Save(context.Background(), &user)
 	
 	assert.Error(t, err)
 	assert.Equal(t, 500, err.Status())	
@@ -72,11 +88,13 @@ func TestSaveExecutionFailed(t *testing.T) {
 
 	user := users.User{Name: "John", Email: "john@mail.com", TelegramUser: "@john", Password: "admin", DateCreated: "2022-01-01"}
 
-	query := "INSERT INTO users(name, email, telegram_user, status, password, date_created) VALUES(?, ?, ?, ?, ?, ?);"
-	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.Status, user.Password, user.DateCreated).WillReturnError(rest_errors.NewInternalServerError("internal_server_error", errors.New("database error")))
+	query := "INSERT INTO users(name, email, telegram_user, time_zone, status, role, password, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?);"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.TimeZone, user.Status, user.Role, user.Password, user.DateCreated).WillReturnError(rest_errors.NewInternalServerError("internal_server_error", errors.New("database error")))
+	mock.ExpectRollback()
 
-	err := UsersRepository.Save(&user)
+	err := UsersRepository.Save(context.Background(), &user)
 	
 	assert.Error(t, err)
 	assert.Equal(t, 500, err.Status())	
@@ -91,18 +109,21 @@ func TestGetOK(t *testing.T) {
 		users_db.Client.Close()
 	}()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "date_created", "status"}).
-		AddRow(667, "john", "john@mail.com", "@john", "2022-01-01", "active")		
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "time_zone", "status", "role", "date_created"}).
+		AddRow(667, "john", "john@mail.com", "@john", "Europe/Madrid", "active", "user", "2022-01-01")		
 
-	query := "SELECT id, name, email, telegram_user, status, date_created FROM users WHERE id=?;"
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE id=?;"
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().WithArgs(667).WillReturnRows(rows)
 
-	user, err := UsersRepository.Get(667)
+	user, err := UsersRepository.Get(context.Background(), 667)
 	
 	assert.NoError(t, err)
 	assert.Equal(t, int64(667), user.Id)
 	assert.Equal(t, "@john", user.TelegramUser)
+	assert.Equal(t, "Europe/Madrid", user.TimeZone)
+	assert.Equal(t, "active", user.Status)
+	assert.Equal(t, "2022-01-01", user.DateCreated)
 }
 
 func TestGetPrepareQueryFailed(t *testing.T) {
@@ -113,10 +134,10 @@ func TestGetPrepareQueryFailed(t *testing.T) {
 		users_db.Client.Close()
 	}()
 
-	query := "SELECT id, name, email, telegram_user, status, date_created FROM users WHERE id=?;"	
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE id=?;"	
 	expected := mock.ExpectPrepare(query).WillReturnError(rest_errors.NewInternalServerError("internal_server_error_prepare", errors.New("database error")))
 	
-	_, err := UsersRepository.Get(667)
+	_, err := UsersRepository.Get(context.Background(), 667)
 	
 	assert.NotNil(t, err)
 	assert.NotNil(t, expected)
@@ -132,11 +153,11 @@ func TestGetExecutionFailed(t *testing.T) {
 		users_db.Client.Close()
 	}()
 
-	query := "SELECT id, name, email, telegram_user, status, date_created FROM users WHERE id=?;"
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE id=?;"
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().WithArgs(667).WillReturnError(rest_errors.NewInternalServerError("internal_server_error", errors.New("database error")))
 
-	_, err := UsersRepository.Get(667)
+	_, err := UsersRepository.Get(context.Background(), 667)
 	
 	assert.Error(t, err)
 	assert.Equal(t, 500, err.Status())	
@@ -152,14 +173,14 @@ func TestFindByEmailAndPasswordOK(t *testing.T) {
 	}()
 
 	loginRequest := users.LoginRequest{Email: "john@mail.com", Password: "ABC123"}
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "status"}).
-		AddRow(667, "john", "john@mail.com", "@john", "active")		
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "time_zone", "status", "role"}).
+		AddRow(667, "john", "john@mail.com", "@john", "UTC", "active", "user")		
 
-	query := "SELECT id, name, email, telegram_user, status FROM users WHERE email=? AND password=? AND status=?"
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role FROM users WHERE email=? AND password=? AND status=?"
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().WithArgs(loginRequest.Email, loginRequest.Password, users.StatusActive).WillReturnRows(rows)
 
-	user, err := UsersRepository.FindByEmailAndPassword(loginRequest)
+	user, err := UsersRepository.FindByEmailAndPassword(context.Background(), loginRequest)
 	
 	assert.NoError(t, err)
 	assert.Equal(t, int64(667), user.Id)
@@ -176,10 +197,10 @@ func TestFindByEmailAndPasswordPrepareQueryFailed(t *testing.T) {
 	}()
 
 	loginRequest := users.LoginRequest{Email: "john@mail.com", Password: "ABC123"}
-	query := "SELECT id, name, email, telegram_user, status FROM users WHERE email=? AND password=? AND status=?"
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role FROM users WHERE email=? AND password=? AND status=?"
 	expected := mock.ExpectPrepare(query).WillReturnError(rest_errors.NewInternalServerError("internal_server_error_prepare", errors.New("database error")))
 	
-	_, err := UsersRepository.FindByEmailAndPassword(loginRequest)
+	_, err := UsersRepository.FindByEmailAndPassword(context.Background(), loginRequest)
 	
 	assert.NotNil(t, err)
 	assert.NotNil(t, expected)
@@ -196,13 +217,307 @@ func TestFindByEmailAndPasswordExecutionFailed(t *testing.T) {
 	}()
 
 	loginRequest := users.LoginRequest{Email: "john@mail.com", Password: "ABC123"}
-	query := "SELECT id, name, email, telegram_user, status FROM users WHERE email=? AND password=? AND status=?"
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role FROM users WHERE email=? AND password=? AND status=?"
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().WithArgs(667).WillReturnError(rest_errors.NewInternalServerError("internal_server_error", errors.New("database error")))
 
-	_, err := UsersRepository.FindByEmailAndPassword(loginRequest)
+	_, err := UsersRepository.FindByEmailAndPassword(context.Background(), loginRequest)
 	
 	assert.Error(t, err)
 	assert.Equal(t, 500, err.Status())	
 	assert.Equal(t, "error when trying to find user", err.Message())	
-}
\ No newline at end of file
+}
+func TestGetNotFound(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "time_zone", "status", "role", "date_created"})
+
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE id=?;"
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().WithArgs(667).WillReturnRows(rows)
+
+	_, err := UsersRepository.Get(context.Background(), 667)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+	assert.Equal(t, "user not found", err.Message())
+}
+
+func TestUpdateOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	user := users.User{Id: 667, Name: "John", Email: "john@mail.com", TelegramUser: "@john", TimeZone: "America/Argentina/Buenos_Aires"}
+
+	query := "UPDATE users SET name=?, email=?, telegram_user=?, time_zone=? WHERE id=?;"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs(user.Name, user.Email, user.TelegramUser, user.TimeZone, user.Id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := UsersRepository.Update(context.Background(), &user)
+
+	assert.Nil(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateExecutionFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	user := users.User{Id: 667, Name: "John", Email: "john@mail.com"}
+
+	query := "UPDATE users SET name=?, email=?, telegram_user=?, time_zone=? WHERE id=?;"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	err := UsersRepository.Update(context.Background(), &user)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error updating user", err.Message())
+}
+
+func TestUpdatePasswordOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	user := users.User{Id: 667, Name: "John", Email: "john@mail.com"}
+
+	query := "UPDATE users SET password=? WHERE id=?;"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs("hash", 667).WillReturnResult(sqlmock.NewResult(0, 1))
+	outbox := expectTxPrepare(mock, "INSERT INTO outbox(event_id, event_type, user_id, payload, date_created) VALUES(?, ?, ?, ?, ?);")
+	outbox.ExpectExec().WithArgs(sqlmock.AnyArg(), "user.password_changed", int64(667), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := UsersRepository.UpdatePassword(context.Background(), &user, "hash", "user.password_changed")
+
+	assert.Nil(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAccessOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	user := users.User{Id: 667, Name: "John", Email: "john@mail.com", Status: users.StatusSuspended, Role: "user"}
+
+	query := "UPDATE users SET status=?, role=? WHERE id=?;"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs(users.StatusSuspended, "user", 667).WillReturnResult(sqlmock.NewResult(0, 1))
+	outbox := expectTxPrepare(mock, "INSERT INTO outbox(event_id, event_type, user_id, payload, date_created) VALUES(?, ?, ?, ?, ?);")
+	outbox.ExpectExec().WithArgs(sqlmock.AnyArg(), "user.status_changed", int64(667), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := UsersRepository.UpdateAccess(context.Background(), &user, "user.status_changed")
+
+	assert.Nil(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchUsersOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "telegram_user", "time_zone", "status", "role", "date_created"}).
+		AddRow(9, "Jane", "jane@mail.com", "", "UTC", "active", "support", "2022-01-01 10:00:00")
+
+	query := "SELECT id, name, email, telegram_user, time_zone, status, role, date_created FROM users WHERE role=? AND id<? ORDER BY id DESC LIMIT ?;"
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().WithArgs("support", int64(10), 5).WillReturnRows(rows)
+
+	result, err := UsersRepository.Search(context.Background(), users.Filter{Role: "support", BeforeId: 10, Limit: 5})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "support", result[0].Role)
+}
+
+func TestDeletePrepareQueryFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	query := "DELETE FROM users WHERE id=?;"
+	mock.ExpectBegin()
+	mock.ExpectPrepare(query).WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	err := UsersRepository.Delete(context.Background(), &users.User{Id: 667})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error deleting user", err.Message())
+}
+
+func TestDeleteRollsBackWhenOutboxFails(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	query := "DELETE FROM users WHERE id=?;"
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, query)
+	prep.ExpectExec().WithArgs(667).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO outbox(event_id, event_type, user_id, payload, date_created) VALUES(?, ?, ?, ?, ?);").WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	err := UsersRepository.Delete(context.Background(), &users.User{Id: 667}, "user.deleted")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error deleting user", err.Message())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTimeoutReturnGatewayTimeout(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	previous := users_db.Timeouts
+	users_db.Timeouts = users_db.OperationTimeouts{Default: time.Second, Operations: map[string]time.Duration{"users.get": 10 * time.Millisecond}}
+	defer func() {
+		users_db.Timeouts = previous
+	}()
+
+	prep := mock.ExpectPrepare(queryGetUser)
+	prep.ExpectQuery().WithArgs(667).WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := UsersRepository.Get(context.Background(), 667)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 504, err.Status())
+	assert.Equal(t, "database timeout", err.Message())
+}
+
+func TestSaveCanceledRequestReturnClientClosedRequest(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mock.ExpectBegin()
+
+	err := UsersRepository.Save(ctx, &users.User{Name: "John", Email: "john@mail.com"}, "user.created")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, StatusClientClosedRequest, err.Status())
+}
+
+func withReplica(t *testing.T) sqlmock.Sqlmock {
+	replica, replicaMock := NewMock()
+	replicas := users_db.NewReplicaSet()
+	replicas.Add("replica", replica)
+	previous := users_db.Replicas
+	users_db.Replicas = replicas
+	t.Cleanup(func() {
+		users_db.Replicas = previous
+		replica.Close()
+	})
+	return replicaMock
+}
+
+func TestGetUserReadsFromReplica(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+	replicaMock := withReplica(t)
+
+	replicaMock.ExpectPrepare(queryGetUser).ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+
+	user, err := UsersRepository.Get(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), user.Id)
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserFailsOverToPrimary(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+	replicaMock := withReplica(t)
+
+	replicaMock.ExpectPrepare(queryGetUser).ExpectQuery().WithArgs(1).WillReturnError(mysql.ErrInvalidConn)
+	prep := mock.ExpectPrepare(queryGetUser)
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+
+	for i := 0; i < 2; i++ {
+		user, err := UsersRepository.Get(context.Background(), 1)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), user.Id)
+	}
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserWithPrimaryReadsOwnWrites(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+	replicaMock := withReplica(t)
+
+	mock.ExpectPrepare(queryGetUser).ExpectQuery().WithArgs(1).WillReturnRows(userRows())
+
+	user, err := UsersRepository.Get(users_db.WithPrimary(context.Background()), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), user.Id)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
+}
@@ -0,0 +1,359 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/webhooks"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/utils/sql_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	queryInsertWebhook             = "INSERT INTO webhooks(url, secret, event_types, enabled, date_created) VALUES(?, ?, ?, ?, ?);"
+	queryGetWebhook                = "SELECT id, url, secret, event_types, enabled, date_created FROM webhooks WHERE id=?;"
+	queryFindWebhooks              = "SELECT id, url, secret, event_types, enabled, date_created FROM webhooks ORDER BY id;"
+	queryFindEnabledWebhooks       = "SELECT id, url, secret, event_types, enabled, date_created FROM webhooks WHERE enabled=? ORDER BY id;"
+	queryDeleteWebhook             = "DELETE FROM webhooks WHERE id=?;"
+	queryInsertWebhookDelivery     = "INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, attempt, status_code, success, error, date_created) VALUES(?, ?, ?, ?, ?, ?, ?, ?);"
+	queryFindWebhookDeliveries     = "SELECT id, webhook_id, event_id, event_type, attempt, status_code, success, error, date_created FROM webhook_deliveries WHERE webhook_id=? ORDER BY id DESC LIMIT ?;"
+	queryEnqueueWebhookDelivery    = "INSERT INTO webhook_queue(webhook_id, event_id, event_type, payload, attempts, next_attempt_at, date_created) VALUES(?, ?, ?, ?, ?, ?, ?);"
+	queryFindDueWebhookDeliveries  = "SELECT id, webhook_id, event_id, event_type, payload, attempts, next_attempt_at, date_created FROM webhook_queue WHERE next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?;"
+	queryClaimWebhookDelivery      = "UPDATE webhook_queue SET next_attempt_at=? WHERE id=?;"
+	queryRescheduleWebhookDelivery = "UPDATE webhook_queue SET attempts=?, next_attempt_at=? WHERE id=?;"
+	queryDequeueWebhookDelivery    = "DELETE FROM webhook_queue WHERE id=?;"
+)
+
+var (
+	WebhooksRepository webhooksRepositoryInterface = &webhooksRepository{}
+)
+
+type webhooksRepository struct {
+	session
+}
+
+type webhooksRepositoryInterface interface {
+	Save(context.Context, *webhooks.Webhook) rest_errors.RestErr
+	Get(context.Context, int64) (*webhooks.Webhook, rest_errors.RestErr)
+	FindAll(context.Context, bool) (webhooks.Webhooks, rest_errors.RestErr)
+	Delete(context.Context, int64) rest_errors.RestErr
+	SaveDelivery(context.Context, *webhooks.Delivery) rest_errors.RestErr
+	FindDeliveries(context.Context, int64, int) (webhooks.Deliveries, rest_errors.RestErr)
+	Enqueue(context.Context, []webhooks.Pending) rest_errors.RestErr
+	ClaimDue(context.Context, string, string, int) ([]webhooks.Pending, rest_errors.RestErr)
+	Reschedule(context.Context, int64, int, string) rest_errors.RestErr
+	Dequeue(context.Context, int64) rest_errors.RestErr
+}
+
+func scanWebhook(row rowScanner) (*webhooks.Webhook, error) {
+	var webhook webhooks.Webhook
+	var eventTypes string
+	if err := row.Scan(&webhook.Id, &webhook.Url, &webhook.Secret, &eventTypes, &webhook.Enabled, dbDateTime{&webhook.DateCreated}); err != nil {
+		return nil, err
+	}
+	webhook.EventTypes = make([]string, 0)
+	if eventTypes != "" {
+		webhook.EventTypes = strings.Split(eventTypes, ",")
+	}
+	return &webhook, nil
+}
+
+func (r *webhooksRepository) Save(ctx context.Context, webhook *webhooks.Webhook) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "webhooks.save")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.save")
+	defer cancel()
+
+	var webhookId int64
+	err := r.run(ctx, users_db.Current.ReturningId(queryInsertWebhook), func(stmt *sql.Stmt) error {
+		var err error
+		webhookId, err = insertedId(ctx, stmt, webhook.Url, webhook.Secret, strings.Join(webhook.EventTypes, ","), webhook.Enabled, webhook.DateCreated)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare save webhook statement", err)
+			return databaseError(ctx, err, "error saving webhook")
+		}
+		logging.Error(ctx, "error when trying to save webhook", err)
+		return databaseError(ctx, err, "error saving webhook")
+	}
+	webhook.Id = webhookId
+	return nil
+}
+
+func (r *webhooksRepository) Get(ctx context.Context, id int64) (*webhooks.Webhook, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "webhooks.get")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.get")
+	defer cancel()
+
+	var webhook *webhooks.Webhook
+	err := r.run(ctx, queryGetWebhook, func(stmt *sql.Stmt) error {
+		var err error
+		webhook, err = scanWebhook(stmt.QueryRowContext(ctx, id))
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare get webhook statement", err)
+			return nil, databaseError(ctx, err, "error fetching webhook")
+		}
+		if strings.Contains(err.Error(), sql_utils.ErrorNoRows) {
+			return nil, rest_errors.NewNotFoundError("webhook not found")
+		}
+		logging.Error(ctx, "error when trying to get webhook by id", err)
+		return nil, databaseError(ctx, err, "error fetching webhook")
+	}
+	return webhook, nil
+}
+
+func (r *webhooksRepository) FindAll(ctx context.Context, onlyEnabled bool) (webhooks.Webhooks, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "webhooks.find_all")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.find_all")
+	defer cancel()
+
+	query, args := queryFindWebhooks, []interface{}{}
+	if onlyEnabled {
+		query, args = queryFindEnabledWebhooks, []interface{}{true}
+	}
+
+	result := make(webhooks.Webhooks, 0)
+	err := r.run(ctx, query, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			webhook, err := scanWebhook(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, *webhook)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare find webhooks statement", err)
+			return nil, databaseError(ctx, err, "error fetching webhooks")
+		}
+		logging.Error(ctx, "error when trying to find webhooks", err)
+		return nil, databaseError(ctx, err, "error fetching webhooks")
+	}
+	return result, nil
+}
+
+func (r *webhooksRepository) Delete(ctx context.Context, id int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "webhooks.delete")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.delete")
+	defer cancel()
+
+	err := r.run(ctx, queryDeleteWebhook, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, id)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare delete webhook statement", err)
+			return databaseError(ctx, err, "error deleting webhook")
+		}
+		logging.Error(ctx, "error when trying to delete webhook", err)
+		return databaseError(ctx, err, "error deleting webhook")
+	}
+	return nil
+}
+
+func (r *webhooksRepository) SaveDelivery(ctx context.Context, delivery *webhooks.Delivery) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "webhooks.save_delivery")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.save_delivery")
+	defer cancel()
+
+	var deliveryId int64
+	err := r.run(ctx, users_db.Current.ReturningId(queryInsertWebhookDelivery), func(stmt *sql.Stmt) error {
+		var err error
+		deliveryId, err = insertedId(ctx, stmt, delivery.WebhookId, delivery.EventId, delivery.EventType, delivery.Attempt,
+			delivery.StatusCode, delivery.Success, delivery.Error, delivery.DateCreated)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare save webhook delivery statement", err)
+			return databaseError(ctx, err, "error saving webhook delivery")
+		}
+		logging.Error(ctx, "error when trying to save webhook delivery", err)
+		return databaseError(ctx, err, "error saving webhook delivery")
+	}
+	delivery.Id = deliveryId
+	return nil
+}
+
+// FindDeliveries returns the latest delivery attempts of the webhook, newest first.
+func (r *webhooksRepository) FindDeliveries(ctx context.Context, webhookId int64, limit int) (webhooks.Deliveries, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "webhooks.find_deliveries")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.find_deliveries")
+	defer cancel()
+
+	result := make(webhooks.Deliveries, 0)
+	err := r.run(ctx, queryFindWebhookDeliveries, func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, webhookId, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var delivery webhooks.Delivery
+			if err := rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.EventType, &delivery.Attempt,
+				&delivery.StatusCode, &delivery.Success, &delivery.Error, dbDateTime{&delivery.DateCreated}); err != nil {
+				return err
+			}
+			result = append(result, delivery)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare find webhook deliveries statement", err)
+			return nil, databaseError(ctx, err, "error fetching webhook deliveries")
+		}
+		logging.Error(ctx, "error when trying to find webhook deliveries", err)
+		return nil, databaseError(ctx, err, "error fetching webhook deliveries")
+	}
+	return result, nil
+}
+
+// Enqueue stores the deliveries all together or none of them.
+func (r *webhooksRepository) Enqueue(ctx context.Context, queued []webhooks.Pending) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "webhooks.enqueue")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.enqueue")
+	defer cancel()
+
+	err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+		return runIn(ctx, tx, queryEnqueueWebhookDelivery, func(stmt *sql.Stmt) error {
+			for _, pending := range queued {
+				if _, err := stmt.ExecContext(ctx, pending.WebhookId, pending.EventId, pending.EventType, pending.Payload,
+					pending.Attempts, pending.NextAttemptAt, pending.DateCreated); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logging.Error(ctx, "error when trying to enqueue webhook deliveries", err)
+		return databaseError(ctx, err, "error queueing webhook deliveries")
+	}
+	return nil
+}
+
+// ClaimDue returns up to limit deliveries whose next attempt is due at now, pushing
+// that attempt back to until so no one else picks them while they are being made. A
+// delivery whose claimer dies is attempted again once until has passed.
+func (r *webhooksRepository) ClaimDue(ctx context.Context, now string, until string, limit int) ([]webhooks.Pending, rest_errors.RestErr) {
+	ctx, end := startQuery(ctx, "webhooks.claim_due")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.claim_due")
+	defer cancel()
+
+	var claimed []webhooks.Pending
+	err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		if claimed, err = findDueWebhookDeliveries(ctx, tx, now, limit); err != nil {
+			return err
+		}
+
+		return runIn(ctx, tx, queryClaimWebhookDelivery, func(claimStmt *sql.Stmt) error {
+			for _, pending := range claimed {
+				if _, err := claimStmt.ExecContext(ctx, until, pending.Id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logging.Error(ctx, "error when trying to claim due webhook deliveries", err)
+		return nil, databaseError(ctx, err, "error fetching webhook deliveries")
+	}
+	return claimed, nil
+}
+
+// Reschedule records the attempts made so far and when to make the next one.
+func (r *webhooksRepository) Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt string) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "webhooks.reschedule")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.reschedule")
+	defer cancel()
+
+	err := r.run(ctx, queryRescheduleWebhookDelivery, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, attempts, nextAttemptAt, id)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare reschedule webhook delivery statement", err)
+			return databaseError(ctx, err, "error rescheduling webhook delivery")
+		}
+		logging.Error(ctx, "error when trying to reschedule webhook delivery", err)
+		return databaseError(ctx, err, "error rescheduling webhook delivery")
+	}
+	return nil
+}
+
+func (r *webhooksRepository) Dequeue(ctx context.Context, id int64) rest_errors.RestErr {
+	ctx, end := startQuery(ctx, "webhooks.dequeue")
+	defer end()
+	ctx, cancel := users_db.WithTimeout(ctx, "webhooks.dequeue")
+	defer cancel()
+
+	err := r.run(ctx, queryDequeueWebhookDelivery, func(stmt *sql.Stmt) error {
+		_, err := stmt.ExecContext(ctx, id)
+		return err
+	})
+	if err != nil {
+		if errors.As(err, &prepareError{}) {
+			logging.Error(ctx, "error when trying to prepare dequeue webhook delivery statement", err)
+			return databaseError(ctx, err, "error dequeueing webhook delivery")
+		}
+		logging.Error(ctx, "error when trying to dequeue webhook delivery", err)
+		return databaseError(ctx, err, "error dequeueing webhook delivery")
+	}
+	return nil
+}
+
+func findDueWebhookDeliveries(ctx context.Context, tx *sql.Tx, now string, limit int) ([]webhooks.Pending, error) {
+	due := make([]webhooks.Pending, 0)
+	err := runIn(ctx, tx, users_db.Current.ForUpdate(queryFindDueWebhookDeliveries), func(stmt *sql.Stmt) error {
+		rows, err := stmt.QueryContext(ctx, now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var pending webhooks.Pending
+			if err := rows.Scan(&pending.Id, &pending.WebhookId, &pending.EventId, &pending.EventType, &pending.Payload,
+				&pending.Attempts, dbDateTime{&pending.NextAttemptAt}, dbDateTime{&pending.DateCreated}); err != nil {
+				return err
+			}
+			due = append(due, pending)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/webhooks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookColumns = []string{"id", "url", "secret", "event_types", "enabled", "date_created"}
+
+func TestSaveWebhookOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	webhook := webhooks.Webhook{Url: "https://example.com/hook", Secret: "secret", EventTypes: []string{"user.created", "user.deleted"}, Enabled: true, DateCreated: "2022-09-06 10:00:00"}
+
+	prep := mock.ExpectPrepare(queryInsertWebhook)
+	prep.ExpectExec().WithArgs(webhook.Url, "secret", "user.created,user.deleted", true, webhook.DateCreated).WillReturnResult(sqlmock.NewResult(3, 1))
+
+	err := WebhooksRepository.Save(context.Background(), &webhook)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), webhook.Id)
+}
+
+func TestGetWebhookNotFound(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryGetWebhook)
+	prep.ExpectQuery().WithArgs(3).WillReturnRows(sqlmock.NewRows(webhookColumns))
+
+	_, err := WebhooksRepository.Get(context.Background(), 3)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestFindEnabledWebhooksOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows(webhookColumns).
+		AddRow(3, "https://example.com/hook", "secret", "user.created", true, "2022-09-06 10:00:00").
+		AddRow(4, "https://example.com/all", "secret", "", true, "2022-09-06 10:00:00")
+
+	prep := mock.ExpectPrepare(queryFindEnabledWebhooks)
+	prep.ExpectQuery().WithArgs(true).WillReturnRows(rows)
+
+	result, err := WebhooksRepository.FindAll(context.Background(), true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, []string{"user.created"}, result[0].EventTypes)
+	assert.Equal(t, []string{}, result[1].EventTypes)
+}
+
+func TestSaveWebhookDeliveryOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	delivery := webhooks.Delivery{WebhookId: 3, EventId: "abc", EventType: "user.created", Attempt: 2, StatusCode: 503, Error: "webhook answered with status 503", DateCreated: "2022-09-06 10:00:00"}
+
+	prep := mock.ExpectPrepare(queryInsertWebhookDelivery)
+	prep.ExpectExec().WithArgs(3, "abc", "user.created", 2, 503, false, delivery.Error, delivery.DateCreated).WillReturnResult(sqlmock.NewResult(9, 1))
+
+	err := WebhooksRepository.SaveDelivery(context.Background(), &delivery)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9), delivery.Id)
+}
+
+func TestFindWebhookDeliveriesExecutionFailed(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	prep := mock.ExpectPrepare(queryFindWebhookDeliveries)
+	prep.ExpectQuery().WithArgs(3, 100).WillReturnError(errors.New("database error"))
+
+	_, err := WebhooksRepository.FindDeliveries(context.Background(), 3, 100)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error fetching webhook deliveries", err.Message())
+}
+
+func TestEnqueueWebhookDeliveriesRollsBackOnError(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	queued := []webhooks.Pending{
+		{WebhookId: 1, EventId: "evt-1", EventType: "user.created", Payload: "{}", NextAttemptAt: "2022-09-10 10:00:00", DateCreated: "2022-09-10 10:00:00"},
+		{WebhookId: 2, EventId: "evt-1", EventType: "user.created", Payload: "{}", NextAttemptAt: "2022-09-10 10:00:00", DateCreated: "2022-09-10 10:00:00"},
+	}
+
+	mock.ExpectBegin()
+	prep := expectTxPrepare(mock, queryEnqueueWebhookDelivery)
+	prep.ExpectExec().WithArgs(1, "evt-1", "user.created", "{}", 0, "2022-09-10 10:00:00", "2022-09-10 10:00:00").WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().WithArgs(2, "evt-1", "user.created", "{}", 0, "2022-09-10 10:00:00", "2022-09-10 10:00:00").WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	err := WebhooksRepository.Enqueue(context.Background(), queued)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error queueing webhook deliveries", err.Message())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimDueWebhookDeliveriesOK(t *testing.T) {
+
+	db, mock := NewMock()
+	users_db.Client = db
+	defer func() {
+		users_db.Client.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "attempts", "next_attempt_at", "date_created"}).
+		AddRow(4, 1, "evt-1", "user.created", "{}", 2, "2022-09-10 10:00:00", "2022-09-10 09:59:00")
+
+	mock.ExpectBegin()
+	expectTxPrepare(mock, "SELECT id, webhook_id, event_id, event_type, payload, attempts, next_attempt_at, date_created FROM webhook_queue WHERE next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE;").
+		ExpectQuery().WithArgs("2022-09-10 10:00:05", 10).WillReturnRows(rows)
+	expectTxPrepare(mock, queryClaimWebhookDelivery).ExpectExec().WithArgs("2022-09-10 10:01:05", 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	due, err := WebhooksRepository.ClaimDue(context.Background(), "2022-09-10 10:00:05", "2022-09-10 10:01:05", 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(due))
+	assert.Equal(t, int64(4), due[0].Id)
+	assert.Equal(t, 2, due[0].Attempts)
+	assert.Equal(t, "evt-1", due[0].EventId)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/tracing"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	AdminService adminServiceInterface = &adminService{}
+)
+
+type adminService struct{}
+
+type adminServiceInterface interface {
+	Authorize(context.Context, int64, string) (*users.User, rest_errors.RestErr)
+	SearchUsers(context.Context, users.Filter) (users.Users, rest_errors.RestErr)
+	ChangeStatus(context.Context, int64, string) (*users.User, rest_errors.RestErr)
+	ChangeRole(context.Context, int64, access.RoleRequest) (*users.User, rest_errors.RestErr)
+}
+
+func newForbiddenError(message string) rest_errors.RestErr {
+	return rest_errors.NewRestError(message, http.StatusForbidden, "forbidden", nil)
+}
+
+// Authorize loads the caller and checks its role grants permission. The caller is
+// read from the primary, past the users cache and the replicas, so a revoked role or
+// a suspension applies right away. Denials are audited, as they may be someone
+// probing for access.
+func (s *adminService) Authorize(ctx context.Context, callerId int64, permission string) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "adminService.Authorize")
+	defer span.End()
+
+	if callerId == 0 {
+		return nil, rest_errors.NewUnauthorizedError("unknown caller")
+	}
+	caller, err := repositories.UsersRepository.Get(users_db.WithPrimary(ctx), callerId)
+	if err != nil {
+		if err.Status() == http.StatusNotFound {
+			return nil, rest_errors.NewUnauthorizedError("unknown caller")
+		}
+		return nil, err
+	}
+	if !caller.Can(permission) {
+		entry := newAuditEntry(ctx, audit.ActionAccessDenied, audit.OutcomeFailure, 0)
+		entry.ActorId = caller.Id
+		entry.Details = map[string]string{"permission": permission, "role": caller.Role, "status": caller.Status}
+		AuditService.Record(ctx, entry)
+		return nil, newForbiddenError("missing permission " + permission)
+	}
+	return caller, nil
+}
+
+func (s *adminService) SearchUsers(ctx context.Context, filter users.Filter) (users.Users, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "adminService.SearchUsers")
+	defer span.End()
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return repositories.UsersRepository.Search(ctx, filter)
+}
+
+// ChangeStatus suspends or reactivates a user. Suspended users can not log in, nor
+// act with any permission. Only those who can change roles can change the status of
+// staff, so support can not lock admins out.
+func (s *adminService) ChangeStatus(ctx context.Context, userId int64, status string) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "adminService.ChangeStatus")
+	defer span.End()
+
+	if status != users.StatusActive && status != users.StatusSuspended {
+		return nil, rest_errors.NewBadRequestError("invalid status " + status)
+	}
+	actor := audit.ActorFrom(ctx)
+	if actor.UserId == userId {
+		return nil, newForbiddenError("can not change your own status")
+	}
+	// The change is made on what the primary holds, not on a stale cached or
+	// replicated copy.
+	user, err := repositories.UsersRepository.Get(users_db.WithPrimary(ctx), userId)
+	if err != nil {
+		return nil, err
+	}
+	if access.IsStaff(user.Role) && !access.Can(actor.Role, access.PermissionRolesChange) {
+		return nil, newForbiddenError("can not change the status of staff")
+	}
+	if user.Status == status {
+		return user, nil
+	}
+
+	previous := *user
+	user.Status = status
+	if err := repositories.UsersRepository.UpdateAccess(ctx, user, events.TypeStatusChanged); err != nil {
+		return nil, err
+	}
+	entry := newAuditEntry(ctx, audit.ActionStatusChanged, audit.OutcomeSuccess, userId)
+	entry.Changes = audit.ProfileChanges(previous, *user)
+	AuditService.Record(ctx, entry)
+	return user, nil
+}
+
+// ChangeRole gives a user another role. Nobody changes their own, so the last admin
+// can not demote themselves by mistake.
+func (s *adminService) ChangeRole(ctx context.Context, userId int64, request access.RoleRequest) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "adminService.ChangeRole")
+	defer span.End()
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if audit.ActorFrom(ctx).UserId == userId {
+		return nil, newForbiddenError("can not change your own role")
+	}
+	user, err := repositories.UsersRepository.Get(users_db.WithPrimary(ctx), userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == request.Role {
+		return user, nil
+	}
+
+	previous := *user
+	user.Role = request.Role
+	if err := repositories.UsersRepository.UpdateAccess(ctx, user, events.TypeRoleChanged); err != nil {
+		return nil, err
+	}
+	entry := newAuditEntry(ctx, audit.ActionRoleChanged, audit.OutcomeSuccess, userId)
+	entry.Changes = audit.ProfileChanges(previous, *user)
+	AuditService.Record(ctx, entry)
+	return user, nil
+}
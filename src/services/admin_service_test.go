@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/datasources/cache"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// withUsers makes the users repository mock return the given users by id, and
+// record the status and role changes made to them.
+func withUsers(stored ...users.User) *[]string {
+	byId := map[int64]users.User{}
+	for _, user := range stored {
+		byId[user.Id] = user
+	}
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		user, ok := byId[id]
+		if !ok {
+			return nil, rest_errors.NewNotFoundError("user not found")
+		}
+		return &user, nil
+	}
+	var eventTypes []string
+	updateUserAccessRepoFunc = func(user *users.User, types []string) rest_errors.RestErr {
+		byId[user.Id] = *user
+		eventTypes = append(eventTypes, types...)
+		return nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	return &eventTypes
+}
+
+func actingAs(id int64, role string) context.Context {
+	return audit.WithActor(context.Background(), audit.Actor{UserId: id, Role: role})
+}
+
+func TestAuthorize(t *testing.T) {
+	withUsers(
+		users.User{Id: 1, Status: users.StatusActive, Role: access.RoleAdmin},
+		users.User{Id: 2, Status: users.StatusActive, Role: access.RoleSupport},
+		users.User{Id: 3, Status: users.StatusSuspended, Role: access.RoleAdmin},
+	)
+	auditLog := withAuditMock()
+
+	caller, err := AdminService.Authorize(context.Background(), 1, access.PermissionRolesChange)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), caller.Id)
+
+	_, err = AdminService.Authorize(context.Background(), 2, access.PermissionUsersRead)
+	assert.Nil(t, err)
+
+	_, err = AdminService.Authorize(context.Background(), 2, access.PermissionRolesChange)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+
+	_, err = AdminService.Authorize(context.Background(), 3, access.PermissionUsersRead)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+
+	for _, callerId := range []int64{0, 404} {
+		_, err = AdminService.Authorize(context.Background(), callerId, access.PermissionUsersRead)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, err.Status())
+	}
+
+	assert.Equal(t, 2, len(auditLog.entries))
+	assert.Equal(t, audit.ActionAccessDenied, auditLog.entries[0].Action)
+	assert.Equal(t, int64(2), auditLog.entries[0].ActorId)
+	assert.Equal(t, access.PermissionRolesChange, auditLog.entries[0].Details["permission"])
+}
+
+func TestAuthorizeDeniesARevokedRoleRightAway(t *testing.T) {
+	stored := repositories.NewMemoryUsersRepository()
+	repositories.UsersRepository = repositories.NewCachedUsersRepository(stored, cache.NewMemoryStore(16), time.Minute, 0)
+	withAuditMock()
+	admin := users.User{Email: "ops@mail.com", Status: users.StatusActive, Role: access.RoleAdmin}
+	assert.Nil(t, stored.Save(context.Background(), &admin))
+
+	_, err := AdminService.Authorize(context.Background(), admin.Id, access.PermissionRolesChange)
+	assert.Nil(t, err)
+	_, err = repositories.UsersRepository.Get(context.Background(), admin.Id)
+	assert.Nil(t, err)
+
+	admin.Role = access.RoleUser
+	assert.Nil(t, stored.UpdateAccess(context.Background(), &admin))
+	_, err = AdminService.Authorize(context.Background(), admin.Id, access.PermissionRolesChange)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+}
+
+func TestSearchUsersValidatesFilter(t *testing.T) {
+	var received users.Filter
+	searchUsersRepoFunc = func(filter users.Filter) (users.Users, rest_errors.RestErr) {
+		received = filter
+		return users.Users{{Id: 1}}, nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+
+	result, err := AdminService.SearchUsers(context.Background(), users.Filter{Role: "Support"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, users.Filter{Role: access.RoleSupport, Limit: users.DefaultFilterLimit}, received)
+
+	_, err = AdminService.SearchUsers(context.Background(), users.Filter{Status: "deleted"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+}
+
+func TestChangeStatusSuspendsUser(t *testing.T) {
+	eventTypes := withUsers(users.User{Id: 7, Status: users.StatusActive, Role: access.RoleUser})
+	auditLog := withAuditMock()
+
+	user, err := AdminService.ChangeStatus(actingAs(2, access.RoleSupport), 7, users.StatusSuspended)
+
+	assert.Nil(t, err)
+	assert.True(t, lastUserReadFromPrimary)
+	assert.Equal(t, users.StatusSuspended, user.Status)
+	assert.Equal(t, []string{events.TypeStatusChanged}, *eventTypes)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionStatusChanged, auditLog.entries[0].Action)
+	assert.Equal(t, int64(2), auditLog.entries[0].ActorId)
+	assert.Equal(t, int64(7), auditLog.entries[0].TargetUserId)
+	assert.Equal(t, map[string]audit.Change{"status": {Before: users.StatusActive, After: users.StatusSuspended}}, auditLog.entries[0].Changes)
+
+	_, err = AdminService.ChangeStatus(actingAs(2, access.RoleSupport), 7, users.StatusSuspended)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*eventTypes))
+	assert.Equal(t, 1, len(auditLog.entries))
+}
+
+func TestChangeStatusOfStaffNeedsRolesChange(t *testing.T) {
+	eventTypes := withUsers(users.User{Id: 1, Status: users.StatusActive, Role: access.RoleAdmin})
+
+	_, err := AdminService.ChangeStatus(actingAs(2, access.RoleSupport), 1, users.StatusSuspended)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+	assert.Empty(t, *eventTypes)
+
+	_, err = AdminService.ChangeStatus(actingAs(3, access.RoleAdmin), 1, users.StatusSuspended)
+
+	assert.Nil(t, err)
+}
+
+func TestChangeStatusRejectsOwnAndInvalid(t *testing.T) {
+	withUsers(users.User{Id: 1, Status: users.StatusActive, Role: access.RoleAdmin})
+
+	_, err := AdminService.ChangeStatus(actingAs(1, access.RoleAdmin), 1, users.StatusSuspended)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+
+	_, err = AdminService.ChangeStatus(actingAs(2, access.RoleAdmin), 1, "banned")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+}
+
+func TestChangeRole(t *testing.T) {
+	eventTypes := withUsers(users.User{Id: 7, Status: users.StatusActive, Role: access.RoleUser})
+	auditLog := withAuditMock()
+
+	user, err := AdminService.ChangeRole(actingAs(1, access.RoleAdmin), 7, access.RoleRequest{Role: " Support "})
+
+	assert.Nil(t, err)
+	assert.True(t, lastUserReadFromPrimary)
+	assert.Equal(t, access.RoleSupport, user.Role)
+	assert.Equal(t, []string{events.TypeRoleChanged}, *eventTypes)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionRoleChanged, auditLog.entries[0].Action)
+	assert.Equal(t, map[string]audit.Change{"role": {Before: access.RoleUser, After: access.RoleSupport}}, auditLog.entries[0].Changes)
+
+	_, err = AdminService.ChangeRole(actingAs(1, access.RoleAdmin), 7, access.RoleRequest{Role: "root"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+
+	_, err = AdminService.ChangeRole(actingAs(7, access.RoleAdmin), 7, access.RoleRequest{Role: access.RoleAdmin})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+	assert.Equal(t, 1, len(auditLog.entries))
+}
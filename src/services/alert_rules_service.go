@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	AlertRulesService alertRulesServiceInterface = &alertRulesService{}
+)
+
+type alertRulesService struct{}
+
+type alertRulesServiceInterface interface {
+	CreateAlertRule(context.Context, alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr)
+	GetAlertRule(context.Context, int64, int64) (*alerts.AlertRule, rest_errors.RestErr)
+	GetUserAlertRules(context.Context, int64) (alerts.AlertRules, rest_errors.RestErr)
+	UpdateAlertRule(context.Context, alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr)
+	SetAlertRuleEnabled(context.Context, int64, int64, bool) (*alerts.AlertRule, rest_errors.RestErr)
+	DeleteAlertRule(context.Context, int64, int64) rest_errors.RestErr
+	StreamActiveAlertRules(context.Context, func(alerts.AlertRule) error) rest_errors.RestErr
+}
+
+func (s *alertRulesService) CreateAlertRule(ctx context.Context, rule alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	enabled := true
+	rule.Enabled = &enabled
+	rule.DateCreated = date_utils.GetNowDBFormat()
+	err := repositories.TransactionManager.Run(ctx, func(repos repositories.Repositories) rest_errors.RestErr {
+		if err := QuotaService.CheckAlertRuleQuota(ctx, repos, rule); err != nil {
+			return err
+		}
+		return repos.AlertRules.Save(ctx, &rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *alertRulesService) GetAlertRule(ctx context.Context, userId int64, ruleId int64) (*alerts.AlertRule, rest_errors.RestErr) {
+	rule, err := repositories.AlertRulesRepository.Get(ctx, ruleId)
+	if err != nil {
+		return nil, err
+	}
+	if rule.UserId != userId {
+		return nil, rest_errors.NewNotFoundError("alert rule not found")
+	}
+	return rule, nil
+}
+
+func (s *alertRulesService) GetUserAlertRules(ctx context.Context, userId int64) (alerts.AlertRules, rest_errors.RestErr) {
+	return repositories.AlertRulesRepository.FindByUserId(ctx, userId)
+}
+
+func (s *alertRulesService) UpdateAlertRule(ctx context.Context, rule alerts.AlertRule) (*alerts.AlertRule, rest_errors.RestErr) {
+	current, err := s.GetAlertRule(ctx, rule.UserId, rule.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	if rule.Enabled == nil {
+		rule.Enabled = current.Enabled
+	}
+	rule.DateCreated = current.DateCreated
+	if rule.Token == current.Token {
+		if err := repositories.AlertRulesRepository.Update(ctx, &rule); err != nil {
+			return nil, err
+		}
+		return &rule, nil
+	}
+
+	// A new token may not fit in the watchlist of the user.
+	err = repositories.TransactionManager.Run(ctx, func(repos repositories.Repositories) rest_errors.RestErr {
+		if err := QuotaService.CheckAlertRuleQuota(ctx, repos, rule); err != nil {
+			return err
+		}
+		return repos.AlertRules.Update(ctx, &rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *alertRulesService) SetAlertRuleEnabled(ctx context.Context, userId int64, ruleId int64, enabled bool) (*alerts.AlertRule, rest_errors.RestErr) {
+	rule, err := s.GetAlertRule(ctx, userId, ruleId)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Enabled = &enabled
+	if err := repositories.AlertRulesRepository.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *alertRulesService) DeleteAlertRule(ctx context.Context, userId int64, ruleId int64) rest_errors.RestErr {
+	if _, err := s.GetAlertRule(ctx, userId, ruleId); err != nil {
+		return err
+	}
+	return repositories.AlertRulesRepository.Delete(ctx, ruleId)
+}
+
+func (s *alertRulesService) StreamActiveAlertRules(ctx context.Context, callback func(alerts.AlertRule) error) rest_errors.RestErr {
+	return repositories.AlertRulesRepository.FindActive(ctx, date_utils.GetNowDBFormat(), callback)
+}
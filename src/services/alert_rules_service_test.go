@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	saveAlertRuleRepoFunc        func(*alerts.AlertRule) rest_errors.RestErr
+	getAlertRuleRepoFunc         func(int64) (*alerts.AlertRule, rest_errors.RestErr)
+	findAlertRulesByUserRepoFunc func(int64) (alerts.AlertRules, rest_errors.RestErr)
+	findActiveAlertRulesRepoFunc func(string, func(alerts.AlertRule) error) rest_errors.RestErr
+	updateAlertRuleRepoFunc      func(*alerts.AlertRule) rest_errors.RestErr
+	deleteAlertRuleRepoFunc      func(int64) rest_errors.RestErr
+)
+
+type alertRulesRepoMock struct{}
+
+func (*alertRulesRepoMock) Save(ctx context.Context, rule *alerts.AlertRule) rest_errors.RestErr {
+	return saveAlertRuleRepoFunc(rule)
+}
+
+func (*alertRulesRepoMock) Get(ctx context.Context, id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+	return getAlertRuleRepoFunc(id)
+}
+
+func (*alertRulesRepoMock) FindByUserId(ctx context.Context, userId int64) (alerts.AlertRules, rest_errors.RestErr) {
+	return findAlertRulesByUserRepoFunc(userId)
+}
+
+func (*alertRulesRepoMock) FindActive(ctx context.Context, now string, callback func(alerts.AlertRule) error) rest_errors.RestErr {
+	return findActiveAlertRulesRepoFunc(now, callback)
+}
+
+func (*alertRulesRepoMock) Update(ctx context.Context, rule *alerts.AlertRule) rest_errors.RestErr {
+	return updateAlertRuleRepoFunc(rule)
+}
+
+func (*alertRulesRepoMock) Delete(ctx context.Context, id int64) rest_errors.RestErr {
+	return deleteAlertRuleRepoFunc(id)
+}
+
+func (*alertRulesRepoMock) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	return deleteByUserRepoFunc("alert_rules", userId)
+}
+
+func TestCreateAlertRuleOK(t *testing.T) {
+
+	rule := alerts.AlertRule{UserId: 1, Token: " BTC ", Type: "Price_Above", Threshold: 30000}
+	saveAlertRuleRepoFunc = func(rule *alerts.AlertRule) rest_errors.RestErr {
+		rule.Id = 12
+		return nil
+	}
+
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+	withinQuota()
+
+	result, err := AlertRulesService.CreateAlertRule(context.Background(), rule)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), result.Id)
+	assert.Equal(t, "btc", result.Token)
+	assert.Equal(t, alerts.TypePriceAbove, result.Type)
+	assert.True(t, *result.Enabled)
+	assert.NotEmpty(t, result.DateCreated)
+}
+
+func TestCreateAlertRuleInvalidReturnBadRequest(t *testing.T) {
+
+	rules := []alerts.AlertRule{
+		{UserId: 1, Type: alerts.TypePriceAbove, Threshold: 1},
+		{UserId: 1, Token: "btc", Type: "unknown", Threshold: 1},
+		{UserId: 1, Token: "btc", Type: alerts.TypePriceBelow, Threshold: 0},
+		{UserId: 1, Token: "btc", Type: alerts.TypePercentChange, Threshold: 5},
+		{UserId: 1, Token: "btc", Type: alerts.TypeVolumeSpike, Threshold: 1, WindowMinutes: 30},
+		{UserId: 1, Token: "btc", Type: alerts.TypePriceAbove, Threshold: 1, CooldownMinutes: -1},
+		{UserId: 1, Token: "btc", Type: alerts.TypePriceAbove, Threshold: 1, ExpiresAt: "tomorrow"},
+		{UserId: 1, Token: "btc", Type: alerts.TypePriceAbove, Threshold: 1, ExpiresAt: "2000-01-01 00:00:00"},
+	}
+
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+
+	for _, rule := range rules {
+		_, err := AlertRulesService.CreateAlertRule(context.Background(), rule)
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.Status())
+	}
+}
+
+func TestCreateAlertRuleQuotaExceededReturnForbidden(t *testing.T) {
+
+	checkAlertRuleQuotaFunc = func(alerts.AlertRule) rest_errors.RestErr {
+		return rest_errors.NewRestError("the Free plan allows up to 10 alert rules", 403, "quota_exceeded", nil)
+	}
+	saved := false
+	saveAlertRuleRepoFunc = func(rule *alerts.AlertRule) rest_errors.RestErr {
+		saved = true
+		return nil
+	}
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+	QuotaService = &quotaServiceMock{}
+	manager := &transactionManagerMock{}
+	repositories.TransactionManager = manager
+
+	_, err := AlertRulesService.CreateAlertRule(context.Background(), alerts.AlertRule{UserId: 1, Token: "btc", Type: alerts.TypePriceAbove, Threshold: 1})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 403, err.Status())
+	assert.False(t, saved)
+	assert.True(t, manager.rolledBack)
+}
+
+func TestGetAlertRuleOfAnotherUserReturnNotFound(t *testing.T) {
+
+	getAlertRuleRepoFunc = func(id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+		return &alerts.AlertRule{Id: id, UserId: 2}, nil
+	}
+
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+
+	_, err := AlertRulesService.GetAlertRule(context.Background(), 1, 12)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestUpdateAlertRuleKeepsDateCreated(t *testing.T) {
+
+	getAlertRuleRepoFunc = func(id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+		return &alerts.AlertRule{Id: id, UserId: 1, DateCreated: "2022-01-01 00:00:00"}, nil
+	}
+	updateAlertRuleRepoFunc = func(rule *alerts.AlertRule) rest_errors.RestErr {
+		return nil
+	}
+
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+	withinQuota()
+
+	result, err := AlertRulesService.UpdateAlertRule(context.Background(), alerts.AlertRule{Id: 12, UserId: 1, Token: "eth", Type: alerts.TypePriceBelow, Threshold: 1000})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "2022-01-01 00:00:00", result.DateCreated)
+	assert.Equal(t, "eth", result.Token)
+}
+
+func TestUpdateAlertRuleWithoutEnabledKeepsIt(t *testing.T) {
+	getAlertRuleRepoFunc = func(id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+		enabled := true
+		return &alerts.AlertRule{Id: id, UserId: 1, Token: "btc", Enabled: &enabled}, nil
+	}
+	var updated *alerts.AlertRule
+	updateAlertRuleRepoFunc = func(rule *alerts.AlertRule) rest_errors.RestErr {
+		updated = rule
+		return nil
+	}
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+
+	_, err := AlertRulesService.UpdateAlertRule(context.Background(), alerts.AlertRule{Id: 12, UserId: 1, Token: "btc", Type: alerts.TypePriceBelow, Threshold: 1000})
+
+	assert.Nil(t, err)
+	assert.True(t, *updated.Enabled)
+
+	disabled := false
+	_, err = AlertRulesService.UpdateAlertRule(context.Background(), alerts.AlertRule{Id: 12, UserId: 1, Token: "btc", Type: alerts.TypePriceBelow, Threshold: 1000, Enabled: &disabled})
+
+	assert.Nil(t, err)
+	assert.False(t, *updated.Enabled)
+}
+
+func TestSetAlertRuleEnabledOK(t *testing.T) {
+
+	getAlertRuleRepoFunc = func(id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+		enabled := true
+		return &alerts.AlertRule{Id: id, UserId: 1, Enabled: &enabled}, nil
+	}
+	var updated *alerts.AlertRule
+	updateAlertRuleRepoFunc = func(rule *alerts.AlertRule) rest_errors.RestErr {
+		updated = rule
+		return nil
+	}
+
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+
+	result, err := AlertRulesService.SetAlertRuleEnabled(context.Background(), 1, 12, false)
+
+	assert.Nil(t, err)
+	assert.False(t, *result.Enabled)
+	assert.False(t, *updated.Enabled)
+}
+
+func TestDeleteAlertRuleFailReturnInternalServerError(t *testing.T) {
+
+	getAlertRuleRepoFunc = func(id int64) (*alerts.AlertRule, rest_errors.RestErr) {
+		return &alerts.AlertRule{Id: id, UserId: 1}, nil
+	}
+	deleteAlertRuleRepoFunc = func(id int64) rest_errors.RestErr {
+		return rest_errors.NewInternalServerError("error deleting alert rule", errors.New("database error"))
+	}
+
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+
+	err := AlertRulesService.DeleteAlertRule(context.Background(), 1, 12)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"time"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	AuditService auditServiceInterface = &auditService{}
+)
+
+type auditService struct{}
+
+type auditServiceInterface interface {
+	Record(context.Context, audit.Entry)
+	Find(context.Context, audit.Filter) (audit.Entries, rest_errors.RestErr)
+	Export(context.Context, audit.Filter, func(audit.Entry) error) rest_errors.RestErr
+}
+
+// newAuditEntry starts an entry about target, acted on by the actor of ctx, as part
+// of the request ctx serves, and marked with who impersonates the actor, if anyone.
+func newAuditEntry(ctx context.Context, action string, outcome string, targetUserId int64) audit.Entry {
+	actor := audit.ActorFrom(ctx)
+	return audit.Entry{
+		Action:         action,
+		Outcome:        outcome,
+		ActorId:        actor.UserId,
+		TargetUserId:   targetUserId,
+		ImpersonatorId: actor.ImpersonatorId,
+		RequestId:      logging.RequestId(ctx),
+		DateCreated:    date_utils.GetNowDBFormat(),
+	}
+}
+
+// Record appends entry once the action it describes is done. The action stands
+// whatever happens here, so a failure is logged rather than returned, and a caller
+// hanging up does not cancel the write. Actions that must not happen unrecorded
+// append their entry in their own transaction instead.
+func (s *auditService) Record(ctx context.Context, entry audit.Entry) {
+	if err := repositories.AuditRepository.Append(detached{ctx}, &entry); err != nil {
+		logging.Error(ctx, "error when trying to record audit entry "+entry.Action, err)
+	}
+}
+
+func (s *auditService) Find(ctx context.Context, filter audit.Filter) (audit.Entries, rest_errors.RestErr) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return repositories.AuditRepository.Find(ctx, filter)
+}
+
+// Export walks every entry matching filter, oldest first, whatever its limit.
+func (s *auditService) Export(ctx context.Context, filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	return repositories.AuditRepository.Walk(ctx, filter, callback)
+}
+
+// detached keeps the values of a context, such as its request id and span, but
+// neither its deadline nor its cancellation.
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detached) Done() <-chan struct{}             { return nil }
+func (d detached) Err() error                        { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
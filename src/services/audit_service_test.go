@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type auditRepoMock struct {
+	entries   []audit.Entry
+	contexts  []context.Context
+	filters   []audit.Filter
+	appendErr rest_errors.RestErr
+}
+
+func (m *auditRepoMock) Append(ctx context.Context, entry *audit.Entry) rest_errors.RestErr {
+	if m.appendErr != nil {
+		return m.appendErr
+	}
+	entry.Id = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, *entry)
+	m.contexts = append(m.contexts, ctx)
+	return nil
+}
+
+func (m *auditRepoMock) Find(ctx context.Context, filter audit.Filter) (audit.Entries, rest_errors.RestErr) {
+	m.filters = append(m.filters, filter)
+	return m.entries, nil
+}
+
+func (m *auditRepoMock) Walk(ctx context.Context, filter audit.Filter, callback func(audit.Entry) error) rest_errors.RestErr {
+	m.filters = append(m.filters, filter)
+	for _, entry := range m.entries {
+		if err := callback(entry); err != nil {
+			return rest_errors.NewInternalServerError("error streaming audit entries", err)
+		}
+	}
+	return nil
+}
+
+// withAuditMock swaps the audit repository for one keeping entries in memory.
+func withAuditMock() *auditRepoMock {
+	mock := &auditRepoMock{}
+	repositories.AuditRepository = mock
+	return mock
+}
+
+// Every service test gets an audit repository that needs no database; those checking
+// the entries take a fresh one.
+func init() {
+	withAuditMock()
+}
+
+func TestNewAuditEntryTakesActorAndRequestFromContext(t *testing.T) {
+	ctx := audit.WithActor(logging.WithRequestId(context.Background(), "abc-123"), audit.Actor{UserId: 1})
+
+	entry := newAuditEntry(ctx, audit.ActionProfileUpdated, audit.OutcomeSuccess, 666)
+
+	assert.Equal(t, audit.ActionProfileUpdated, entry.Action)
+	assert.Equal(t, int64(1), entry.ActorId)
+	assert.Equal(t, int64(666), entry.TargetUserId)
+	assert.Equal(t, "abc-123", entry.RequestId)
+	assert.NotEmpty(t, entry.DateCreated)
+}
+
+func TestRecordOutlivesCanceledRequest(t *testing.T) {
+	auditLog := withAuditMock()
+	ctx, cancel := context.WithCancel(logging.WithRequestId(context.Background(), "abc-123"))
+	cancel()
+
+	AuditService.Record(ctx, newAuditEntry(ctx, audit.ActionPasswordChanged, audit.OutcomeSuccess, 666))
+
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Nil(t, auditLog.contexts[0].Err())
+	assert.Equal(t, "abc-123", logging.RequestId(auditLog.contexts[0]))
+}
+
+func TestRecordFailureDoesNotPanic(t *testing.T) {
+	auditLog := withAuditMock()
+	auditLog.appendErr = rest_errors.NewInternalServerError("error saving audit entry", errors.New("database error"))
+
+	AuditService.Record(context.Background(), audit.Entry{Action: audit.ActionLoginFailed})
+
+	assert.Empty(t, auditLog.entries)
+}
+
+func TestFindAuditValidatesFilter(t *testing.T) {
+	auditLog := withAuditMock()
+
+	_, err := AuditService.Find(context.Background(), audit.Filter{Action: "user.exploded"})
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+
+	_, err = AuditService.Find(context.Background(), audit.Filter{Action: " Login.Failed "})
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Filter{Action: audit.ActionLoginFailed, Limit: audit.DefaultLimit}, auditLog.filters[0])
+}
+
+func TestExportAuditWalksEntries(t *testing.T) {
+	withAuditMock()
+	AuditService.Record(context.Background(), audit.Entry{Action: audit.ActionLoginFailed})
+	AuditService.Record(context.Background(), audit.Entry{Action: audit.ActionLoginSucceeded})
+	exported := make([]string, 0)
+
+	err := AuditService.Export(context.Background(), audit.Filter{}, func(entry audit.Entry) error {
+		exported = append(exported, entry.Action)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{audit.ActionLoginFailed, audit.ActionLoginSucceeded}, exported)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/logging"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	EventBusService eventBusServiceInterface = &eventBusService{}
+)
+
+type eventBusService struct{}
+
+type eventBusServiceInterface interface {
+	Publish(context.Context, events.Event) rest_errors.RestErr
+}
+
+// Publish sends the event as a CloudEvent on the subject configured for its type. It
+// does nothing when no bus is configured.
+func (s *eventBusService) Publish(ctx context.Context, event events.Event) rest_errors.RestErr {
+	if bus.Client == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(event.CloudEvent())
+	if err != nil {
+		logging.Error(ctx, "error when trying to marshal event for the bus", err)
+		return rest_errors.NewInternalServerError("error publishing event", errors.New("json error"))
+	}
+
+	if err := bus.Client.Publish(bus.Routes.For(event.Type), body); err != nil {
+		logging.Error(ctx, "error when trying to publish event "+event.Id+" on the bus", err)
+		return rest_errors.NewInternalServerError("error publishing event", errors.New("bus error"))
+	}
+	return nil
+}
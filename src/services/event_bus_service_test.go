@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"tokenalert_user-api/src/datasources/bus"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withBus(t *testing.T, routes bus.Subjects) *bus.MemoryPublisher {
+	publisher := bus.NewMemoryPublisher()
+	previousClient, previousRoutes := bus.Client, bus.Routes
+	bus.Client, bus.Routes = publisher, routes
+	t.Cleanup(func() {
+		bus.Client, bus.Routes = previousClient, previousRoutes
+	})
+	return publisher
+}
+
+func TestPublishSendsCloudEventOnConfiguredSubject(t *testing.T) {
+	publisher := withBus(t, bus.Subjects{Prefix: "tokenalert", Overrides: map[string]string{events.TypeUserDeleted: "crm.churn"}})
+
+	created := events.Event{Id: "evt-1", Type: events.TypeUserCreated, UserId: 666, OccurredAt: "2022-09-12T10:00:00Z", Data: json.RawMessage(`{"id":666}`)}
+	deleted := events.Event{Id: "evt-2", Type: events.TypeUserDeleted, UserId: 666, OccurredAt: "2022-09-12T11:00:00Z", Data: json.RawMessage(`{"id":666}`)}
+
+	assert.Nil(t, EventBusService.Publish(context.Background(), created))
+	assert.Nil(t, EventBusService.Publish(context.Background(), deleted))
+
+	messages := publisher.Messages()
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "tokenalert.user.created", messages[0].Subject)
+	assert.Equal(t, "crm.churn", messages[1].Subject)
+
+	var envelope events.CloudEvent
+	assert.Nil(t, json.Unmarshal(messages[0].Data, &envelope))
+	assert.Equal(t, "1.0", envelope.SpecVersion)
+	assert.Equal(t, "evt-1", envelope.Id)
+	assert.Equal(t, "com.tokenalert.user.created", envelope.Type)
+	assert.Equal(t, events.CloudEventsSource, envelope.Source)
+	assert.Equal(t, "666", envelope.Subject)
+	assert.Equal(t, "2022-09-12T10:00:00Z", envelope.Time)
+	assert.Equal(t, "application/json", envelope.DataContentType)
+	assert.JSONEq(t, `{"id":666}`, string(envelope.Data))
+}
+
+func TestPublishFailsWhenBusIsClosed(t *testing.T) {
+	publisher := withBus(t, bus.Subjects{Prefix: "tokenalert"})
+	publisher.Close()
+
+	err := EventBusService.Publish(context.Background(), events.Event{Id: "evt-1", Type: events.TypeUserCreated, UserId: 666})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error publishing event", err.Message())
+}
+
+func TestRelayPendingKeepsEventsWhenBusFails(t *testing.T) {
+	publisher := withBus(t, bus.Subjects{Prefix: "tokenalert"})
+	publisher.Close()
+	outbox := &outboxRepoMock{pending: pendingEvents(2)}
+	repositories.OutboxRepository = outbox
+	dispatched := captureEvents()
+
+	relay := &outboxRelay{batchSize: 10}
+	relayed, err := relay.RelayPending()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, relayed)
+	assert.Empty(t, dispatched.events)
+	assert.Equal(t, 2, len(outbox.pending))
+}
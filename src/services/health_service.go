@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"tokenalert_user-api/src/domain/health"
+)
+
+var (
+	HealthService healthServiceInterface = &healthService{}
+)
+
+// Checker tells whether a dependency the API needs to serve requests works. It must
+// give up once ctx is done.
+type Checker func(ctx context.Context) error
+
+type registeredChecker struct {
+	name    string
+	timeout time.Duration
+	check   Checker
+}
+
+type healthService struct {
+	mu           sync.RWMutex
+	checkers     []registeredChecker
+	shuttingDown bool
+}
+
+type healthServiceInterface interface {
+	Register(string, time.Duration, Checker)
+	Ready(context.Context) health.Report
+	ShuttingDown()
+}
+
+// Register adds a readiness check. Each one gets its own timeout, after which it is
+// reported down; registering a name again replaces the previous check.
+func (s *healthService) Register(name string, timeout time.Duration, check Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checker := registeredChecker{name: name, timeout: timeout, check: check}
+	for i := range s.checkers {
+		if s.checkers[i].name == name {
+			s.checkers[i] = checker
+			return
+		}
+	}
+	s.checkers = append(s.checkers, checker)
+}
+
+// Ready runs every check at once and reports them in registration order. After
+// ShuttingDown is called, the report is down whatever the checks say.
+func (s *healthService) Ready(ctx context.Context) health.Report {
+	s.mu.RLock()
+	checkers := append([]registeredChecker(nil), s.checkers...)
+	shuttingDown := s.shuttingDown
+	s.mu.RUnlock()
+
+	report := health.Report{Status: health.StatusUp, ShuttingDown: shuttingDown, Checks: make([]health.Check, len(checkers))}
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker registeredChecker) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	if shuttingDown {
+		report.Status = health.StatusDown
+	}
+	for _, check := range report.Checks {
+		if check.Status != health.StatusUp {
+			report.Status = health.StatusDown
+		}
+	}
+	return report
+}
+
+// ShuttingDown marks the API as going away. The server keeps serving for
+// server.drain_delay afterwards, so load balancers see readiness fail and stop
+// routing requests to it before its listener closes.
+func (s *healthService) ShuttingDown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shuttingDown = true
+}
+
+// runCheck waits for the check no longer than its timeout, even if it ignores ctx.
+func runCheck(ctx context.Context, checker registeredChecker) health.Check {
+	ctx, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				result <- fmt.Errorf("check panicked: %v", recovered)
+			}
+		}()
+		result <- checker.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("no answer within %s", checker.timeout)
+	}
+
+	check := health.Check{Name: checker.name, Status: health.StatusUp, Duration: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Status = health.StatusDown
+		check.Error = err.Error()
+	}
+	return check
+}
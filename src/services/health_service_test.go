@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/domain/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func up(context.Context) error {
+	return nil
+}
+
+func TestHealthReadyAllUp(t *testing.T) {
+	service := &healthService{}
+	service.Register("database", time.Second, up)
+	service.Register("cache", time.Second, up)
+
+	report := service.Ready(context.Background())
+
+	assert.True(t, report.Up())
+	assert.False(t, report.ShuttingDown)
+	assert.Equal(t, 2, len(report.Checks))
+	assert.Equal(t, "database", report.Checks[0].Name)
+	assert.Equal(t, "cache", report.Checks[1].Name)
+	assert.Equal(t, health.StatusUp, report.Checks[1].Status)
+}
+
+func TestHealthReadyCheckDown(t *testing.T) {
+	service := &healthService{}
+	service.Register("database", time.Second, up)
+	service.Register("event_bus", time.Second, func(context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	report := service.Ready(context.Background())
+
+	assert.False(t, report.Up())
+	assert.Equal(t, health.StatusUp, report.Checks[0].Status)
+	assert.Equal(t, health.Check{Name: "event_bus", Status: health.StatusDown, Error: "connection refused"}, report.Checks[1])
+}
+
+func TestHealthReadyCheckTimeout(t *testing.T) {
+	service := &healthService{}
+	release := make(chan struct{})
+	defer close(release)
+	service.Register("database", 20*time.Millisecond, func(context.Context) error {
+		// Ignores ctx, as a driver without deadline support would.
+		<-release
+		return nil
+	})
+	service.Register("cache", time.Second, up)
+
+	start := time.Now()
+	report := service.Ready(context.Background())
+
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.False(t, report.Up())
+	assert.Equal(t, "no answer within 20ms", report.Checks[0].Error)
+	assert.Equal(t, health.StatusUp, report.Checks[1].Status)
+}
+
+func TestHealthReadyCheckPanics(t *testing.T) {
+	service := &healthService{}
+	service.Register("cache", time.Second, func(context.Context) error {
+		panic("nil store")
+	})
+
+	report := service.Ready(context.Background())
+
+	assert.False(t, report.Up())
+	assert.Equal(t, "check panicked: nil store", report.Checks[0].Error)
+}
+
+func TestHealthRegisterReplacesCheck(t *testing.T) {
+	service := &healthService{}
+	service.Register("database", time.Second, func(context.Context) error {
+		return errors.New("down")
+	})
+	service.Register("database", time.Second, up)
+
+	report := service.Ready(context.Background())
+
+	assert.True(t, report.Up())
+	assert.Equal(t, 1, len(report.Checks))
+}
+
+func TestHealthReadyDownWhileShuttingDown(t *testing.T) {
+	service := &healthService{}
+	service.Register("database", time.Second, up)
+
+	service.ShuttingDown()
+	report := service.Ready(context.Background())
+
+	assert.False(t, report.Up())
+	assert.True(t, report.ShuttingDown)
+	assert.Equal(t, health.StatusUp, report.Checks[0].Status)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/impersonation"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/tracing"
+	"tokenalert_user-api/src/utils/crypto_utils"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	// ImpersonationService is disabled until the application replaces it with one
+	// holding the configured secret.
+	ImpersonationService impersonationServiceInterface = NewImpersonationService(impersonation.DefaultConfig())
+)
+
+type impersonationService struct {
+	config impersonation.Config
+}
+
+type impersonationServiceInterface interface {
+	Start(context.Context, impersonation.Request) (*impersonation.Grant, rest_errors.RestErr)
+	Verify(context.Context, string, int64) (*impersonation.Claims, rest_errors.RestErr)
+	Restrict(context.Context, string) rest_errors.RestErr
+}
+
+func NewImpersonationService(config impersonation.Config) impersonationServiceInterface {
+	return &impersonationService{config: config}
+}
+
+// Start issues a token for the caller to act as a regular, active user. Staff can
+// not be impersonated, so nobody gains permissions through a token.
+func (s *impersonationService) Start(ctx context.Context, request impersonation.Request) (*impersonation.Grant, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "impersonationService.Start")
+	defer span.End()
+
+	if !s.config.Enabled() {
+		return nil, rest_errors.NewRestError("impersonation is disabled", http.StatusServiceUnavailable, "service_unavailable", nil)
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	actor := audit.ActorFrom(ctx)
+	if actor.UserId == request.UserId {
+		return nil, rest_errors.NewBadRequestError("can not impersonate yourself")
+	}
+	user, err := repositories.UsersRepository.Get(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if access.IsStaff(user.Role) {
+		return nil, newForbiddenError("can not impersonate staff")
+	}
+	if user.Status != users.StatusActive {
+		return nil, newForbiddenError("can only impersonate active users")
+	}
+
+	tokenId, uuidErr := crypto_utils.NewUUID()
+	if uuidErr != nil {
+		logging.Error(ctx, "error when trying to generate impersonation token id", uuidErr)
+		return nil, rest_errors.NewInternalServerError("error starting impersonation", errors.New("crypto error"))
+	}
+	expiresAt := date_utils.GetNow().Add(s.config.TTL)
+	claims := impersonation.Claims{TokenId: tokenId, UserId: user.Id, ImpersonatorId: actor.UserId, ExpiresAt: expiresAt.Unix()}
+	token, signErr := impersonation.Sign(s.config.Secret, claims)
+	if signErr != nil {
+		logging.Error(ctx, "error when trying to sign impersonation token", signErr)
+		return nil, rest_errors.NewInternalServerError("error starting impersonation", errors.New("crypto error"))
+	}
+
+	entry := newAuditEntry(ctx, audit.ActionImpersonationStarted, audit.OutcomeSuccess, user.Id)
+	entry.Details = map[string]string{"reason": request.Reason, "token_id": tokenId, "expires_at": date_utils.FormatDB(expiresAt)}
+	AuditService.Record(ctx, entry)
+	return &impersonation.Grant{Token: token, UserId: user.Id, ImpersonatorId: actor.UserId, ExpiresAt: date_utils.FormatAPI(expiresAt)}, nil
+}
+
+// Verify checks token was issued to callerId, the authenticated caller, and that the
+// caller still may impersonate: a token dies with the role or status that got it.
+func (s *impersonationService) Verify(ctx context.Context, token string, callerId int64) (*impersonation.Claims, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "impersonationService.Verify")
+	defer span.End()
+
+	if !s.config.Enabled() {
+		return nil, rest_errors.NewUnauthorizedError("impersonation is disabled")
+	}
+	claims, err := impersonation.Parse(s.config.Secret, token, date_utils.GetNow())
+	if err != nil {
+		if errors.Is(err, impersonation.ErrExpired) {
+			return nil, rest_errors.NewUnauthorizedError("expired impersonation token")
+		}
+		return nil, rest_errors.NewUnauthorizedError("invalid impersonation token")
+	}
+	if claims.ImpersonatorId != callerId {
+		return nil, rest_errors.NewUnauthorizedError("impersonation token issued to another caller")
+	}
+	if _, authErr := AdminService.Authorize(ctx, claims.ImpersonatorId, access.PermissionUsersImpersonate); authErr != nil {
+		return nil, authErr
+	}
+	return claims, nil
+}
+
+// Restrict refuses, and audits, operation when ctx is impersonating a user. Changing
+// credentials, deleting the account and staff actions stay with the user and the
+// staff member themselves.
+func (s *impersonationService) Restrict(ctx context.Context, operation string) rest_errors.RestErr {
+	actor := audit.ActorFrom(ctx)
+	if !actor.Impersonated() {
+		return nil
+	}
+	entry := newAuditEntry(ctx, audit.ActionImpersonationDenied, audit.OutcomeFailure, actor.UserId)
+	entry.Details = map[string]string{"operation": operation}
+	AuditService.Record(ctx, entry)
+	return newForbiddenError(operation + " is not allowed while impersonating")
+}
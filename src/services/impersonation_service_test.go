@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/impersonation"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testImpersonationSecret = "0123456789abcdef0123456789abcdef"
+
+func withImpersonation(t *testing.T) {
+	previous := ImpersonationService
+	ImpersonationService = NewImpersonationService(impersonation.Config{Secret: testImpersonationSecret, TTL: 15 * time.Minute})
+	t.Cleanup(func() {
+		ImpersonationService = previous
+	})
+}
+
+func impersonationUsers() *[]string {
+	return withUsers(
+		users.User{Id: 1, Status: users.StatusActive, Role: access.RoleAdmin},
+		users.User{Id: 2, Status: users.StatusActive, Role: access.RoleSupport},
+		users.User{Id: 7, Status: users.StatusActive, Role: access.RoleUser},
+		users.User{Id: 8, Status: users.StatusSuspended, Role: access.RoleUser},
+	)
+}
+
+func TestStartImpersonationOK(t *testing.T) {
+	withImpersonation(t)
+	withClock(t, "2024-03-01T10:00:00Z")
+	impersonationUsers()
+	auditLog := withAuditMock()
+
+	grant, err := ImpersonationService.Start(actingAs(1, access.RoleAdmin), impersonation.Request{UserId: 7, Reason: " ticket 42 "})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), grant.UserId)
+	assert.Equal(t, int64(1), grant.ImpersonatorId)
+	assert.Equal(t, "2024-03-01T10:15:00Z", grant.ExpiresAt)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionImpersonationStarted, auditLog.entries[0].Action)
+	assert.Equal(t, int64(1), auditLog.entries[0].ActorId)
+	assert.Equal(t, int64(7), auditLog.entries[0].TargetUserId)
+	assert.Equal(t, "ticket 42", auditLog.entries[0].Details["reason"])
+
+	claims, err := ImpersonationService.Verify(context.Background(), grant.Token, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), claims.UserId)
+	assert.Equal(t, int64(1), claims.ImpersonatorId)
+	assert.Equal(t, auditLog.entries[0].Details["token_id"], claims.TokenId)
+}
+
+func TestStartImpersonationRefused(t *testing.T) {
+	withImpersonation(t)
+	impersonationUsers()
+	auditLog := withAuditMock()
+
+	cases := []struct {
+		request impersonation.Request
+		status  int
+	}{
+		{impersonation.Request{UserId: 7}, http.StatusBadRequest},
+		{impersonation.Request{UserId: 1, Reason: "testing"}, http.StatusBadRequest},
+		{impersonation.Request{UserId: 2, Reason: "testing"}, http.StatusForbidden},
+		{impersonation.Request{UserId: 8, Reason: "testing"}, http.StatusForbidden},
+		{impersonation.Request{UserId: 404, Reason: "testing"}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		_, err := ImpersonationService.Start(actingAs(1, access.RoleAdmin), c.request)
+		assert.NotNil(t, err)
+		assert.Equal(t, c.status, err.Status())
+	}
+	assert.Equal(t, 0, len(auditLog.entries))
+}
+
+func TestStartImpersonationDisabled(t *testing.T) {
+	impersonationUsers()
+
+	_, err := NewImpersonationService(impersonation.DefaultConfig()).Start(actingAs(1, access.RoleAdmin), impersonation.Request{UserId: 7, Reason: "testing"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, err.Status())
+}
+
+func TestVerifyImpersonationRefused(t *testing.T) {
+	withImpersonation(t)
+	withClock(t, "2024-03-01T10:00:00Z")
+	impersonationUsers()
+	withAuditMock()
+
+	grant, err := ImpersonationService.Start(actingAs(1, access.RoleAdmin), impersonation.Request{UserId: 7, Reason: "testing"})
+	assert.Nil(t, err)
+
+	_, err = ImpersonationService.Verify(context.Background(), grant.Token, 2)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.Status())
+
+	_, err = ImpersonationService.Verify(context.Background(), grant.Token+"0", 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.Status())
+
+	_, err = NewImpersonationService(impersonation.Config{Secret: testImpersonationSecret + "!", TTL: time.Minute}).Verify(context.Background(), grant.Token, 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.Status())
+
+	withClock(t, "2024-03-01T10:15:00Z")
+	_, err = ImpersonationService.Verify(context.Background(), grant.Token, 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.Status())
+	assert.Equal(t, "expired impersonation token", err.Message())
+}
+
+func TestVerifyImpersonationEndsWithRole(t *testing.T) {
+	withImpersonation(t)
+	impersonationUsers()
+	withAuditMock()
+
+	grant, err := ImpersonationService.Start(actingAs(1, access.RoleAdmin), impersonation.Request{UserId: 7, Reason: "testing"})
+	assert.Nil(t, err)
+
+	withUsers(
+		users.User{Id: 1, Status: users.StatusActive, Role: access.RoleSupport},
+		users.User{Id: 7, Status: users.StatusActive, Role: access.RoleUser},
+	)
+	_, err = ImpersonationService.Verify(context.Background(), grant.Token, 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+}
+
+func TestRestrictImpersonation(t *testing.T) {
+	auditLog := withAuditMock()
+
+	assert.Nil(t, ImpersonationService.Restrict(actingAs(7, access.RoleUser), "changing the password"))
+	assert.Equal(t, 0, len(auditLog.entries))
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserId: 7, ImpersonatorId: 1})
+	err := ImpersonationService.Restrict(ctx, "changing the password")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionImpersonationDenied, auditLog.entries[0].Action)
+	assert.Equal(t, audit.OutcomeFailure, auditLog.entries[0].Outcome)
+	assert.Equal(t, int64(7), auditLog.entries[0].ActorId)
+	assert.Equal(t, int64(1), auditLog.entries[0].ImpersonatorId)
+	assert.Equal(t, "changing the password", auditLog.entries[0].Details["operation"])
+}
+
+func TestUpdateEmailWhileImpersonatingIsForbidden(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Name: "John", Email: "john@mail.com", TimeZone: "UTC"}, nil
+	}
+	updated := false
+	updateUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		updated = true
+		return nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	withAuditMock()
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserId: 666, ImpersonatorId: 1})
+
+	_, err := UsersService.UpdateUser(ctx, true, users.User{Id: 666, Email: "other@mail.com"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.Status())
+	assert.False(t, updated)
+
+	_, err = UsersService.UpdateUser(ctx, true, users.User{Id: 666, Name: "Johnny"})
+
+	assert.Nil(t, err)
+	assert.True(t, updated)
+}
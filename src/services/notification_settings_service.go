@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	NotificationSettingsService notificationSettingsServiceInterface = &notificationSettingsService{}
+)
+
+type notificationSettingsService struct{}
+
+type notificationSettingsServiceInterface interface {
+	GetSettings(context.Context, int64) (*notifications.Settings, rest_errors.RestErr)
+	UpdateSettings(context.Context, notifications.Settings) (*notifications.Settings, rest_errors.RestErr)
+	VerifyChannel(context.Context, int64, string) (*notifications.Settings, rest_errors.RestErr)
+}
+
+// GetSettings returns the stored settings of the user. Users without a telegram
+// channel of their own get the telegram user they signed up with as an enabled one,
+// primary when no other channel is; storing a disabled telegram channel turns it off.
+func (s *notificationSettingsService) GetSettings(ctx context.Context, userId int64) (*notifications.Settings, rest_errors.RestErr) {
+	settings, err := repositories.NotificationSettingsRepository.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Channels.Find(notifications.ChannelTelegram) != nil {
+		return settings, nil
+	}
+
+	user, err := repositories.UsersRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	channel := notifications.Channel{
+		Type:    notifications.ChannelTelegram,
+		Target:  notifications.TelegramTarget(user.TelegramUser),
+		Enabled: true,
+	}
+	if channel.Validate() != nil {
+		return settings, nil
+	}
+	if settings.PrimaryChannel == "" {
+		settings.PrimaryChannel = notifications.ChannelTelegram
+	}
+	settings.Channels = append(settings.Channels, channel)
+	return settings, nil
+}
+
+func (s *notificationSettingsService) UpdateSettings(ctx context.Context, settings notifications.Settings) (*notifications.Settings, rest_errors.RestErr) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, err := s.GetSettings(ctx, settings.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := QuotaService.CheckNotificationChannelsQuota(ctx, settings.UserId, len(settings.Channels)); err != nil {
+		return nil, err
+	}
+
+	// Verification is owned by the verification flow, so a channel keeps its status
+	// only while its target does not change.
+	for index := range settings.Channels {
+		channel := &settings.Channels[index]
+		previous := current.Channels.Find(channel.Type)
+		channel.Verified = previous != nil && previous.Verified && previous.Target == channel.Target
+	}
+
+	if err := repositories.NotificationSettingsRepository.Save(ctx, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s *notificationSettingsService) VerifyChannel(ctx context.Context, userId int64, channelType string) (*notifications.Settings, rest_errors.RestErr) {
+	settings, err := repositories.NotificationSettingsRepository.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := settings.Channels.Find(strings.TrimSpace(strings.ToLower(channelType)))
+	if channel == nil {
+		return nil, rest_errors.NewNotFoundError("notification channel not found")
+	}
+	if channel.Verified {
+		return settings, nil
+	}
+
+	if err := repositories.NotificationSettingsRepository.MarkVerified(ctx, userId, channel.Type); err != nil {
+		return nil, err
+	}
+	channel.Verified = true
+	return settings, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	getNotificationSettingsRepoFunc  func(int64) (*notifications.Settings, rest_errors.RestErr)
+	saveNotificationSettingsRepoFunc func(*notifications.Settings) rest_errors.RestErr
+	markChannelVerifiedRepoFunc      func(int64, string) rest_errors.RestErr
+)
+
+type notificationSettingsRepoMock struct{}
+
+func (*notificationSettingsRepoMock) GetByUserId(ctx context.Context, userId int64) (*notifications.Settings, rest_errors.RestErr) {
+	return getNotificationSettingsRepoFunc(userId)
+}
+
+func (*notificationSettingsRepoMock) Save(ctx context.Context, settings *notifications.Settings) rest_errors.RestErr {
+	return saveNotificationSettingsRepoFunc(settings)
+}
+
+func (*notificationSettingsRepoMock) MarkVerified(ctx context.Context, userId int64, channelType string) rest_errors.RestErr {
+	return markChannelVerifiedRepoFunc(userId, channelType)
+}
+
+func (*notificationSettingsRepoMock) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	return deleteByUserRepoFunc("notification_channels", userId)
+}
+
+func emptyNotificationSettings(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+	return &notifications.Settings{UserId: userId, Channels: notifications.Channels{}}, nil
+}
+
+func TestGetNotificationSettingsDefaultsToTelegram(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = emptyNotificationSettings
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: id, TelegramUser: " John "}, nil
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	repositories.UsersRepository = &usersRepoMock{}
+
+	settings, err := NotificationSettingsService.GetSettings(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, notifications.ChannelTelegram, settings.PrimaryChannel)
+	assert.Equal(t, "@john", settings.Channels[0].Target)
+	assert.True(t, settings.Channels[0].Enabled)
+	assert.False(t, settings.Channels[0].Verified)
+}
+
+func TestGetNotificationSettingsAddsTelegramNextToOtherChannels(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, PrimaryChannel: "email", Channels: notifications.Channels{
+			{Type: notifications.ChannelEmail, Target: "john@mail.com", Enabled: true},
+		}}, nil
+	}
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: id, TelegramUser: "123456"}, nil
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	repositories.UsersRepository = &usersRepoMock{}
+
+	settings, err := NotificationSettingsService.GetSettings(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, notifications.ChannelEmail, settings.PrimaryChannel)
+	assert.Equal(t, 2, len(settings.Channels))
+	assert.Equal(t, "123456", settings.Channels.Find(notifications.ChannelTelegram).Target)
+}
+
+func TestGetNotificationSettingsKeepsTelegramTurnedOff(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, PrimaryChannel: "email", Channels: notifications.Channels{
+			{Type: notifications.ChannelEmail, Target: "john@mail.com", Enabled: true},
+			{Type: notifications.ChannelTelegram, Target: "@john", Enabled: false},
+		}}, nil
+	}
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		t.Fatal("the user must not be read when a telegram channel is stored")
+		return nil, nil
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	repositories.UsersRepository = &usersRepoMock{}
+
+	settings, err := NotificationSettingsService.GetSettings(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(settings.Channels))
+	assert.False(t, settings.Channels.Find(notifications.ChannelTelegram).Enabled)
+}
+
+func TestGetNotificationSettingsUnknownUserReturnNotFound(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = emptyNotificationSettings
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("user not found")
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	repositories.UsersRepository = &usersRepoMock{}
+
+	_, err := NotificationSettingsService.GetSettings(context.Background(), 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestUpdateNotificationSettingsKeepsVerificationOfUnchangedTargets(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, PrimaryChannel: "email", Channels: notifications.Channels{
+			{Type: notifications.ChannelEmail, Target: "john@mail.com", Enabled: true, Verified: true},
+			{Type: notifications.ChannelTelegram, Target: "@john", Enabled: true, Verified: true},
+		}}, nil
+	}
+	saveNotificationSettingsRepoFunc = func(settings *notifications.Settings) rest_errors.RestErr {
+		return nil
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	withinQuota()
+
+	result, err := NotificationSettingsService.UpdateSettings(context.Background(), notifications.Settings{
+		UserId:         1,
+		PrimaryChannel: "Telegram",
+		Channels: notifications.Channels{
+			{Type: "EMAIL", Target: " John@Mail.com ", Enabled: true, Verified: true},
+			{Type: "telegram", Target: "@johnny", Enabled: true, Verified: true},
+			{Type: "webhook", Target: "https://example.com/hook", Verified: true},
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, notifications.ChannelTelegram, result.PrimaryChannel)
+	assert.True(t, result.Channels.Find(notifications.ChannelEmail).Verified)
+	assert.False(t, result.Channels.Find(notifications.ChannelTelegram).Verified)
+	assert.False(t, result.Channels.Find(notifications.ChannelWebhook).Verified)
+}
+
+func TestUpdateNotificationSettingsInvalidReturnBadRequest(t *testing.T) {
+
+	invalid := []notifications.Settings{
+		{UserId: 1, PrimaryChannel: "sms", Channels: notifications.Channels{{Type: "sms", Target: "123", Enabled: true}}},
+		{UserId: 1, PrimaryChannel: "email", Channels: notifications.Channels{{Type: "email", Target: "not an email", Enabled: true}}},
+		{UserId: 1, PrimaryChannel: "webhook", Channels: notifications.Channels{{Type: "webhook", Target: "ftp://example.com", Enabled: true}}},
+		{UserId: 1, PrimaryChannel: "telegram", Channels: notifications.Channels{{Type: "telegram", Target: "john", Enabled: true}}},
+		{UserId: 1, PrimaryChannel: "email", Channels: notifications.Channels{{Type: "telegram", Target: "@john", Enabled: true}}},
+		{UserId: 1, PrimaryChannel: "telegram", Channels: notifications.Channels{{Type: "telegram", Target: "@john"}}},
+		{UserId: 1, Channels: notifications.Channels{{Type: "telegram", Target: "@john", Enabled: true}}},
+		{UserId: 1, PrimaryChannel: "email", Channels: notifications.Channels{
+			{Type: "email", Target: "john@mail.com", Enabled: true},
+			{Type: "email", Target: "other@mail.com", Enabled: true},
+		}},
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+
+	for _, settings := range invalid {
+		_, err := NotificationSettingsService.UpdateSettings(context.Background(), settings)
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.Status())
+	}
+}
+
+func TestVerifyNotificationChannelNotConfiguredReturnNotFound(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = emptyNotificationSettings
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+
+	_, err := NotificationSettingsService.VerifyChannel(context.Background(), 1, notifications.ChannelEmail)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestVerifyNotificationChannelOK(t *testing.T) {
+
+	getNotificationSettingsRepoFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, PrimaryChannel: "email", Channels: notifications.Channels{
+			{Type: notifications.ChannelEmail, Target: "john@mail.com", Enabled: true},
+		}}, nil
+	}
+	var verified string
+	markChannelVerifiedRepoFunc = func(userId int64, channelType string) rest_errors.RestErr {
+		verified = channelType
+		return nil
+	}
+
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+
+	settings, err := NotificationSettingsService.VerifyChannel(context.Background(), 1, "Email")
+
+	assert.Nil(t, err)
+	assert.Equal(t, notifications.ChannelEmail, verified)
+	assert.True(t, settings.Channels[0].Verified)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/logger"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	OutboxRelay outboxRelayInterface = &outboxRelay{
+		batchSize: 100,
+		interval:  time.Second,
+		lease:     time.Minute,
+	}
+)
+
+type outboxRelay struct {
+	batchSize int
+	interval  time.Duration
+	lease     time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+	mu        sync.Mutex
+}
+
+type outboxRelayInterface interface {
+	Start()
+	Stop()
+	RelayPending() (int, rest_errors.RestErr)
+}
+
+// Start polls the outbox in the background until Stop is called. Calling it on a
+// running relay does nothing.
+func (r *outboxRelay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(r.stop, r.done)
+}
+
+// Stop waits for the batch in progress, if any, and stops polling.
+func (r *outboxRelay) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+	r.done = nil
+}
+
+// RelayPending publishes one batch of pending events on the bus and queues them for
+// webhooks, and returns how many of them were published. Other relays leave the batch
+// alone for lease, far longer than publishing it takes.
+func (r *outboxRelay) RelayPending() (int, rest_errors.RestErr) {
+	ctx := context.Background()
+	return repositories.OutboxRepository.ProcessPending(ctx, r.batchSize, r.lease, func(event events.Event) error {
+		if err := EventBusService.Publish(ctx, event); err != nil {
+			return err
+		}
+		if err := WebhooksService.Dispatch(ctx, event); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// deliverWebhooks makes one batch of the webhook deliveries that are due, and returns
+// how many of them were attempted.
+func (r *outboxRelay) deliverWebhooks() (int, rest_errors.RestErr) {
+	return WebhooksService.DeliverPending(context.Background(), r.batchSize)
+}
+
+func (r *outboxRelay) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.drain(stop, "relay outbox events", r.RelayPending)
+			r.drain(stop, "deliver webhooks", r.deliverWebhooks)
+		}
+	}
+}
+
+// drain keeps calling batch while full batches come back, so a backlog does not have
+// to wait one interval per batch.
+func (r *outboxRelay) drain(stop <-chan struct{}, operation string, batch func() (int, rest_errors.RestErr)) {
+	for {
+		relayed, err := batch()
+		if err != nil {
+			logger.Error("error when trying to "+operation, err)
+			return
+		}
+		if relayed < r.batchSize {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type outboxRepoMock struct {
+	events    []events.Event
+	appendErr rest_errors.RestErr
+	pending   []events.Event
+	batches   int
+	relayed   chan int
+}
+
+func (m *outboxRepoMock) Append(ctx context.Context, event events.Event) rest_errors.RestErr {
+	if m.appendErr != nil {
+		return m.appendErr
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *outboxRepoMock) ProcessPending(ctx context.Context, limit int, lease time.Duration, handler func(events.Event) error) (int, rest_errors.RestErr) {
+	m.batches++
+	processed := 0
+	for processed < limit && processed < len(m.pending) {
+		if err := handler(m.pending[processed]); err != nil {
+			break
+		}
+		processed++
+	}
+	m.pending = m.pending[processed:]
+	if m.relayed != nil {
+		select {
+		case m.relayed <- processed:
+		default:
+		}
+	}
+	return processed, nil
+}
+
+func pendingEvents(count int) []events.Event {
+	pending := make([]events.Event, 0, count)
+	for i := 0; i < count; i++ {
+		pending = append(pending, events.Event{Id: "evt", Type: events.TypeUserCreated, UserId: int64(i + 1)})
+	}
+	return pending
+}
+
+func TestRelayPendingDispatchesEvents(t *testing.T) {
+	outbox := &outboxRepoMock{pending: pendingEvents(3)}
+	repositories.OutboxRepository = outbox
+	dispatched := captureEvents()
+
+	relay := &outboxRelay{batchSize: 10, interval: time.Millisecond}
+	relayed, err := relay.RelayPending()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, relayed)
+	assert.Equal(t, 3, len(dispatched.events))
+	assert.Empty(t, outbox.pending)
+}
+
+func TestRelayPendingKeepsEventsWhenDispatchFails(t *testing.T) {
+	outbox := &outboxRepoMock{pending: pendingEvents(2)}
+	repositories.OutboxRepository = outbox
+	dispatched := captureEvents()
+	dispatched.err = rest_errors.NewInternalServerError("error fetching webhooks", errors.New("database error"))
+
+	relay := &outboxRelay{batchSize: 10, interval: time.Millisecond}
+	relayed, err := relay.RelayPending()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, relayed)
+	assert.Equal(t, 2, len(outbox.pending))
+}
+
+func TestRelayDrainsFullBatches(t *testing.T) {
+	outbox := &outboxRepoMock{pending: pendingEvents(5)}
+	repositories.OutboxRepository = outbox
+	captureEvents()
+
+	relay := &outboxRelay{batchSize: 2, interval: time.Millisecond}
+	relay.drain(make(chan struct{}), "relay outbox events", relay.RelayPending)
+
+	assert.Empty(t, outbox.pending)
+	assert.Equal(t, 3, outbox.batches)
+}
+
+func TestRelayDrainsDueWebhookDeliveries(t *testing.T) {
+	dispatched := captureEvents()
+	dispatched.due = 5
+
+	relay := &outboxRelay{batchSize: 2, interval: time.Millisecond}
+	relay.drain(make(chan struct{}), "deliver webhooks", relay.deliverWebhooks)
+
+	assert.Equal(t, 0, dispatched.due)
+	assert.Equal(t, 3, dispatched.deliveryBatches)
+}
+
+func TestRelayStartAndStop(t *testing.T) {
+	outbox := &outboxRepoMock{pending: pendingEvents(1), relayed: make(chan int, 1)}
+	repositories.OutboxRepository = outbox
+	dispatched := captureEvents()
+
+	relay := &outboxRelay{batchSize: 10, interval: time.Millisecond}
+	relay.Start()
+	relay.Start()
+	select {
+	case <-outbox.relayed:
+	case <-time.After(time.Second):
+		t.Fatal("relay did not poll the outbox")
+	}
+	relay.Stop()
+	relay.Stop()
+
+	assert.Nil(t, relay.stop)
+	assert.Equal(t, 1, len(dispatched.events))
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	PlansService plansServiceInterface = &plansService{}
+)
+
+type plansService struct{}
+
+type plansServiceInterface interface {
+	GetUserPlan(context.Context, int64) (*plans.Plan, rest_errors.RestErr)
+	AssignPlan(context.Context, plans.Assignment) (*plans.Assignment, rest_errors.RestErr)
+	GetPlanHistory(context.Context, int64) (plans.Assignments, rest_errors.RestErr)
+}
+
+// GetUserPlan returns the plan in effect right now, falling back to the free plan for
+// users without an active assignment.
+func (s *plansService) GetUserPlan(ctx context.Context, userId int64) (*plans.Plan, rest_errors.RestErr) {
+	return userPlan(ctx, repositories.Default(), userId)
+}
+
+// userPlan is GetUserPlan on the given repositories, which may share a transaction.
+func userPlan(ctx context.Context, repos repositories.Repositories, userId int64) (*plans.Plan, rest_errors.RestErr) {
+	assignment, err := repos.PlanAssignments.GetActive(ctx, userId, date_utils.GetNowDBFormat())
+	if err != nil {
+		if err.Status() == http.StatusNotFound {
+			return plans.Default(), nil
+		}
+		return nil, err
+	}
+
+	plan, ok := plans.Get(assignment.Plan)
+	if !ok {
+		logging.Info(ctx, "user assigned to the unknown plan "+assignment.Plan+", falling back to the default plan")
+		return plans.Default(), nil
+	}
+	return plan, nil
+}
+
+func (s *plansService) AssignPlan(ctx context.Context, assignment plans.Assignment) (*plans.Assignment, rest_errors.RestErr) {
+	if err := assignment.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := repositories.UsersRepository.Get(ctx, assignment.UserId); err != nil {
+		return nil, err
+	}
+
+	assignment.DateCreated = date_utils.GetNowDBFormat()
+	if err := repositories.PlanAssignmentsRepository.Save(ctx, &assignment); err != nil {
+		return nil, err
+	}
+
+	entry := newAuditEntry(ctx, audit.ActionPlanAssigned, audit.OutcomeSuccess, assignment.UserId)
+	entry.Details = map[string]string{"assignment_id": strconv.FormatInt(assignment.Id, 10), "plan": assignment.Plan,
+		"effective_from": assignment.EffectiveFrom, "effective_until": assignment.EffectiveUntil}
+	AuditService.Record(ctx, entry)
+	return &assignment, nil
+}
+
+func (s *plansService) GetPlanHistory(ctx context.Context, userId int64) (plans.Assignments, rest_errors.RestErr) {
+	return repositories.PlanAssignmentsRepository.FindByUserId(ctx, userId)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	savePlanAssignmentRepoFunc        func(*plans.Assignment) rest_errors.RestErr
+	getActivePlanAssignmentRepoFunc   func(int64, string) (*plans.Assignment, rest_errors.RestErr)
+	findPlanAssignmentsByUserRepoFunc func(int64) (plans.Assignments, rest_errors.RestErr)
+	lockQuotaRepoFunc                 func(int64) rest_errors.RestErr
+)
+
+type planAssignmentsRepoMock struct{}
+
+func (*planAssignmentsRepoMock) Save(ctx context.Context, assignment *plans.Assignment) rest_errors.RestErr {
+	return savePlanAssignmentRepoFunc(assignment)
+}
+
+func (*planAssignmentsRepoMock) GetActive(ctx context.Context, userId int64, now string) (*plans.Assignment, rest_errors.RestErr) {
+	return getActivePlanAssignmentRepoFunc(userId, now)
+}
+
+func (*planAssignmentsRepoMock) FindByUserId(ctx context.Context, userId int64) (plans.Assignments, rest_errors.RestErr) {
+	return findPlanAssignmentsByUserRepoFunc(userId)
+}
+
+func (*planAssignmentsRepoMock) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	return deleteByUserRepoFunc("user_plans", userId)
+}
+
+func (*planAssignmentsRepoMock) LockQuota(ctx context.Context, userId int64) rest_errors.RestErr {
+	return lockQuotaRepoFunc(userId)
+}
+
+func TestGetUserPlanWithoutAssignmentReturnsFree(t *testing.T) {
+	getActivePlanAssignmentRepoFunc = func(userId int64, now string) (*plans.Assignment, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("no active plan assignment")
+	}
+	repositories.PlanAssignmentsRepository = &planAssignmentsRepoMock{}
+	PlansService = &plansService{}
+
+	plan, err := PlansService.GetUserPlan(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, plans.PlanFree, plan.Code)
+}
+
+func TestGetUserPlanFailReturnInternalServerError(t *testing.T) {
+	getActivePlanAssignmentRepoFunc = func(userId int64, now string) (*plans.Assignment, rest_errors.RestErr) {
+		return nil, rest_errors.NewInternalServerError("error fetching plan assignment", errors.New("database error"))
+	}
+	repositories.PlanAssignmentsRepository = &planAssignmentsRepoMock{}
+	PlansService = &plansService{}
+
+	_, err := PlansService.GetUserPlan(context.Background(), 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+}
+
+func TestAssignPlanOK(t *testing.T) {
+	withClock(t, "2022-09-06T10:00:00Z")
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: id}, nil
+	}
+	savePlanAssignmentRepoFunc = func(assignment *plans.Assignment) rest_errors.RestErr {
+		assignment.Id = 7
+		return nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	repositories.PlanAssignmentsRepository = &planAssignmentsRepoMock{}
+	PlansService = &plansService{}
+	auditLog := withAuditMock()
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserId: 2})
+
+	assignment, err := PlansService.AssignPlan(ctx, plans.Assignment{UserId: 1, Plan: " PRO ", EffectiveUntil: "2022-10-06 10:00:00"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), assignment.Id)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionPlanAssigned, auditLog.entries[0].Action)
+	assert.Equal(t, int64(2), auditLog.entries[0].ActorId)
+	assert.Equal(t, int64(1), auditLog.entries[0].TargetUserId)
+	assert.Equal(t, "pro", auditLog.entries[0].Details["plan"])
+	assert.Equal(t, plans.PlanPro, assignment.Plan)
+	assert.Equal(t, "2022-09-06 10:00:00", assignment.EffectiveFrom)
+	assert.Equal(t, "2022-09-06 10:00:00", assignment.DateCreated)
+}
+
+func TestAssignPlanInvalidReturnBadRequest(t *testing.T) {
+	PlansService = &plansService{}
+
+	invalid := []plans.Assignment{
+		{UserId: 1, Plan: "enterprise"},
+		{UserId: 1, Plan: "pro", EffectiveFrom: "tomorrow"},
+		{UserId: 1, Plan: "pro", EffectiveFrom: "2022-09-06 10:00:00", EffectiveUntil: "2022-09-01 10:00:00"},
+	}
+
+	for _, assignment := range invalid {
+		_, err := PlansService.AssignPlan(context.Background(), assignment)
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.Status())
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"time"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	QuietHoursService quietHoursServiceInterface = &quietHoursService{}
+)
+
+type quietHoursService struct{}
+
+type quietHoursServiceInterface interface {
+	GetQuietHours(context.Context, int64) (*notifications.QuietHours, rest_errors.RestErr)
+	UpdateQuietHours(context.Context, notifications.QuietHours) (*notifications.QuietHours, rest_errors.RestErr)
+	CanNotify(context.Context, int64, bool) (*notifications.Availability, rest_errors.RestErr)
+}
+
+func (s *quietHoursService) GetQuietHours(ctx context.Context, userId int64) (*notifications.QuietHours, rest_errors.RestErr) {
+	return repositories.QuietHoursRepository.GetByUserId(ctx, userId)
+}
+
+func (s *quietHoursService) UpdateQuietHours(ctx context.Context, quietHours notifications.QuietHours) (*notifications.QuietHours, rest_errors.RestErr) {
+	if err := quietHours.Validate(); err != nil {
+		return nil, err
+	}
+	if quietHours.Windows == nil {
+		quietHours.Windows = []notifications.QuietWindow{}
+	}
+
+	if _, err := repositories.UsersRepository.Get(ctx, quietHours.UserId); err != nil {
+		return nil, err
+	}
+	if err := repositories.QuietHoursRepository.Save(ctx, &quietHours); err != nil {
+		return nil, err
+	}
+	return &quietHours, nil
+}
+
+// CanNotify evaluates the quiet hours of the user against the current time in the
+// user's own time zone.
+func (s *quietHoursService) CanNotify(ctx context.Context, userId int64, urgent bool) (*notifications.Availability, rest_errors.RestErr) {
+	user, err := repositories.UsersRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	quietHours, err := repositories.QuietHoursRepository.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	location, locationErr := user.Location()
+	if locationErr != nil {
+		logging.Error(ctx, "error when trying to load user time zone, falling back to utc", locationErr)
+		location = time.UTC
+	}
+	local := date_utils.GetNowIn(location)
+
+	canNotify, reason := quietHours.Check(local, urgent)
+	return &notifications.Availability{
+		UserId:    userId,
+		CanNotify: canNotify,
+		Reason:    reason,
+		TimeZone:  local.Location().String(),
+		LocalTime: local.Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	getQuietHoursRepoFunc  func(int64) (*notifications.QuietHours, rest_errors.RestErr)
+	saveQuietHoursRepoFunc func(*notifications.QuietHours) rest_errors.RestErr
+)
+
+type quietHoursRepoMock struct{}
+
+func (*quietHoursRepoMock) GetByUserId(ctx context.Context, userId int64) (*notifications.QuietHours, rest_errors.RestErr) {
+	return getQuietHoursRepoFunc(userId)
+}
+
+func (*quietHoursRepoMock) Save(ctx context.Context, quietHours *notifications.QuietHours) rest_errors.RestErr {
+	return saveQuietHoursRepoFunc(quietHours)
+}
+
+func (*quietHoursRepoMock) DeleteByUserId(ctx context.Context, userId int64) rest_errors.RestErr {
+	return deleteByUserRepoFunc("quiet_hours", userId)
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func withClock(t *testing.T, now string) {
+	parsed, err := time.Parse(time.RFC3339, now)
+	assert.Nil(t, err)
+	previous := date_utils.Clock
+	date_utils.Clock = &fixedClock{now: parsed}
+	t.Cleanup(func() {
+		date_utils.Clock = previous
+	})
+}
+
+func mockQuietHoursUser(timeZone string, quietHours notifications.QuietHours) {
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: id, TimeZone: timeZone}, nil
+	}
+	getQuietHoursRepoFunc = func(userId int64) (*notifications.QuietHours, rest_errors.RestErr) {
+		quietHours.UserId = userId
+		return &quietHours, nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	repositories.QuietHoursRepository = &quietHoursRepoMock{}
+}
+
+var overnightQuietHours = notifications.QuietHours{Enabled: true, UrgentBypass: true, Windows: []notifications.QuietWindow{
+	{Weekday: "monday", Start: "22:00", End: "07:00"},
+}}
+
+func TestCanNotifyInsideQuietHoursOfUserTimeZone(t *testing.T) {
+	// 2022-09-06 03:00 in Madrid is Tuesday, inside the window that started Monday night.
+	withClock(t, "2022-09-06T01:00:00Z")
+	mockQuietHoursUser("Europe/Madrid", overnightQuietHours)
+
+	availability, err := QuietHoursService.CanNotify(context.Background(), 1, false)
+
+	assert.Nil(t, err)
+	assert.False(t, availability.CanNotify)
+	assert.Equal(t, notifications.ReasonQuietHours, availability.Reason)
+	assert.Equal(t, "Europe/Madrid", availability.TimeZone)
+	assert.Equal(t, "2022-09-06T03:00:00+02:00", availability.LocalTime)
+}
+
+func TestCanNotifyUrgentBypassesQuietHours(t *testing.T) {
+	withClock(t, "2022-09-06T01:00:00Z")
+	mockQuietHoursUser("Europe/Madrid", overnightQuietHours)
+
+	availability, err := QuietHoursService.CanNotify(context.Background(), 1, true)
+
+	assert.Nil(t, err)
+	assert.True(t, availability.CanNotify)
+	assert.Equal(t, notifications.ReasonUrgent, availability.Reason)
+}
+
+func TestCanNotifyOutsideQuietHours(t *testing.T) {
+	// The same instant is Monday 21:30 in Buenos Aires, before the window starts.
+	withClock(t, "2022-09-06T00:30:00Z")
+	mockQuietHoursUser("America/Argentina/Buenos_Aires", overnightQuietHours)
+
+	availability, err := QuietHoursService.CanNotify(context.Background(), 1, false)
+
+	assert.Nil(t, err)
+	assert.True(t, availability.CanNotify)
+	assert.Equal(t, notifications.ReasonAvailable, availability.Reason)
+	assert.Equal(t, "2022-09-05T21:30:00-03:00", availability.LocalTime)
+}
+
+func TestCanNotifyDisabledQuietHours(t *testing.T) {
+	withClock(t, "2022-09-06T01:00:00Z")
+	disabled := overnightQuietHours
+	disabled.Enabled = false
+	mockQuietHoursUser("Europe/Madrid", disabled)
+
+	availability, err := QuietHoursService.CanNotify(context.Background(), 1, false)
+
+	assert.Nil(t, err)
+	assert.True(t, availability.CanNotify)
+}
+
+func TestCanNotifyUnknownUserReturnNotFound(t *testing.T) {
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("user not found")
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+
+	_, err := QuietHoursService.CanNotify(context.Background(), 1, false)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestUpdateQuietHoursInvalidReturnBadRequest(t *testing.T) {
+
+	invalid := [][]notifications.QuietWindow{
+		{{Weekday: "someday", Start: "22:00", End: "07:00"}},
+		{{Weekday: "monday", Start: "25:00", End: "07:00"}},
+		{{Weekday: "monday", Start: "22:00", End: "7:00"}},
+		{{Weekday: "monday", Start: "24:00", End: "07:00"}},
+		{{Weekday: "monday", Start: "07:00", End: "07:00"}},
+	}
+
+	for _, windows := range invalid {
+		_, err := QuietHoursService.UpdateQuietHours(context.Background(), notifications.QuietHours{UserId: 1, Enabled: true, Windows: windows})
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.Status())
+	}
+}
+
+func TestUpdateQuietHoursOK(t *testing.T) {
+	getUserRepoFunc = func(id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: id}, nil
+	}
+	saveQuietHoursRepoFunc = func(quietHours *notifications.QuietHours) rest_errors.RestErr {
+		return nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	repositories.QuietHoursRepository = &quietHoursRepoMock{}
+
+	result, err := QuietHoursService.UpdateQuietHours(context.Background(), notifications.QuietHours{UserId: 1, Enabled: true, Windows: []notifications.QuietWindow{
+		{Weekday: " Sunday ", Start: "00:00", End: "24:00"},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "sunday", result.Windows[0].Weekday)
+}
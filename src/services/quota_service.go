@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+var (
+	QuotaService quotaServiceInterface = &quotaService{}
+)
+
+type quotaService struct{}
+
+type quotaServiceInterface interface {
+	GetUsage(context.Context, int64) (*plans.Usage, rest_errors.RestErr)
+	CheckAlertRuleQuota(context.Context, repositories.Repositories, alerts.AlertRule) rest_errors.RestErr
+	CheckNotificationChannelsQuota(context.Context, int64, int) rest_errors.RestErr
+}
+
+func newQuotaExceededError(resource string, plan *plans.Plan, limit int) rest_errors.RestErr {
+	message := fmt.Sprintf("the %s plan allows up to %d %s", plan.Name, limit, resource)
+	return rest_errors.NewRestError(message, http.StatusForbidden, "quota_exceeded", nil)
+}
+
+// Watchlist tokens are the distinct tokens the user has alert rules for.
+func watchlistTokens(rules alerts.AlertRules) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, rule := range rules {
+		tokens[rule.Token] = true
+	}
+	return tokens
+}
+
+func (s *quotaService) GetUsage(ctx context.Context, userId int64) (*plans.Usage, rest_errors.RestErr) {
+	plan, err := PlansService.GetUserPlan(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := repositories.AlertRulesRepository.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := NotificationSettingsService.GetSettings(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plans.Usage{
+		UserId:               userId,
+		Plan:                 plan,
+		WatchlistTokens:      plans.Quota{Used: len(watchlistTokens(rules)), Limit: plan.MaxWatchlistTokens},
+		AlertRules:           plans.Quota{Used: len(rules), Limit: plan.MaxAlertRules},
+		NotificationChannels: plans.Quota{Used: len(settings.Channels), Limit: plan.MaxNotificationChannels},
+	}, nil
+}
+
+// CheckAlertRuleQuota verifies the user can store the rule, which also adds its token
+// to the watchlist when it is not already on it. Rules that already have an id are
+// being updated, so they are left out of the current usage. repos must share the
+// transaction the rule is stored in: the quota of the user stays locked until it ends,
+// so concurrent requests can not both pass the check.
+func (s *quotaService) CheckAlertRuleQuota(ctx context.Context, repos repositories.Repositories, rule alerts.AlertRule) rest_errors.RestErr {
+	if err := repos.PlanAssignments.LockQuota(ctx, rule.UserId); err != nil {
+		return err
+	}
+	plan, err := userPlan(ctx, repos, rule.UserId)
+	if err != nil {
+		return err
+	}
+	stored, err := repos.AlertRules.FindByUserId(ctx, rule.UserId)
+	if err != nil {
+		return err
+	}
+
+	others := make(alerts.AlertRules, 0, len(stored))
+	for _, current := range stored {
+		if rule.Id == 0 || current.Id != rule.Id {
+			others = append(others, current)
+		}
+	}
+
+	if len(others) >= plan.MaxAlertRules {
+		return newQuotaExceededError("alert rules", plan, plan.MaxAlertRules)
+	}
+	tokens := watchlistTokens(others)
+	if !tokens[rule.Token] && len(tokens) >= plan.MaxWatchlistTokens {
+		return newQuotaExceededError("watchlist tokens", plan, plan.MaxWatchlistTokens)
+	}
+	return nil
+}
+
+func (s *quotaService) CheckNotificationChannelsQuota(ctx context.Context, userId int64, channels int) rest_errors.RestErr {
+	plan, err := PlansService.GetUserPlan(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if channels > plan.MaxNotificationChannels {
+		return newQuotaExceededError("notification channels", plan, plan.MaxNotificationChannels)
+	}
+	return nil
+}
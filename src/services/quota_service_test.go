@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"tokenalert_user-api/src/domain/alerts"
+	"tokenalert_user-api/src/domain/notifications"
+	"tokenalert_user-api/src/domain/plans"
+	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	checkAlertRuleQuotaFunc            func(alerts.AlertRule) rest_errors.RestErr
+	checkNotificationChannelsQuotaFunc func(int64, int) rest_errors.RestErr
+)
+
+type quotaServiceMock struct{}
+
+func (*quotaServiceMock) GetUsage(ctx context.Context, userId int64) (*plans.Usage, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (*quotaServiceMock) CheckAlertRuleQuota(ctx context.Context, repos repositories.Repositories, rule alerts.AlertRule) rest_errors.RestErr {
+	return checkAlertRuleQuotaFunc(rule)
+}
+
+func (*quotaServiceMock) CheckNotificationChannelsQuota(ctx context.Context, userId int64, channels int) rest_errors.RestErr {
+	return checkNotificationChannelsQuotaFunc(userId, channels)
+}
+
+func withinQuota() {
+	checkAlertRuleQuotaFunc = func(alerts.AlertRule) rest_errors.RestErr {
+		return nil
+	}
+	checkNotificationChannelsQuotaFunc = func(int64, int) rest_errors.RestErr {
+		return nil
+	}
+	QuotaService = &quotaServiceMock{}
+	repositories.TransactionManager = &transactionManagerMock{}
+}
+
+func onPlan(code string) {
+	getActivePlanAssignmentRepoFunc = func(userId int64, now string) (*plans.Assignment, rest_errors.RestErr) {
+		return &plans.Assignment{UserId: userId, Plan: code}, nil
+	}
+	lockQuotaRepoFunc = func(int64) rest_errors.RestErr {
+		return nil
+	}
+	repositories.PlanAssignmentsRepository = &planAssignmentsRepoMock{}
+	PlansService = &plansService{}
+	QuotaService = &quotaService{}
+}
+
+func withAlertRules(rules alerts.AlertRules) {
+	findAlertRulesByUserRepoFunc = func(int64) (alerts.AlertRules, rest_errors.RestErr) {
+		return rules, nil
+	}
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+}
+
+func TestCheckAlertRuleQuotaRulesExceeded(t *testing.T) {
+	onPlan(plans.PlanFree)
+	rules := make(alerts.AlertRules, 0)
+	for id := int64(1); id <= 10; id++ {
+		rules = append(rules, alerts.AlertRule{Id: id, UserId: 1, Token: "btc"})
+	}
+	withAlertRules(rules)
+
+	err := QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{UserId: 1, Token: "btc"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 403, err.Status())
+	assert.Equal(t, "the Free plan allows up to 10 alert rules", err.Message())
+
+	err = QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{Id: 3, UserId: 1, Token: "btc"})
+
+	assert.Nil(t, err)
+}
+
+func TestCheckAlertRuleQuotaWatchlistTokensExceeded(t *testing.T) {
+	onPlan(plans.PlanFree)
+	withAlertRules(alerts.AlertRules{
+		{Id: 1, Token: "btc"}, {Id: 2, Token: "eth"}, {Id: 3, Token: "sol"}, {Id: 4, Token: "ada"}, {Id: 5, Token: "dot"},
+	})
+
+	err := QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{UserId: 1, Token: "xrp"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 403, err.Status())
+	assert.Equal(t, "the Free plan allows up to 5 watchlist tokens", err.Message())
+
+	assert.Nil(t, QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{UserId: 1, Token: "eth"}))
+	assert.Nil(t, QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{Id: 5, UserId: 1, Token: "xrp"}))
+}
+
+func TestCheckNotificationChannelsQuota(t *testing.T) {
+	onPlan(plans.PlanFree)
+
+	assert.Nil(t, QuotaService.CheckNotificationChannelsQuota(context.Background(), 1, 1))
+
+	err := QuotaService.CheckNotificationChannelsQuota(context.Background(), 1, 2)
+	assert.NotNil(t, err)
+	assert.Equal(t, 403, err.Status())
+
+	onPlan(plans.PlanPro)
+
+	assert.Nil(t, QuotaService.CheckNotificationChannelsQuota(context.Background(), 1, 3))
+}
+
+func TestGetUsageOK(t *testing.T) {
+	onPlan(plans.PlanPro)
+	withAlertRules(alerts.AlertRules{{Id: 1, Token: "btc"}, {Id: 2, Token: "btc"}, {Id: 3, Token: "eth"}})
+	getNotificationSettingsRepoFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId, Channels: notifications.Channels{{Type: "telegram"}, {Type: "email"}}}, nil
+	}
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+
+	usage, err := QuotaService.GetUsage(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, plans.PlanPro, usage.Plan.Code)
+	assert.Equal(t, plans.Quota{Used: 2, Limit: 50}, usage.WatchlistTokens)
+	assert.Equal(t, plans.Quota{Used: 3, Limit: 200}, usage.AlertRules)
+	assert.Equal(t, plans.Quota{Used: 2, Limit: 3}, usage.NotificationChannels)
+}
+
+func TestCheckAlertRuleQuotaLocksQuotaFirst(t *testing.T) {
+	onPlan(plans.PlanFree)
+	calls := make([]string, 0)
+	lockQuotaRepoFunc = func(userId int64) rest_errors.RestErr {
+		calls = append(calls, "lock")
+		return nil
+	}
+	findAlertRulesByUserRepoFunc = func(int64) (alerts.AlertRules, rest_errors.RestErr) {
+		calls = append(calls, "find")
+		return alerts.AlertRules{}, nil
+	}
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+
+	assert.Nil(t, QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{UserId: 1, Token: "btc"}))
+	assert.Equal(t, []string{"lock", "find"}, calls)
+
+	lockQuotaRepoFunc = func(userId int64) rest_errors.RestErr {
+		return rest_errors.NewNotFoundError("user not found")
+	}
+	err := QuotaService.CheckAlertRuleQuota(context.Background(), repositories.Default(), alerts.AlertRule{UserId: 1, Token: "btc"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+}
+
+func TestGetUsageCountsTelegramFallback(t *testing.T) {
+	onPlan(plans.PlanFree)
+	withAlertRules(alerts.AlertRules{})
+	getNotificationSettingsRepoFunc = func(userId int64) (*notifications.Settings, rest_errors.RestErr) {
+		return &notifications.Settings{UserId: userId}, nil
+	}
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	withUsers(users.User{Id: 1, TelegramUser: "@john"})
+
+	usage, err := QuotaService.GetUsage(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, plans.Quota{Used: 1, Limit: 1}, usage.NotificationChannels)
+}
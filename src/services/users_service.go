@@ -1,8 +1,18 @@
 package services
 
 import (
+	"context"
+	"net/http"
+	"strings"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
 	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/metrics"
 	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/tracing"
 	"tokenalert_user-api/src/utils/crypto_utils"
 	"tokenalert_user-api/src/utils/date_utils"
 
@@ -11,44 +21,241 @@ import (
 
 var (
 	UsersService usersServiceInterface = &usersService{}
+
+	loginSuccesses = metrics.NewCounter("users_login_successes_total", "Successful logins.")
+	loginFailures  = metrics.NewCounter("users_login_failures_total", "Failed logins, by reason.", "reason")
+	usersCreated   = metrics.NewCounter("users_created_total", "Users created.")
+	createFailures = metrics.NewCounter("users_create_failures_total", "Users not created, by reason.", "reason")
 )
 
+// failureReason names the cause of err for the failure counters.
+func failureReason(err rest_errors.RestErr) string {
+	switch err.Status() {
+	case http.StatusBadRequest:
+		if err.Message() == "email already registered" {
+			return "email_registered"
+		}
+		return "invalid_request"
+	case http.StatusNotFound:
+		return "invalid_credentials"
+	case http.StatusGatewayTimeout:
+		return "database_timeout"
+	case repositories.StatusClientClosedRequest:
+		return "canceled"
+	}
+	return "error"
+}
+
+// hashPassword hashes a password in a span of its own, as hashing is what a slow
+// password change would most likely be waiting on.
+func hashPassword(ctx context.Context, password string) string {
+	_, span := tracing.Start(ctx, "crypto.hash_password")
+	defer span.End()
+
+	return crypto_utils.GetMd5(password)
+}
+
 type usersService struct{}
 
 type usersServiceInterface interface {
-	CreateUser(users.User) (*users.User, rest_errors.RestErr)
-	GetUser(int64) (*users.User, rest_errors.RestErr)
-	LoginUser(users.LoginRequest) (*users.User, rest_errors.RestErr)
+	CreateUser(context.Context, users.User) (*users.User, rest_errors.RestErr)
+	GetUser(context.Context, int64) (*users.User, rest_errors.RestErr)
+	UpdateUser(context.Context, bool, users.User) (*users.User, rest_errors.RestErr)
+	DeleteUser(context.Context, int64) rest_errors.RestErr
+	ChangePassword(context.Context, int64, users.ChangePasswordRequest) rest_errors.RestErr
+	LoginUser(context.Context, users.LoginRequest) (*users.User, rest_errors.RestErr)
 }
 
-func (s *usersService) CreateUser(user users.User) (*users.User, rest_errors.RestErr) {
+func (s *usersService) CreateUser(ctx context.Context, user users.User) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.CreateUser")
+	defer span.End()
+
 	if err := user.Validate(); err != nil {
+		createFailures.Inc(failureReason(err))
 		return nil, err
 	}
 
 	user.Status = users.StatusActive
+	user.Role = access.RoleUser
 	user.DateCreated = date_utils.GetNowDBFormat()
-	user.Password = crypto_utils.GetMd5(user.Password)
-	if err := repositories.UsersRepository.Save(&user); err != nil {
+	user.Password = hashPassword(ctx, user.Password)
+	eventTypes := []string{events.TypeUserCreated}
+	if user.TelegramUser != "" {
+		eventTypes = append(eventTypes, events.TypeTelegramLinked)
+	}
+	if err := repositories.UsersRepository.Save(ctx, &user, eventTypes...); err != nil {
+		createFailures.Inc(failureReason(err))
 		return nil, err
 	}
+	usersCreated.Inc()
+
+	entry := newAuditEntry(ctx, audit.ActionUserCreated, audit.OutcomeSuccess, user.Id)
+	entry.Changes = audit.ProfileChanges(users.User{}, user)
+	AuditService.Record(ctx, entry)
 	return &user, nil
 }
 
-func (s *usersService) GetUser(userId int64) (*users.User, rest_errors.RestErr) {
+func (s *usersService) GetUser(ctx context.Context, userId int64) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.GetUser")
+	defer span.End()
+
 	var user *users.User
 	var err rest_errors.RestErr
-	if user, err = repositories.UsersRepository.Get(userId); err != nil {
+	if user, err = repositories.UsersRepository.Get(ctx, userId); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *usersService) LoginUser(request users.LoginRequest) (*users.User, rest_errors.RestErr) {	
+func (s *usersService) UpdateUser(ctx context.Context, isPartial bool, user users.User) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.UpdateUser")
+	defer span.End()
+
+	current, err := s.GetUser(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	previous := *current
+
+	if isPartial {
+		if user.Name != "" {
+			current.Name = user.Name
+		}
+		if user.Email != "" {
+			current.Email = user.Email
+		}
+		if user.TelegramUser != "" {
+			current.TelegramUser = user.TelegramUser
+		}
+		if user.TimeZone != "" {
+			current.TimeZone = user.TimeZone
+		}
+	} else {
+		current.Name = user.Name
+		current.Email = user.Email
+		current.TelegramUser = user.TelegramUser
+		current.TimeZone = user.TimeZone
+	}
+
+	if err := current.ValidateProfile(); err != nil {
+		return nil, err
+	}
+	// The email is where password resets go, so it is not changed for the user.
+	if current.Email != previous.Email {
+		if err := ImpersonationService.Restrict(ctx, "changing the email"); err != nil {
+			return nil, err
+		}
+	}
+	eventTypes := []string{events.TypeUserUpdated}
+	if current.TelegramUser != "" && current.TelegramUser != previous.TelegramUser {
+		eventTypes = append(eventTypes, events.TypeTelegramLinked)
+	}
+	if err := repositories.UsersRepository.Update(ctx, current, eventTypes...); err != nil {
+		return nil, err
+	}
+
+	if changes := audit.ProfileChanges(previous, *current); len(changes) > 0 {
+		entry := newAuditEntry(ctx, audit.ActionProfileUpdated, audit.OutcomeSuccess, current.Id)
+		entry.Changes = changes
+		AuditService.Record(ctx, entry)
+	}
+	return current, nil
+}
+
+func (s *usersService) DeleteUser(ctx context.Context, userId int64) rest_errors.RestErr {
+	ctx, span := tracing.Start(ctx, "usersService.DeleteUser")
+	defer span.End()
+
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	// Everything owned by the user goes away with it, or nothing does, and the
+	// deletion is never left out of the audit log.
+	entry := newAuditEntry(ctx, audit.ActionUserDeleted, audit.OutcomeSuccess, userId)
+	entry.Changes = audit.ProfileChanges(*user, users.User{})
+	return repositories.TransactionManager.Run(ctx, func(repos repositories.Repositories) rest_errors.RestErr {
+		if err := repos.AlertRules.DeleteByUserId(ctx, userId); err != nil {
+			return err
+		}
+		if err := repos.NotificationSettings.DeleteByUserId(ctx, userId); err != nil {
+			return err
+		}
+		if err := repos.QuietHours.DeleteByUserId(ctx, userId); err != nil {
+			return err
+		}
+		if err := repos.PlanAssignments.DeleteByUserId(ctx, userId); err != nil {
+			return err
+		}
+		if err := repos.Users.Delete(ctx, user, events.TypeUserDeleted); err != nil {
+			return err
+		}
+		return repos.Audit.Append(ctx, &entry)
+	})
+}
+
+func (s *usersService) ChangePassword(ctx context.Context, userId int64, request users.ChangePasswordRequest) rest_errors.RestErr {
+	ctx, span := tracing.Start(ctx, "usersService.ChangePassword")
+	defer span.End()
+
+	newPassword := strings.TrimSpace(request.NewPassword)
+	if newPassword == "" {
+		return rest_errors.NewBadRequestError("invalid password")
+	}
+
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	login := users.LoginRequest{Email: user.Email, Password: hashPassword(ctx, strings.TrimSpace(request.CurrentPassword))}
+	if _, err := repositories.UsersRepository.FindByEmailAndPassword(users_db.WithPrimary(ctx), login); err != nil {
+		if err.Status() == http.StatusNotFound {
+			entry := newAuditEntry(ctx, audit.ActionPasswordChanged, audit.OutcomeFailure, userId)
+			entry.Details = map[string]string{"reason": "invalid_current_password"}
+			AuditService.Record(ctx, entry)
+			return rest_errors.NewUnauthorizedError("invalid current password")
+		}
+		return err
+	}
+
+	if err := repositories.UsersRepository.UpdatePassword(ctx, user, hashPassword(ctx, newPassword), events.TypePasswordChanged); err != nil {
+		return err
+	}
+	AuditService.Record(ctx, newAuditEntry(ctx, audit.ActionPasswordChanged, audit.OutcomeSuccess, userId))
+	return nil
+}
+
+func (s *usersService) LoginUser(ctx context.Context, request users.LoginRequest) (*users.User, rest_errors.RestErr) {
+	ctx, span := tracing.Start(ctx, "usersService.LoginUser")
+	defer span.End()
+
+	// Credentials are checked on the primary, so a password change or a suspension
+	// not replicated yet can not be bypassed.
 	var user *users.User
 	var err rest_errors.RestErr
-	if user, err = repositories.UsersRepository.FindByEmailAndPassword(request); err != nil {
+	if user, err = repositories.UsersRepository.FindByEmailAndPassword(users_db.WithPrimary(ctx), request); err != nil {
+		loginFailures.Inc(failureReason(err))
+		entry := newAuditEntry(ctx, audit.ActionLoginFailed, audit.OutcomeFailure, 0)
+		entry.Details = map[string]string{"email": strings.TrimSpace(strings.ToLower(request.Email)), "reason": failureReason(err)}
+		AuditService.Record(ctx, entry)
 		return nil, err
 	}
+	loginSuccesses.Inc()
+
+	entry := newAuditEntry(ctx, audit.ActionLoginSucceeded, audit.OutcomeSuccess, user.Id)
+	entry.ActorId = user.Id
+	AuditService.Record(ctx, entry)
+
+	// Logins do not change the user, so the event is appended on its own. Failing
+	// to record it never fails the login.
+	event, eventErr := events.New(events.TypeUserLoggedIn, user.Id, user.Marshall(false))
+	if eventErr != nil {
+		logging.Error(ctx, "error when trying to build event "+events.TypeUserLoggedIn, eventErr)
+		return user, nil
+	}
+	if appendErr := repositories.OutboxRepository.Append(ctx, *event); appendErr != nil {
+		logging.Error(ctx, "error when trying to append event "+events.TypeUserLoggedIn, appendErr)
+	}
 	return user, nil
 }
@@ -1,9 +1,16 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"net/http"
 	"testing"
+	"tokenalert_user-api/src/datasources/mysql/users_db"
+	"tokenalert_user-api/src/domain/access"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
 	"tokenalert_user-api/src/domain/users"
+	"tokenalert_user-api/src/utils/crypto_utils"
 	"tokenalert_user-api/src/repositories"
 
 	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
@@ -11,38 +18,105 @@ import (
 )
 
 var (
-	createUserRepoFunc func(user *users.User) rest_errors.RestErr
+	createUserRepoFunc func(user *users.User, eventTypes []string) rest_errors.RestErr
 	getUserRepoFunc func(int64) (*users.User, rest_errors.RestErr)
+	updateUserRepoFunc func(*users.User, []string) rest_errors.RestErr
+	updateUserPasswordRepoFunc func(*users.User, string, []string) rest_errors.RestErr
+	deleteUserRepoFunc func(*users.User, []string) rest_errors.RestErr
+	updateUserAccessRepoFunc func(*users.User, []string) rest_errors.RestErr
+	searchUsersRepoFunc func(users.Filter) (users.Users, rest_errors.RestErr)
 	findByEmailAndPasswordRepoFunc func(users.LoginRequest) (*users.User, rest_errors.RestErr)
+	deleteByUserRepoFunc func(string, int64) rest_errors.RestErr
+
+	// lastUserReadFromPrimary tells whether the last Get or FindByEmailAndPassword of
+	// the mock was asked to read from the primary.
+	lastUserReadFromPrimary bool
 )
 
+// transactionManagerMock runs units of work on the package level repositories,
+// which tests replace with mocks.
+type transactionManagerMock struct {
+	committed  bool
+	rolledBack bool
+}
+
+func (m *transactionManagerMock) Run(ctx context.Context, fn func(repositories.Repositories) rest_errors.RestErr) rest_errors.RestErr {
+	if err := fn(repositories.Default()); err != nil {
+		m.rolledBack = true
+		return err
+	}
+	m.committed = true
+	return nil
+}
+
+func withOwnedDataMocks() *transactionManagerMock {
+	repositories.AlertRulesRepository = &alertRulesRepoMock{}
+	repositories.NotificationSettingsRepository = &notificationSettingsRepoMock{}
+	repositories.QuietHoursRepository = &quietHoursRepoMock{}
+	repositories.PlanAssignmentsRepository = &planAssignmentsRepoMock{}
+	manager := &transactionManagerMock{}
+	repositories.TransactionManager = manager
+	return manager
+}
+
 type usersRepoMock struct{}
 
-func (*usersRepoMock) Save(user *users.User) rest_errors.RestErr {
-	return createUserRepoFunc(user)
+func (*usersRepoMock) Save(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	return createUserRepoFunc(user, eventTypes)
 }
 
-func (*usersRepoMock) FindByEmailAndPassword(loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
+func (*usersRepoMock) FindByEmailAndPassword(ctx context.Context, loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
+	lastUserReadFromPrimary = users_db.ReadsFromPrimary(ctx)
 	return findByEmailAndPasswordRepoFunc(loginRequest)
 }
 
-func (*usersRepoMock) Get(Id int64) (*users.User, rest_errors.RestErr) {
+func (*usersRepoMock) Get(ctx context.Context, Id int64) (*users.User, rest_errors.RestErr) {
+	lastUserReadFromPrimary = users_db.ReadsFromPrimary(ctx)
 	return getUserRepoFunc(Id)
 }
 
+func (*usersRepoMock) Update(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	return updateUserRepoFunc(user, eventTypes)
+}
+
+func (*usersRepoMock) UpdatePassword(ctx context.Context, user *users.User, password string, eventTypes ...string) rest_errors.RestErr {
+	return updateUserPasswordRepoFunc(user, password, eventTypes)
+}
+
+func (*usersRepoMock) UpdateAccess(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	return updateUserAccessRepoFunc(user, eventTypes)
+}
+
+func (*usersRepoMock) Delete(ctx context.Context, user *users.User, eventTypes ...string) rest_errors.RestErr {
+	return deleteUserRepoFunc(user, eventTypes)
+}
+
+func (*usersRepoMock) Search(ctx context.Context, filter users.Filter) (users.Users, rest_errors.RestErr) {
+	return searchUsersRepoFunc(filter)
+}
+
 func TestCreateOK(t *testing.T) {
 
 	user := users.User{Id: 666, Name: "John", Email: "john@mail.com", Password: "admin"}
-	createUserRepoFunc = func(user *users.User) rest_errors.RestErr {
+	var stored []string
+	createUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		stored = eventTypes
 		return nil
 	}
 
 	repositories.UsersRepository = &usersRepoMock{}
+	auditLog := withAuditMock()
 
-	_, err := UsersService.CreateUser(user)
+	_, err := UsersService.CreateUser(context.Background(), user)
 
 	assert.NoError(t, err)
+	assert.Equal(t, []string{events.TypeUserCreated}, stored)
 	assert.Equal(t, int64(666), user.Id)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionUserCreated, auditLog.entries[0].Action)
+	assert.Equal(t, audit.Change{Before: "", After: users.StatusActive}, auditLog.entries[0].Changes["status"])
+	assert.Equal(t, audit.Change{Before: "", After: access.RoleUser}, auditLog.entries[0].Changes["role"])
+	assert.NotContains(t, auditLog.entries[0].Changes, "password")
 }
 
 func TestCreateMissingPasswordReturnBadRequest(t *testing.T) {
@@ -50,20 +124,20 @@ func TestCreateMissingPasswordReturnBadRequest(t *testing.T) {
 	
 	repositories.UsersRepository = &usersRepoMock{}
 
-	_, err := UsersService.CreateUser(user)
+	_, err := UsersService.CreateUser(context.Background(), user)
 
 	assert.Equal(t, 400, err.Status())
 }
 
 func TestCreateFailReturnInternalServerError(t *testing.T) {
 	user := users.User{Id: 666, Name: "John", Email: "john@mail.com", Password: "admin"}
-	createUserRepoFunc = func(user *users.User) rest_errors.RestErr {
+	createUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
 		return rest_errors.NewInternalServerError("error when trying to save user", errors.New("database error"))
 	}
 
 	repositories.UsersRepository = &usersRepoMock{}
 
-	_, err := UsersService.CreateUser(user)
+	_, err := UsersService.CreateUser(context.Background(), user)
 
 	assert.Equal(t, 500, err.Status())	
 }
@@ -76,7 +150,7 @@ func TestGetOK(t *testing.T) {
 	}
 
 	repositories.UsersRepository = &usersRepoMock{}
-	_, err := UsersService.GetUser(666)
+	_, err := UsersService.GetUser(context.Background(), 666)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(666), user.Id)
@@ -91,12 +165,212 @@ func TestGetFailReturnInternalServerError(t *testing.T) {
 
 	repositories.UsersRepository = &usersRepoMock{}
 
-	_, err := UsersService.GetUser(777)
+	_, err := UsersService.GetUser(context.Background(), 777)
 
 	assert.Error(t, err)
 	assert.Equal(t, 500, err.Status())	
 }
 
+func TestCreateInvalidTimeZoneReturnBadRequest(t *testing.T) {
+	user := users.User{Name: "John", Email: "john@mail.com", Password: "admin", TimeZone: "Mars/Olympus_Mons"}
+
+	repositories.UsersRepository = &usersRepoMock{}
+
+	_, err := UsersService.CreateUser(context.Background(), user)
+
+	assert.Equal(t, 400, err.Status())
+}
+
+func TestCreateDefaultsTimeZoneToUTC(t *testing.T) {
+	user := users.User{Name: "John", Email: "john@mail.com", Password: "admin"}
+	createUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+
+	result, err := UsersService.CreateUser(context.Background(), user)
+
+	assert.Nil(t, err)
+	assert.Equal(t, users.DefaultTimeZone, result.TimeZone)
+}
+
+func TestUpdatePartialOK(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Name: "John", Email: "john@mail.com", TelegramUser: "@john", TimeZone: "UTC"}, nil
+	}
+	var updated *users.User
+	var stored []string
+	updateUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		updated = user
+		stored = eventTypes
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+	auditLog := withAuditMock()
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserId: 666})
+
+	result, err := UsersService.UpdateUser(ctx, true, users.User{Id: 666, TimeZone: "Europe/Madrid"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{events.TypeUserUpdated}, stored)
+	assert.Equal(t, "John", result.Name)
+	assert.Equal(t, "@john", result.TelegramUser)
+	assert.Equal(t, "Europe/Madrid", updated.TimeZone)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionProfileUpdated, auditLog.entries[0].Action)
+	assert.Equal(t, int64(666), auditLog.entries[0].ActorId)
+	assert.Equal(t, map[string]audit.Change{"time_zone": {Before: "UTC", After: "Europe/Madrid"}}, auditLog.entries[0].Changes)
+}
+
+func TestUpdateFullMissingEmailReturnBadRequest(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Name: "John", Email: "john@mail.com"}, nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+
+	_, err := UsersService.UpdateUser(context.Background(), false, users.User{Id: 666, Name: "Johnny"})
+
+	assert.Equal(t, 400, err.Status())
+}
+
+func TestUpdateTelegramUserPublishesTelegramLinked(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Name: "John", Email: "john@mail.com", TimeZone: "UTC"}, nil
+	}
+	var stored []string
+	updateUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		stored = eventTypes
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+
+	_, err := UsersService.UpdateUser(context.Background(), true, users.User{Id: 666, TelegramUser: "@John"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{events.TypeUserUpdated, events.TypeTelegramLinked}, stored)
+}
+
+func TestDeleteUserOK(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Email: "john@mail.com"}, nil
+	}
+	var deleted *users.User
+	var stored []string
+	deleteUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		deleted = user
+		stored = eventTypes
+		return nil
+	}
+	cascaded := make([]string, 0)
+	deleteByUserRepoFunc = func(table string, userId int64) rest_errors.RestErr {
+		assert.Equal(t, int64(666), userId)
+		cascaded = append(cascaded, table)
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+	manager := withOwnedDataMocks()
+	auditLog := withAuditMock()
+
+	err := UsersService.DeleteUser(context.Background(), 666)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionUserDeleted, auditLog.entries[0].Action)
+	assert.Equal(t, audit.Change{Before: "john@mail.com", After: ""}, auditLog.entries[0].Changes["email"])
+	assert.True(t, manager.committed)
+	assert.Equal(t, []string{"alert_rules", "notification_channels", "quiet_hours", "user_plans"}, cascaded)
+	assert.Equal(t, int64(666), deleted.Id)
+	assert.Equal(t, "john@mail.com", deleted.Email)
+	assert.Equal(t, []string{events.TypeUserDeleted}, stored)
+}
+
+func TestDeleteUserRollsBackWhenOwnedDataFails(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Email: "john@mail.com"}, nil
+	}
+	deleteUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		t.Fatal("user must not be deleted")
+		return nil
+	}
+	deleteByUserRepoFunc = func(table string, userId int64) rest_errors.RestErr {
+		if table == "quiet_hours" {
+			return rest_errors.NewInternalServerError("error deleting quiet hours", errors.New("database error"))
+		}
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+	manager := withOwnedDataMocks()
+
+	err := UsersService.DeleteUser(context.Background(), 666)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error deleting quiet hours", err.Message())
+	assert.True(t, manager.rolledBack)
+	assert.False(t, manager.committed)
+}
+
+func TestChangePasswordOK(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Email: "john@mail.com"}, nil
+	}
+	findByEmailAndPasswordRepoFunc = func(request users.LoginRequest) (*users.User, rest_errors.RestErr) {
+		assert.Equal(t, crypto_utils.GetMd5("admin"), request.Password)
+		return &users.User{Id: 666}, nil
+	}
+	var stored string
+	var storedEvents []string
+	updateUserPasswordRepoFunc = func(user *users.User, password string, eventTypes []string) rest_errors.RestErr {
+		stored = password
+		storedEvents = eventTypes
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+	auditLog := withAuditMock()
+
+	err := UsersService.ChangePassword(context.Background(), 666, users.ChangePasswordRequest{CurrentPassword: "admin", NewPassword: "s3cret"})
+
+	assert.Nil(t, err)
+	assert.True(t, lastUserReadFromPrimary)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionPasswordChanged, auditLog.entries[0].Action)
+	assert.Equal(t, audit.OutcomeSuccess, auditLog.entries[0].Outcome)
+	assert.Empty(t, auditLog.entries[0].Changes)
+	assert.Equal(t, crypto_utils.GetMd5("s3cret"), stored)
+	assert.Equal(t, []string{events.TypePasswordChanged}, storedEvents)
+}
+
+func TestChangePasswordWrongCurrentReturnUnauthorized(t *testing.T) {
+	getUserRepoFunc = func(Id int64) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: Id, Email: "john@mail.com"}, nil
+	}
+	findByEmailAndPasswordRepoFunc = func(request users.LoginRequest) (*users.User, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("invalid user credentials")
+	}
+
+	updateUserPasswordRepoFunc = func(user *users.User, password string, eventTypes []string) rest_errors.RestErr {
+		t.Fatal("password must not be updated")
+		return nil
+	}
+
+	repositories.UsersRepository = &usersRepoMock{}
+	auditLog := withAuditMock()
+
+	err := UsersService.ChangePassword(context.Background(), 666, users.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "s3cret"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 401, err.Status())
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.OutcomeFailure, auditLog.entries[0].Outcome)
+	assert.Equal(t, "invalid_current_password", auditLog.entries[0].Details["reason"])
+}
+
 func TestLoginUserOK(t *testing.T) {
 
 	loginReq := users.LoginRequest{Email: "john@mail.com", Password: "admin"}
@@ -106,14 +380,39 @@ func TestLoginUserOK(t *testing.T) {
 	}
 
 	repositories.UsersRepository = &usersRepoMock{}
-	_, err := UsersService.LoginUser(loginReq)
+	outbox := &outboxRepoMock{}
+	repositories.OutboxRepository = outbox
+	auditLog := withAuditMock()
+	_, err := UsersService.LoginUser(context.Background(), loginReq)
 
 	assert.NoError(t, err)
+	assert.True(t, lastUserReadFromPrimary)
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionLoginSucceeded, auditLog.entries[0].Action)
+	assert.Equal(t, int64(666), auditLog.entries[0].ActorId)
+	assert.Equal(t, int64(666), auditLog.entries[0].TargetUserId)
+	assert.Equal(t, 1, len(outbox.events))
+	assert.Equal(t, events.TypeUserLoggedIn, outbox.events[0].Type)
+	assert.Equal(t, int64(666), outbox.events[0].UserId)
 	assert.Equal(t, int64(666), user.Id)
 	assert.Equal(t, "John", user.Name)
 	assert.Equal(t, "john@mail.com", user.Email)
 }
 
+func TestLoginUserSucceedsWhenEventIsNotRecorded(t *testing.T) {
+	findByEmailAndPasswordRepoFunc = func(loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: 666}, nil
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	repositories.OutboxRepository = &outboxRepoMock{appendErr: rest_errors.NewInternalServerError("error saving event", errors.New("database error"))}
+	withAuditMock()
+
+	user, err := UsersService.LoginUser(context.Background(), users.LoginRequest{Email: "john@mail.com", Password: "admin"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(666), user.Id)
+}
+
 func TestLoginUserFailReturnInternalServerError(t *testing.T) {
 	loginReq := users.LoginRequest{Email: "john@mail.com", Password: "admin"}
 	findByEmailAndPasswordRepoFunc = func(loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
@@ -122,8 +421,66 @@ func TestLoginUserFailReturnInternalServerError(t *testing.T) {
 
 	repositories.UsersRepository = &usersRepoMock{}
 
-	_, err := UsersService.LoginUser(loginReq)
+	_, err := UsersService.LoginUser(context.Background(), loginReq)
 
 	assert.Error(t, err)
 	assert.Equal(t, 500, err.Status())	
 }
+
+func TestLoginUserCountsResults(t *testing.T) {
+	loginReq := users.LoginRequest{Email: "john@mail.com", Password: "wrong"}
+	findByEmailAndPasswordRepoFunc = func(loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
+		return nil, rest_errors.NewNotFoundError("invalid user credentials")
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	auditLog := withAuditMock()
+	failures := loginFailures.Value("invalid_credentials")
+	successes := loginSuccesses.Value()
+
+	UsersService.LoginUser(context.Background(), loginReq)
+
+	assert.Equal(t, failures+1, loginFailures.Value("invalid_credentials"))
+	assert.Equal(t, audit.ActionLoginFailed, auditLog.entries[0].Action)
+	assert.Equal(t, int64(0), auditLog.entries[0].ActorId)
+	assert.Equal(t, map[string]string{"email": "john@mail.com", "reason": "invalid_credentials"}, auditLog.entries[0].Details)
+
+	findByEmailAndPasswordRepoFunc = func(loginRequest users.LoginRequest) (*users.User, rest_errors.RestErr) {
+		return &users.User{Id: 666}, nil
+	}
+	repositories.OutboxRepository = &outboxRepoMock{}
+
+	UsersService.LoginUser(context.Background(), loginReq)
+
+	assert.Equal(t, successes+1, loginSuccesses.Value())
+}
+
+func TestCreateUserCountsResults(t *testing.T) {
+	user := users.User{Name: "John", Email: "john@mail.com", Password: "admin"}
+	createUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		return rest_errors.NewBadRequestError("email already registered")
+	}
+	repositories.UsersRepository = &usersRepoMock{}
+	failures := createFailures.Value("email_registered")
+	created := usersCreated.Value()
+
+	UsersService.CreateUser(context.Background(), user)
+
+	assert.Equal(t, failures+1, createFailures.Value("email_registered"))
+
+	createUserRepoFunc = func(user *users.User, eventTypes []string) rest_errors.RestErr {
+		return nil
+	}
+
+	UsersService.CreateUser(context.Background(), user)
+
+	assert.Equal(t, created+1, usersCreated.Value())
+}
+
+func TestFailureReason(t *testing.T) {
+	assert.Equal(t, "invalid_request", failureReason(rest_errors.NewBadRequestError("invalid email address")))
+	assert.Equal(t, "email_registered", failureReason(rest_errors.NewBadRequestError("email already registered")))
+	assert.Equal(t, "invalid_credentials", failureReason(rest_errors.NewNotFoundError("invalid user credentials")))
+	assert.Equal(t, "database_timeout", failureReason(rest_errors.NewRestError("database timeout", http.StatusGatewayTimeout, "gateway_timeout", nil)))
+	assert.Equal(t, "canceled", failureReason(rest_errors.NewRestError("request canceled", repositories.StatusClientClosedRequest, "client_closed_request", nil)))
+	assert.Equal(t, "error", failureReason(rest_errors.NewInternalServerError("error saving user", errors.New("database error"))))
+}
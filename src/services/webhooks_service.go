@@ -0,0 +1,253 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/webhooks"
+	"tokenalert_user-api/src/logging"
+	"tokenalert_user-api/src/repositories"
+	"tokenalert_user-api/src/utils/crypto_utils"
+	"tokenalert_user-api/src/utils/date_utils"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	webhookSecretSize     = 32
+	webhookDeliveriesPage = 100
+)
+
+var (
+	WebhooksService webhooksServiceInterface = &webhooksService{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 5,
+		baseDelay:   time.Second,
+		lease:       time.Minute,
+	}
+)
+
+type webhooksService struct {
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	lease       time.Duration
+}
+
+type webhooksServiceInterface interface {
+	CreateWebhook(context.Context, webhooks.Webhook) (*webhooks.Webhook, rest_errors.RestErr)
+	GetWebhooks(context.Context) (webhooks.Webhooks, rest_errors.RestErr)
+	DeleteWebhook(context.Context, int64) rest_errors.RestErr
+	GetDeliveries(context.Context, int64) (webhooks.Deliveries, rest_errors.RestErr)
+	Dispatch(context.Context, events.Event) rest_errors.RestErr
+	DeliverPending(context.Context, int) (int, rest_errors.RestErr)
+}
+
+func (s *webhooksService) CreateWebhook(ctx context.Context, webhook webhooks.Webhook) (*webhooks.Webhook, rest_errors.RestErr) {
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret == "" {
+		secret, err := crypto_utils.GetRandomHex(webhookSecretSize)
+		if err != nil {
+			logging.Error(ctx, "error when trying to generate webhook secret", err)
+			return nil, rest_errors.NewInternalServerError("error saving webhook", errors.New("secret generation error"))
+		}
+		webhook.Secret = secret
+	}
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	webhook.Enabled = true
+	webhook.DateCreated = date_utils.GetNowDBFormat()
+	if err := repositories.WebhooksRepository.Save(ctx, &webhook); err != nil {
+		return nil, err
+	}
+
+	entry := newAuditEntry(ctx, audit.ActionWebhookCreated, audit.OutcomeSuccess, 0)
+	entry.Details = map[string]string{"webhook_id": strconv.FormatInt(webhook.Id, 10), "url": webhook.Url,
+		"event_types": strings.Join(webhook.EventTypes, ",")}
+	AuditService.Record(ctx, entry)
+	return &webhook, nil
+}
+
+func (s *webhooksService) GetWebhooks(ctx context.Context) (webhooks.Webhooks, rest_errors.RestErr) {
+	return repositories.WebhooksRepository.FindAll(ctx, false)
+}
+
+func (s *webhooksService) DeleteWebhook(ctx context.Context, webhookId int64) rest_errors.RestErr {
+	webhook, err := repositories.WebhooksRepository.Get(ctx, webhookId)
+	if err != nil {
+		return err
+	}
+	if err := repositories.WebhooksRepository.Delete(ctx, webhookId); err != nil {
+		return err
+	}
+
+	entry := newAuditEntry(ctx, audit.ActionWebhookDeleted, audit.OutcomeSuccess, 0)
+	entry.Details = map[string]string{"webhook_id": strconv.FormatInt(webhookId, 10), "url": webhook.Url}
+	AuditService.Record(ctx, entry)
+	return nil
+}
+
+func (s *webhooksService) GetDeliveries(ctx context.Context, webhookId int64) (webhooks.Deliveries, rest_errors.RestErr) {
+	if _, err := repositories.WebhooksRepository.Get(ctx, webhookId); err != nil {
+		return nil, err
+	}
+	return repositories.WebhooksRepository.FindDeliveries(ctx, webhookId, webhookDeliveriesPage)
+}
+
+// Dispatch queues a delivery of the event to every enabled webhook subscribed to its
+// type. Deliveries are made by DeliverPending, so a slow receiver never holds up the
+// outbox. An error means nothing was queued and the event should be dispatched again
+// later.
+func (s *webhooksService) Dispatch(ctx context.Context, event events.Event) rest_errors.RestErr {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logging.Error(ctx, "error when trying to marshal event for webhooks", err)
+		return rest_errors.NewInternalServerError("error dispatching event", errors.New("json error"))
+	}
+
+	targets, getErr := repositories.WebhooksRepository.FindAll(ctx, true)
+	if getErr != nil {
+		logging.Error(ctx, "error when trying to find webhooks for event "+event.Type, getErr)
+		return getErr
+	}
+
+	now := date_utils.GetNowDBFormat()
+	queued := make([]webhooks.Pending, 0, len(targets))
+	for _, webhook := range targets {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+		queued = append(queued, webhooks.Pending{
+			WebhookId:     webhook.Id,
+			EventId:       event.Id,
+			EventType:     event.Type,
+			Payload:       string(body),
+			NextAttemptAt: now,
+			DateCreated:   now,
+		})
+	}
+	if len(queued) == 0 {
+		return nil
+	}
+	return repositories.WebhooksRepository.Enqueue(ctx, queued)
+}
+
+// DeliverPending makes the next attempt of up to limit queued deliveries that are due,
+// in parallel, and returns how many it made. Every attempt is logged. A delivery leaves
+// the queue once the receiver answers with a 2xx status, a non retryable status, or
+// attempts run out; otherwise it is attempted again with exponential backoff. Claimed
+// deliveries are kept from other relays for lease, longer than an attempt can take.
+func (s *webhooksService) DeliverPending(ctx context.Context, limit int) (int, rest_errors.RestErr) {
+	now := date_utils.GetNow()
+	due, err := repositories.WebhooksRepository.ClaimDue(ctx, date_utils.FormatDB(now), date_utils.FormatDB(now.Add(s.lease)), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var attempts sync.WaitGroup
+	for _, pending := range due {
+		attempts.Add(1)
+		go func(pending webhooks.Pending) {
+			defer attempts.Done()
+			s.deliver(ctx, pending)
+		}(pending)
+	}
+	attempts.Wait()
+	return len(due), nil
+}
+
+func (s *webhooksService) deliver(ctx context.Context, pending webhooks.Pending) {
+	webhook, getErr := repositories.WebhooksRepository.Get(ctx, pending.WebhookId)
+	if getErr != nil {
+		if getErr.Status() == http.StatusNotFound {
+			s.dequeue(ctx, pending)
+		}
+		return
+	}
+
+	attempt := pending.Attempts + 1
+	statusCode, sendErr := s.send(ctx, *webhook, pending)
+
+	delivery := webhooks.Delivery{
+		WebhookId:   pending.WebhookId,
+		EventId:     pending.EventId,
+		EventType:   pending.EventType,
+		Attempt:     attempt,
+		StatusCode:  statusCode,
+		Success:     sendErr == nil,
+		DateCreated: date_utils.GetNowDBFormat(),
+	}
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	}
+	if err := repositories.WebhooksRepository.SaveDelivery(ctx, &delivery); err != nil {
+		logging.Error(ctx, "error when trying to log webhook delivery", err)
+	}
+
+	if sendErr == nil || !retryableStatus(statusCode) {
+		s.dequeue(ctx, pending)
+		return
+	}
+	if attempt >= s.maxAttempts {
+		logging.Info(ctx, fmt.Sprintf("giving up delivering event %s to webhook %d", pending.EventId, pending.WebhookId))
+		s.dequeue(ctx, pending)
+		return
+	}
+
+	delay := s.baseDelay << (attempt - 1)
+	nextAttemptAt := date_utils.FormatDB(date_utils.GetNow().Add(delay))
+	if err := repositories.WebhooksRepository.Reschedule(ctx, pending.Id, attempt, nextAttemptAt); err != nil {
+		logging.Error(ctx, "error when trying to reschedule webhook delivery", err)
+	}
+}
+
+// dequeue failures are only logged: the claim expires and the delivery is made again,
+// which receivers have to cope with anyway.
+func (s *webhooksService) dequeue(ctx context.Context, pending webhooks.Pending) {
+	if err := repositories.WebhooksRepository.Dequeue(ctx, pending.Id); err != nil {
+		logging.Error(ctx, "error when trying to dequeue webhook delivery", err)
+	}
+}
+
+func retryableStatus(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func (s *webhooksService) send(ctx context.Context, webhook webhooks.Webhook, pending webhooks.Pending) (int, error) {
+	body := []byte(pending.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(date_utils.GetNow().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhooks.HeaderEvent, pending.EventType)
+	request.Header.Set(webhooks.HeaderDelivery, pending.EventId)
+	request.Header.Set(webhooks.HeaderTimestamp, timestamp)
+	request.Header.Set(webhooks.HeaderSignature, webhooks.Sign(webhook.Secret, timestamp, body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook answered with status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
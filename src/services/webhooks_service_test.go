@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"tokenalert_user-api/src/domain/audit"
+	"tokenalert_user-api/src/domain/events"
+	"tokenalert_user-api/src/domain/webhooks"
+	"tokenalert_user-api/src/repositories"
+
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type dispatchedEvents struct {
+	events          []events.Event
+	err             rest_errors.RestErr
+	due             int
+	deliveryBatches int
+}
+
+func (d *dispatchedEvents) types() []string {
+	result := make([]string, 0, len(d.events))
+	for _, event := range d.events {
+		result = append(result, event.Type)
+	}
+	return result
+}
+
+type webhooksServiceMock struct {
+	dispatched *dispatchedEvents
+}
+
+func (*webhooksServiceMock) CreateWebhook(context.Context, webhooks.Webhook) (*webhooks.Webhook, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (*webhooksServiceMock) GetWebhooks(context.Context) (webhooks.Webhooks, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (*webhooksServiceMock) DeleteWebhook(context.Context, int64) rest_errors.RestErr {
+	return nil
+}
+
+func (*webhooksServiceMock) GetDeliveries(context.Context, int64) (webhooks.Deliveries, rest_errors.RestErr) {
+	return nil, nil
+}
+
+func (m *webhooksServiceMock) Dispatch(ctx context.Context, event events.Event) rest_errors.RestErr {
+	if m.dispatched.err != nil {
+		return m.dispatched.err
+	}
+	m.dispatched.events = append(m.dispatched.events, event)
+	return nil
+}
+
+func (m *webhooksServiceMock) DeliverPending(ctx context.Context, limit int) (int, rest_errors.RestErr) {
+	m.dispatched.deliveryBatches++
+	delivered := limit
+	if m.dispatched.due < limit {
+		delivered = m.dispatched.due
+	}
+	m.dispatched.due -= delivered
+	return delivered, nil
+}
+
+func captureEvents() *dispatchedEvents {
+	dispatched := &dispatchedEvents{}
+	WebhooksService = &webhooksServiceMock{dispatched: dispatched}
+	return dispatched
+}
+
+type webhooksRepoMock struct {
+	webhooks   webhooks.Webhooks
+	mutex      sync.Mutex
+	deliveries webhooks.Deliveries
+	queue      []webhooks.Pending
+	enqueueErr rest_errors.RestErr
+}
+
+func (*webhooksRepoMock) Save(ctx context.Context, webhook *webhooks.Webhook) rest_errors.RestErr {
+	webhook.Id = 1
+	return nil
+}
+
+func (m *webhooksRepoMock) Get(ctx context.Context, id int64) (*webhooks.Webhook, rest_errors.RestErr) {
+	for _, webhook := range m.webhooks {
+		if webhook.Id == id {
+			return &webhook, nil
+		}
+	}
+	return nil, rest_errors.NewNotFoundError("webhook not found")
+}
+
+func (m *webhooksRepoMock) FindAll(ctx context.Context, onlyEnabled bool) (webhooks.Webhooks, rest_errors.RestErr) {
+	return m.webhooks, nil
+}
+
+func (*webhooksRepoMock) Delete(context.Context, int64) rest_errors.RestErr {
+	return nil
+}
+
+func (m *webhooksRepoMock) SaveDelivery(ctx context.Context, delivery *webhooks.Delivery) rest_errors.RestErr {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.deliveries = append(m.deliveries, *delivery)
+	return nil
+}
+
+func (m *webhooksRepoMock) FindDeliveries(context.Context, int64, int) (webhooks.Deliveries, rest_errors.RestErr) {
+	return m.deliveries, nil
+}
+
+func (m *webhooksRepoMock) Enqueue(ctx context.Context, queued []webhooks.Pending) rest_errors.RestErr {
+	if m.enqueueErr != nil {
+		return m.enqueueErr
+	}
+	for _, pending := range queued {
+		pending.Id = int64(len(m.queue) + 1)
+		m.queue = append(m.queue, pending)
+	}
+	return nil
+}
+
+func (m *webhooksRepoMock) ClaimDue(ctx context.Context, now string, until string, limit int) ([]webhooks.Pending, rest_errors.RestErr) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	due := make([]webhooks.Pending, 0)
+	for index := range m.queue {
+		if len(due) < limit && m.queue[index].NextAttemptAt <= now {
+			due = append(due, m.queue[index])
+			m.queue[index].NextAttemptAt = until
+		}
+	}
+	return due, nil
+}
+
+func (m *webhooksRepoMock) Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt string) rest_errors.RestErr {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for index := range m.queue {
+		if m.queue[index].Id == id {
+			m.queue[index].Attempts = attempts
+			m.queue[index].NextAttemptAt = nextAttemptAt
+		}
+	}
+	return nil
+}
+
+func (m *webhooksRepoMock) Dequeue(ctx context.Context, id int64) rest_errors.RestErr {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for index := range m.queue {
+		if m.queue[index].Id == id {
+			m.queue = append(m.queue[:index], m.queue[index+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func newTestWebhooksService() *webhooksService {
+	return &webhooksService{client: &http.Client{Timeout: time.Second}, maxAttempts: 3, baseDelay: time.Second, lease: time.Minute}
+}
+
+func TestCreateWebhookGeneratesSecret(t *testing.T) {
+	repositories.WebhooksRepository = &webhooksRepoMock{}
+	service := newTestWebhooksService()
+	auditLog := withAuditMock()
+
+	webhook, err := service.CreateWebhook(context.Background(), webhooks.Webhook{Url: "https://example.com/hook", EventTypes: []string{"User.Created"}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 64, len(webhook.Secret))
+	assert.Equal(t, 1, len(auditLog.entries))
+	assert.Equal(t, audit.ActionWebhookCreated, auditLog.entries[0].Action)
+	assert.Equal(t, "https://example.com/hook", auditLog.entries[0].Details["url"])
+	assert.NotContains(t, auditLog.entries[0].Details, "secret")
+	assert.Equal(t, []string{events.TypeUserCreated}, webhook.EventTypes)
+	assert.True(t, webhook.Enabled)
+}
+
+func TestCreateWebhookInvalidReturnBadRequest(t *testing.T) {
+	service := newTestWebhooksService()
+
+	_, err := service.CreateWebhook(context.Background(), webhooks.Webhook{Url: "example.com/hook"})
+	assert.Equal(t, 400, err.Status())
+
+	_, err = service.CreateWebhook(context.Background(), webhooks.Webhook{Url: "https://example.com/hook", EventTypes: []string{"user.exploded"}})
+	assert.Equal(t, 400, err.Status())
+}
+
+func TestDispatchQueuesSubscribedWebhooks(t *testing.T) {
+	event, _ := events.New(events.TypeUserCreated, 666, map[string]string{"email": "john@mail.com"})
+
+	repo := &webhooksRepoMock{webhooks: webhooks.Webhooks{
+		{Id: 1, Url: "https://example.com/all", Secret: "secret", Enabled: true},
+		{Id: 2, Url: "https://example.com/created", Secret: "secret", EventTypes: []string{events.TypeUserCreated}, Enabled: true},
+		{Id: 3, Url: "https://example.com/deleted", Secret: "secret", EventTypes: []string{events.TypeUserDeleted}, Enabled: true},
+	}}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+
+	err := service.Dispatch(context.Background(), *event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(repo.queue))
+	assert.Equal(t, int64(1), repo.queue[0].WebhookId)
+	assert.Equal(t, int64(2), repo.queue[1].WebhookId)
+	for _, pending := range repo.queue {
+		assert.Equal(t, event.Id, pending.EventId)
+		assert.Equal(t, 0, pending.Attempts)
+		assert.Contains(t, pending.Payload, "john@mail.com")
+	}
+	assert.Empty(t, repo.deliveries)
+}
+
+func TestDispatchFailsWhenDeliveriesAreNotQueued(t *testing.T) {
+	event, _ := events.New(events.TypeUserCreated, 666, nil)
+
+	repo := &webhooksRepoMock{webhooks: webhooks.Webhooks{{Id: 1, Url: "https://example.com/all", Secret: "secret", Enabled: true}},
+		enqueueErr: rest_errors.NewInternalServerError("error queueing webhook deliveries", errors.New("database error"))}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+
+	err := service.Dispatch(context.Background(), *event)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 500, err.Status())
+}
+
+func TestDeliverPendingSignsAndDequeues(t *testing.T) {
+	event, _ := events.New(events.TypeUserCreated, 666, map[string]string{"email": "john@mail.com"})
+
+	var mutex sync.Mutex
+	received := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		received[r.URL.Path] = r.Header.Get(webhooks.HeaderSignature)
+		assert.Equal(t, webhooks.Sign("secret", r.Header.Get(webhooks.HeaderTimestamp), body), r.Header.Get(webhooks.HeaderSignature))
+		assert.Equal(t, event.Id, r.Header.Get(webhooks.HeaderDelivery))
+		assert.Equal(t, events.TypeUserCreated, r.Header.Get(webhooks.HeaderEvent))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := &webhooksRepoMock{webhooks: webhooks.Webhooks{
+		{Id: 1, Url: server.URL + "/all", Secret: "secret", Enabled: true},
+		{Id: 2, Url: server.URL + "/created", Secret: "secret", EventTypes: []string{events.TypeUserCreated}, Enabled: true},
+	}}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+	assert.Nil(t, service.Dispatch(context.Background(), *event))
+
+	delivered, err := service.DeliverPending(context.Background(), 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, 2, len(received))
+	assert.NotEmpty(t, received["/all"])
+	assert.NotEmpty(t, received["/created"])
+	assert.Equal(t, 2, len(repo.deliveries))
+	for _, delivery := range repo.deliveries {
+		assert.True(t, delivery.Success)
+		assert.Equal(t, 1, delivery.Attempt)
+		assert.Equal(t, http.StatusNoContent, delivery.StatusCode)
+	}
+	assert.Empty(t, repo.queue)
+}
+
+func TestDeliverPendingRetriesWithBackoff(t *testing.T) {
+	event, _ := events.New(events.TypeUserDeleted, 666, nil)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	repo := &webhooksRepoMock{webhooks: webhooks.Webhooks{{Id: 1, Url: server.URL, Secret: "secret", Enabled: true}}}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+	withClock(t, "2024-03-01T10:00:00Z")
+	assert.Nil(t, service.Dispatch(context.Background(), *event))
+
+	service.DeliverPending(context.Background(), 10)
+	assert.Equal(t, 1, repo.queue[0].Attempts)
+	assert.Equal(t, "2024-03-01 10:00:01", repo.queue[0].NextAttemptAt)
+
+	delivered, _ := service.DeliverPending(context.Background(), 10)
+	assert.Equal(t, 0, delivered)
+
+	withClock(t, "2024-03-01T10:00:01Z")
+	service.DeliverPending(context.Background(), 10)
+	assert.Equal(t, 2, repo.queue[0].Attempts)
+	assert.Equal(t, "2024-03-01 10:00:03", repo.queue[0].NextAttemptAt)
+
+	withClock(t, "2024-03-01T10:00:03Z")
+	service.DeliverPending(context.Background(), 10)
+
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 3, len(repo.deliveries))
+	assert.False(t, repo.deliveries[0].Success)
+	assert.Equal(t, "webhook answered with status 503", repo.deliveries[0].Error)
+	assert.True(t, repo.deliveries[2].Success)
+	assert.Equal(t, 3, repo.deliveries[2].Attempt)
+	assert.Empty(t, repo.queue)
+}
+
+func TestDeliverPendingGivesUpAfterMaxAttempts(t *testing.T) {
+	event, _ := events.New(events.TypeUserDeleted, 666, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	repo := &webhooksRepoMock{webhooks: webhooks.Webhooks{{Id: 1, Url: server.URL, Secret: "secret", Enabled: true}}}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+	withClock(t, "2024-03-01T10:00:00Z")
+	assert.Nil(t, service.Dispatch(context.Background(), *event))
+
+	for _, now := range []string{"2024-03-01T10:00:00Z", "2024-03-01T10:01:00Z", "2024-03-01T10:02:00Z"} {
+		withClock(t, now)
+		service.DeliverPending(context.Background(), 10)
+	}
+
+	assert.Equal(t, 3, len(repo.deliveries))
+	assert.Empty(t, repo.queue)
+}
+
+func TestDeliverPendingDoesNotRetryClientErrors(t *testing.T) {
+	event, _ := events.New(events.TypeUserDeleted, 666, nil)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	repo := &webhooksRepoMock{webhooks: webhooks.Webhooks{{Id: 1, Url: server.URL, Secret: "secret", Enabled: true}}}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+	assert.Nil(t, service.Dispatch(context.Background(), *event))
+
+	service.DeliverPending(context.Background(), 10)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, len(repo.deliveries))
+	assert.False(t, repo.deliveries[0].Success)
+	assert.Empty(t, repo.queue)
+}
+
+func TestDeliverPendingDropsDeliveriesOfDeletedWebhooks(t *testing.T) {
+	repo := &webhooksRepoMock{queue: []webhooks.Pending{{Id: 1, WebhookId: 9, EventId: "evt", NextAttemptAt: "2000-01-01 00:00:00"}}}
+	repositories.WebhooksRepository = repo
+	service := newTestWebhooksService()
+
+	delivered, err := service.DeliverPending(context.Background(), 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Empty(t, repo.deliveries)
+	assert.Empty(t, repo.queue)
+}
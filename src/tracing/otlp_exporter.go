@@ -0,0 +1,238 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLP status codes, which do not share the values of codes.Code.
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// otlpExporter posts spans to an OTLP/HTTP endpoint, such as the /v1/traces of an
+// OpenTelemetry collector, in the JSON encoding of ExportTraceServiceRequest.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue sets exactly one field. Integers are strings, as the protobuf JSON
+// mapping of int64 requires.
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+func NewOtlpExporter(endpoint string, headers map[string]string, timeout time.Duration) sdktrace.SpanExporter {
+	return &otlpExporter{endpoint: endpoint, headers: headers, client: &http.Client{Timeout: timeout}}
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequestOf(spans))
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp endpoint answered %d", response.StatusCode)
+	}
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlpRequestOf groups spans by resource, then by instrumentation scope.
+func otlpRequestOf(spans []sdktrace.ReadOnlySpan) otlpRequest {
+	request := otlpRequest{}
+	resources := map[attribute.Distinct]int{}
+	scopes := map[attribute.Distinct]map[string]int{}
+	for _, span := range spans {
+		resourceKey := span.Resource().Equivalent()
+		resourceIndex, ok := resources[resourceKey]
+		if !ok {
+			resourceIndex = len(request.ResourceSpans)
+			resources[resourceKey] = resourceIndex
+			scopes[resourceKey] = map[string]int{}
+			request.ResourceSpans = append(request.ResourceSpans, otlpResourceSpans{
+				Resource: otlpResource{Attributes: otlpAttributes(span.Resource().Attributes())},
+			})
+		}
+		resourceSpans := &request.ResourceSpans[resourceIndex]
+
+		scope := span.InstrumentationScope()
+		scopeKey := scope.Name + "@" + scope.Version
+		scopeIndex, ok := scopes[resourceKey][scopeKey]
+		if !ok {
+			scopeIndex = len(resourceSpans.ScopeSpans)
+			scopes[resourceKey][scopeKey] = scopeIndex
+			resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, otlpScopeSpans{Scope: otlpScope{Name: scope.Name, Version: scope.Version}})
+		}
+		scopeSpans := &resourceSpans.ScopeSpans[scopeIndex]
+		scopeSpans.Spans = append(scopeSpans.Spans, otlpSpanOf(span))
+	}
+	return request
+}
+
+func otlpSpanOf(span sdktrace.ReadOnlySpan) otlpSpan {
+	result := otlpSpan{
+		TraceId:           span.SpanContext().TraceID().String(),
+		SpanId:            span.SpanContext().SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: unixNano(span.StartTime()),
+		EndTimeUnixNano:   unixNano(span.EndTime()),
+		Attributes:        otlpAttributes(span.Attributes()),
+		Status:            otlpStatus{Code: otlpStatusUnset},
+	}
+	if span.Parent().IsValid() {
+		result.ParentSpanId = span.Parent().SpanID().String()
+	}
+	switch span.Status().Code {
+	case codes.Ok:
+		result.Status.Code = otlpStatusOk
+	case codes.Error:
+		result.Status = otlpStatus{Code: otlpStatusError, Message: span.Status().Description}
+	}
+	for _, event := range span.Events() {
+		result.Events = append(result.Events, otlpEvent{TimeUnixNano: unixNano(event.Time), Name: event.Name, Attributes: otlpAttributes(event.Attributes)})
+	}
+	return result
+}
+
+func otlpAttributes(attributes []attribute.KeyValue) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(attributes))
+	for _, kv := range attributes {
+		result = append(result, otlpKeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return result
+}
+
+func otlpValue(value attribute.Value) otlpAnyValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		v := value.AsBool()
+		return otlpAnyValue{BoolValue: &v}
+	case attribute.INT64:
+		v := strconv.FormatInt(value.AsInt64(), 10)
+		return otlpAnyValue{IntValue: &v}
+	case attribute.FLOAT64:
+		v := value.AsFloat64()
+		return otlpAnyValue{DoubleValue: &v}
+	case attribute.BOOLSLICE:
+		return otlpArray(value.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return otlpArray(value.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return otlpArray(value.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return otlpArray(value.AsStringSlice(), attribute.StringValue)
+	}
+	v := value.Emit()
+	return otlpAnyValue{StringValue: &v}
+}
+
+func otlpArray[T any](items []T, toValue func(T) attribute.Value) otlpAnyValue {
+	values := make([]otlpAnyValue, 0, len(items))
+	for _, item := range items {
+		values = append(values, otlpValue(toValue(item)))
+	}
+	return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// stdoutExporter writes a JSON line per span, for local debugging.
+type stdoutExporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+type stdoutSpan struct {
+	Name         string                 `json:"name"`
+	Kind         string                 `json:"kind"`
+	TraceId      string                 `json:"trace_id"`
+	SpanId       string                 `json:"span_id"`
+	ParentSpanId string                 `json:"parent_span_id,omitempty"`
+	Start        time.Time              `json:"start"`
+	DurationMs   float64                `json:"duration_ms"`
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Events       []stdoutEvent          `json:"events,omitempty"`
+}
+
+type stdoutEvent struct {
+	Name       string                 `json:"name"`
+	Time       time.Time              `json:"time"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func NewStdoutExporter(w io.Writer) sdktrace.SpanExporter {
+	return &stdoutExporter{encoder: json.NewEncoder(w)}
+}
+
+func (e *stdoutExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, span := range spans {
+		line := stdoutSpan{
+			Name:       span.Name(),
+			Kind:       span.SpanKind().String(),
+			TraceId:    span.SpanContext().TraceID().String(),
+			SpanId:     span.SpanContext().SpanID().String(),
+			Start:      span.StartTime().UTC(),
+			DurationMs: float64(span.EndTime().Sub(span.StartTime()).Microseconds()) / 1000,
+			Status:     span.Status().Code.String(),
+			Error:      span.Status().Description,
+			Attributes: attributeMap(span.Attributes()),
+		}
+		if span.Parent().IsValid() {
+			line.ParentSpanId = span.Parent().SpanID().String()
+		}
+		for _, event := range span.Events() {
+			line.Events = append(line.Events, stdoutEvent{Name: event.Name, Time: event.Time.UTC(), Attributes: attributeMap(event.Attributes)})
+		}
+		if err := e.encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *stdoutExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func attributeMap(attributes []attribute.KeyValue) map[string]interface{} {
+	if len(attributes) == 0 {
+		return nil
+	}
+	result := make(map[string]interface{}, len(attributes))
+	for _, kv := range attributes {
+		result[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+// Package tracing sets OpenTelemetry up: W3C trace context propagation, always on,
+// and the export of the spans recorded, to stdout or to an OTLP collector.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOtlp   = "otlp"
+
+	instrumentationName = "tokenalert_user-api"
+	exportTimeout       = 10 * time.Second
+)
+
+var (
+	// Provider records and exports spans. It stays nil, and spans are only propagated,
+	// unless InitTracing finds an exporter configured.
+	Provider *sdktrace.TracerProvider
+)
+
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Config picks where spans go. Endpoint and Headers are only used by the otlp
+// exporter, which posts to Endpoint in the OTLP/HTTP JSON encoding. SampleRatio
+// applies to the traces started here; those started upstream keep their decision.
+type Config struct {
+	Exporter    string
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+	SampleRatio float64
+}
+
+// DefaultConfig returns the settings used for anything left unset.
+func DefaultConfig() Config {
+	return Config{
+		Exporter:    ExporterNone,
+		Endpoint:    "http://localhost:4318/v1/traces",
+		Headers:     map[string]string{},
+		ServiceName: "users-api",
+		SampleRatio: 1,
+	}
+}
+
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterOtlp:
+		parsed, err := url.Parse(c.Endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid tracing.endpoint %q", c.Endpoint)
+		}
+	default:
+		return fmt.Errorf("invalid tracing.exporter %q, use none, stdout or otlp", c.Exporter)
+	}
+	if strings.TrimSpace(c.ServiceName) == "" {
+		return fmt.Errorf("tracing.service_name can not be empty")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// InitTracing starts recording spans and exporting them in batches, unless the
+// exporter is none.
+func InitTracing(config Config) {
+	if config.Exporter == "" || config.Exporter == ExporterNone {
+		log.Println("tracing not configured, trace context is only propagated")
+		return
+	}
+	if err := config.Validate(); err != nil {
+		panic(err)
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case ExporterStdout:
+		exporter = NewStdoutExporter(os.Stdout)
+	case ExporterOtlp:
+		exporter = NewOtlpExporter(config.Endpoint, config.Headers, exportTimeout)
+	}
+	Provider = newProvider(config, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(Provider)
+	log.Println("tracing successfully configured, exporting to " + config.Exporter)
+}
+
+func newProvider(config Config, options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	options = append(options,
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", config.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Shutdown exports the spans still buffered and stops the provider.
+func Shutdown() error {
+	if Provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	return Provider.Shutdown(ctx)
+}
+
+// Start starts a span, child of the one in ctx if any, with the provider in use.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
+
+// Fail records err on the span of ctx and marks it failed.
+func Fail(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
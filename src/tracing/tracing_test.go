@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// export runs fn with a provider writing to exporter, and flushes it.
+func export(t *testing.T, exporter sdktrace.SpanExporter, fn func()) {
+	provider := newProvider(DefaultConfig(), sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	fn()
+	assert.Nil(t, provider.Shutdown(context.Background()))
+}
+
+// remoteParent returns a context carrying the span context of traceparent, as the
+// middleware extracts it from a request.
+func remoteParent() context.Context {
+	carrier := propagation.MapCarrier{"traceparent": traceparent}
+	return otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+}
+
+func TestValidate(t *testing.T) {
+	config := DefaultConfig()
+	assert.Nil(t, config.Validate())
+
+	config.Exporter = "jaeger"
+	assert.Equal(t, `invalid tracing.exporter "jaeger", use none, stdout or otlp`, config.Validate().Error())
+
+	config.Exporter = ExporterOtlp
+	config.Endpoint = "collector:4318"
+	assert.Equal(t, `invalid tracing.endpoint "collector:4318"`, config.Validate().Error())
+
+	config.Endpoint = "https://collector:4318/v1/traces"
+	config.SampleRatio = -0.1
+	assert.Equal(t, "tracing.sample_ratio must be between 0 and 1", config.Validate().Error())
+
+	config.SampleRatio = 0
+	config.ServiceName = " "
+	assert.Equal(t, "tracing.service_name can not be empty", config.Validate().Error())
+}
+
+func TestStartContinuesRemoteTrace(t *testing.T) {
+	var out bytes.Buffer
+	export(t, NewStdoutExporter(&out), func() {
+		_, span := Start(remoteParent(), "usersService.GetUser")
+		span.End()
+	})
+
+	var line map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "usersService.GetUser", line["name"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", line["parent_span_id"])
+	assert.Equal(t, "Unset", line["status"])
+}
+
+func TestFailMarksSpan(t *testing.T) {
+	var out bytes.Buffer
+	export(t, NewStdoutExporter(&out), func() {
+		ctx, span := Start(context.Background(), "users.get")
+		Fail(ctx, errors.New("connection refused"))
+		span.End()
+	})
+
+	var line stdoutSpan
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "Error", line.Status)
+	assert.Equal(t, "connection refused", line.Error)
+	assert.Equal(t, 1, len(line.Events))
+	assert.Equal(t, "exception", line.Events[0].Name)
+	assert.Equal(t, "connection refused", line.Events[0].Attributes["exception.message"])
+}
+
+func TestOtlpExporterPostsSpans(t *testing.T) {
+	var body []byte
+	var headers http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer collector.Close()
+
+	exporter := NewOtlpExporter(collector.URL+"/v1/traces", map[string]string{"Authorization": "Bearer abc"}, time.Second)
+	export(t, exporter, func() {
+		_, span := Start(remoteParent(), "GET /users/:user_id", trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.Int("http.status_code", 200), attribute.StringSlice("roles", []string{"admin"})))
+		span.End()
+	})
+
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer abc", headers.Get("Authorization"))
+	var request otlpRequest
+	assert.Nil(t, json.Unmarshal(body, &request))
+	assert.Equal(t, 1, len(request.ResourceSpans))
+	assert.Equal(t, "service.name", request.ResourceSpans[0].Resource.Attributes[0].Key)
+	assert.Equal(t, "users-api", *request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, instrumentationName, request.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+	span := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "GET /users/:user_id", span.Name)
+	assert.Equal(t, int(trace.SpanKindServer), span.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentSpanId)
+	assert.Equal(t, "200", *span.Attributes[0].Value.IntValue)
+	assert.Equal(t, "admin", *span.Attributes[1].Value.ArrayValue.Values[0].StringValue)
+}
+
+func TestOtlpExporterFailsOnErrorStatus(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer collector.Close()
+	provider := newProvider(DefaultConfig())
+	_, span := provider.Tracer(instrumentationName).Start(context.Background(), "users.get")
+	span.End()
+
+	err := NewOtlpExporter(collector.URL, nil, time.Second).ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "otlp endpoint answered 401", err.Error())
+}
@@ -1,8 +1,12 @@
 package crypto_utils
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 )
 
 func GetMd5(input string) string {
@@ -10,4 +14,29 @@ func GetMd5(input string) string {
 	defer hash.Reset()
 	hash.Write([]byte(input))
 	return hex.EncodeToString(hash.Sum(nil))
-}
\ No newline at end of file
+}
+
+func GetHmacSha256(secret string, input []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(input)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func GetRandomHex(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// NewUUID returns a random (version 4) UUID.
+func NewUUID() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	buffer[6] = (buffer[6] & 0x0f) | 0x40
+	buffer[8] = (buffer[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buffer[0:4], buffer[4:6], buffer[6:8], buffer[8:10], buffer[10:]), nil
+}
@@ -9,8 +9,26 @@ const (
 	apiDbLayout   = "2006-01-02 15:04:05"
 )
 
+var (
+	Clock clockInterface = &systemClock{}
+)
+
+type clockInterface interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (c *systemClock) Now() time.Time {
+	return time.Now()
+}
+
 func GetNow() time.Time {
-	return time.Now().UTC()
+	return Clock.Now().UTC()
+}
+
+func GetNowIn(location *time.Location) time.Time {
+	return GetNow().In(location)
 }
 
 func GetNowString() string {
@@ -19,4 +37,16 @@ func GetNowString() string {
 
 func GetNowDBFormat() string {
 	return GetNow().Format(apiDbLayout)
-}
\ No newline at end of file
+}
+
+func FormatDB(value time.Time) string {
+	return value.Format(apiDbLayout)
+}
+
+func FormatAPI(value time.Time) string {
+	return value.UTC().Format(apiDateLayout)
+}
+
+func ParseDBFormat(value string) (time.Time, error) {
+	return time.Parse(apiDbLayout, value)
+}
@@ -1,28 +0,0 @@
-package mysql_utils
-
-import (
-	"github.com/go-sql-driver/mysql"
-	"strings"
-	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
-	"errors"
-)
-
-const (
-	ErrorNoRows = "no rows in result set"
-)
-
-func ParseError(err error) rest_errors.RestErr {
-	sqlErr, ok := err.(*mysql.MySQLError)
-	if !ok {
-		if strings.Contains(err.Error(), ErrorNoRows) {
-			return rest_errors.NewNotFoundError("no record matching given id")
-		}
-		return rest_errors.NewInternalServerError("error parsing database response", err)
-	}
-
-	switch sqlErr.Number {
-	case 1062:
-		return rest_errors.NewBadRequestError("invalid data")
-	}
-	return rest_errors.NewInternalServerError("error processing request", errors.New("database error"))
-}
\ No newline at end of file
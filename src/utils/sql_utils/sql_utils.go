@@ -0,0 +1,49 @@
+package sql_utils
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/rafawilliner/tokenalert_utils-go/src/rest_errors"
+)
+
+const (
+	ErrorNoRows = "no rows in result set"
+
+	mysqlDuplicateEntry     = 1062
+	postgresUniqueViolation = "23505"
+)
+
+// ParseError maps an error returned by any of the supported databases to the one
+// given to callers.
+func ParseError(err error) rest_errors.RestErr {
+	if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), ErrorNoRows) {
+		return rest_errors.NewNotFoundError("no record matching given id")
+	}
+	if IsUniqueViolation(err) {
+		return rest_errors.NewBadRequestError("invalid data")
+	}
+
+	var mysqlErr *mysql.MySQLError
+	var postgresErr *pq.Error
+	if !errors.As(err, &mysqlErr) && !errors.As(err, &postgresErr) && !isSQLiteError(err) {
+		return rest_errors.NewInternalServerError("error parsing database response", err)
+	}
+	return rest_errors.NewInternalServerError("error processing request", errors.New("database error"))
+}
+
+// IsUniqueViolation reports whether err comes from a write that broke a unique key.
+func IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDuplicateEntry
+	}
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) {
+		return postgresErr.Code == postgresUniqueViolation
+	}
+	return isSQLiteUniqueViolation(err)
+}
@@ -0,0 +1,50 @@
+package sql_utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorNoRows(t *testing.T) {
+	err := ParseError(fmt.Errorf("scanning: %w", sql.ErrNoRows))
+
+	assert.Equal(t, 404, err.Status())
+	assert.Equal(t, "no record matching given id", err.Message())
+}
+
+func TestParseErrorUniqueViolation(t *testing.T) {
+	for _, cause := range []error{
+		&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"},
+		&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"},
+	} {
+		err := ParseError(cause)
+
+		assert.True(t, IsUniqueViolation(cause))
+		assert.Equal(t, 400, err.Status())
+		assert.Equal(t, "invalid data", err.Message())
+	}
+}
+
+func TestParseErrorOtherDatabaseErrors(t *testing.T) {
+	for _, cause := range []error{
+		&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"},
+		&pq.Error{Code: "42P01", Message: "relation does not exist"},
+	} {
+		err := ParseError(cause)
+
+		assert.False(t, IsUniqueViolation(cause))
+		assert.Equal(t, 500, err.Status())
+		assert.Equal(t, "error processing request", err.Message())
+	}
+
+	err := ParseError(errors.New("broken pipe"))
+
+	assert.Equal(t, 500, err.Status())
+	assert.Equal(t, "error parsing database response", err.Message())
+}
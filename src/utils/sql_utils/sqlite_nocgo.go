@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package sql_utils
+
+// Without cgo the SQLite driver is a stub that never opens a database, so no error
+// can come from it.
+
+func isSQLiteError(err error) bool {
+	return false
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	return false
+}